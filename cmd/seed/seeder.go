@@ -67,7 +67,7 @@ func createTeacher(db *gorm.DB) userRow {
 		Password:  hashPassword("Demo1234!"),
 		FirstName: "Aidana",
 		LastName:  "Seitkali",
-		Role:      "user",
+		Role:      "teacher",
 	}
 	must(db.Where("email = ?", teacher.Email).FirstOrCreate(&teacher).Error)
 	return teacher
@@ -81,7 +81,7 @@ func createStudents(db *gorm.DB) []userRow {
 			Password:  hashPassword("Student123!"),
 			FirstName: s.firstName,
 			LastName:  s.lastName,
-			Role:      "user",
+			Role:      "student",
 		}
 		db.Where("email = ?", row.Email).FirstOrCreate(&row)
 		out = append(out, row)
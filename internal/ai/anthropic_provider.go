@@ -0,0 +1,240 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+type anthropicProvider struct {
+	name       string
+	baseURL    string
+	model      string
+	apiKey     string
+	maxTokens  int
+	httpClient *http.Client
+}
+
+func newAnthropicProvider(name, baseURL, model, apiKey string, maxTokens int, httpClient *http.Client) Provider {
+	if maxTokens <= 0 {
+		maxTokens = 8192
+	}
+	return &anthropicProvider{
+		name:       strings.ToLower(strings.TrimSpace(name)),
+		baseURL:    strings.TrimRight(strings.TrimSpace(baseURL), "/"),
+		model:      strings.TrimSpace(model),
+		apiKey:     strings.TrimSpace(apiKey),
+		maxTokens:  maxTokens,
+		httpClient: httpClient,
+	}
+}
+
+func (p *anthropicProvider) Name() string {
+	return p.name
+}
+
+func (p *anthropicProvider) Model() string {
+	return p.model
+}
+
+func (p *anthropicProvider) IsConfigured() bool {
+	return p.baseURL != "" && p.model != "" && p.apiKey != ""
+}
+
+func (p *anthropicProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	resp, err := p.send(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var payload anthropicResponse
+	if err := json.Unmarshal(respBody, &payload); err != nil {
+		return nil, fmt.Errorf("provider %s invalid response: %w", p.name, err)
+	}
+
+	var text strings.Builder
+	for _, block := range payload.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return p.completion(text.String(), payload.Model, payload.StopReason, Usage{
+		PromptTokens:     payload.Usage.InputTokens,
+		CompletionTokens: payload.Usage.OutputTokens,
+	})
+}
+
+// Stream reads the Messages API event stream: the model and input tokens
+// come with message_start, text with content_block_delta and the output
+// tokens with message_delta.
+func (p *anthropicProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	resp, err := p.send(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		text       strings.Builder
+		model      string
+		stopReason string
+		usage      Usage
+	)
+	err = readServerSentEvents(resp.Body, func(data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("provider %s invalid stream event: %w", p.name, err)
+		}
+		switch event.Type {
+		case "message_start":
+			model = event.Message.Model
+			usage.PromptTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				text.WriteString(event.Delta.Text)
+				if onDelta != nil {
+					onDelta(event.Delta.Text)
+				}
+			}
+		case "message_delta":
+			stopReason = event.Delta.StopReason
+			usage.CompletionTokens = event.Usage.OutputTokens
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			return fmt.Errorf("provider %s stream error: %s", p.name, event.Error.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.completion(text.String(), model, stopReason, usage)
+}
+
+func (p *anthropicProvider) send(ctx context.Context, req CompletionRequest, stream bool) (*http.Response, error) {
+	if !p.IsConfigured() {
+		return nil, fmt.Errorf("provider %s is not configured", p.name)
+	}
+
+	body := anthropicRequest{
+		Model:       p.model,
+		MaxTokens:   p.maxTokens,
+		Temperature: req.Temperature,
+		Stream:      stream,
+	}
+	var system []string
+	for _, m := range req.Messages {
+		if m.Role == "system" {
+			system = append(system, m.Content)
+			continue
+		}
+		role := "user"
+		if m.Role == "assistant" {
+			role = "assistant"
+		}
+		// The API expects turns to alternate, so consecutive messages of
+		// one role are merged.
+		if n := len(body.Messages); n > 0 && body.Messages[n-1].Role == role {
+			body.Messages[n-1].Content += "\n\n" + m.Content
+			continue
+		}
+		body.Messages = append(body.Messages, Message{Role: role, Content: m.Content})
+	}
+	if len(body.Messages) == 0 {
+		return nil, fmt.Errorf("provider %s received empty prompt", p.name)
+	}
+	body.System = strings.Join(system, "\n\n")
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+		return nil, fmt.Errorf("provider %s returned status %d: %s", p.name, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return resp, nil
+}
+
+func (p *anthropicProvider) completion(content, model, stopReason string, usage Usage) (*CompletionResponse, error) {
+	if stopReason == "max_tokens" {
+		return nil, fmt.Errorf("provider %s stopped at the %d token limit", p.name, p.maxTokens)
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, fmt.Errorf("provider %s returned empty content", p.name)
+	}
+	if model == "" {
+		model = p.model
+	}
+	return &CompletionResponse{Text: content, Model: model, Usage: usage}, nil
+}
+
+type anthropicRequest struct {
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      anthropicUsage `json:"usage"`
+}
+
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Model string         `json:"model"`
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
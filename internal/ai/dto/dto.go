@@ -1,14 +1,27 @@
 package dto
 
+import (
+	"time"
+
+	testdto "edu-system/internal/test/dto"
+)
+
 type PipelineRunRequest struct {
 	Material         MaterialInput      `json:"material" binding:"required"`
 	GenerationConfig GenerationConfig   `json:"generation_config"`
 	Provider         *ProviderSelection `json:"provider,omitempty"`
+	// Files are documents uploaded as multipart form data.
+	Files []MaterialFile `json:"-"`
+}
+
+type MaterialFile struct {
+	Name string
+	Data []byte
 }
 
 type MaterialInput struct {
 	Title          string `json:"title,omitempty"`
-	Text           string `json:"text" binding:"required_without=SourceURL"`
+	Text           string `json:"text"`
 	SourceURL      string `json:"source_url,omitempty" binding:"omitempty,url"`
 	Language       string `json:"language,omitempty"`
 	AdditionalNote string `json:"additional_note,omitempty"`
@@ -22,6 +35,9 @@ type GenerationConfig struct {
 	OutputLanguage       string `json:"output_language,omitempty"`
 	IncludeExplanations  *bool  `json:"include_explanations,omitempty"`
 	IncludePracticeTests *bool  `json:"include_practice_test,omitempty"`
+	// PromptLanguage picks the prompt templates; by default those of
+	// OutputLanguage are used when they exist.
+	PromptLanguage string `json:"prompt_language,omitempty"`
 }
 
 type ProviderSelection struct {
@@ -30,6 +46,7 @@ type ProviderSelection struct {
 }
 
 type PipelineRunResponse struct {
+	RunID         string               `json:"run_id"`
 	Plan          PlanOutput           `json:"plan"`
 	Draft         GenerationOutput     `json:"draft"`
 	Validation    ValidationOutput     `json:"validation"`
@@ -37,6 +54,67 @@ type PipelineRunResponse struct {
 	ProviderTrace []LayerProviderTrace `json:"provider_trace"`
 }
 
+// PublishRunRequest picks what a run is published as: the test variant at
+// index Variant, or the practice test when Practice is set.
+type PublishRunRequest struct {
+	Variant     int    `json:"variant" binding:"min=0"`
+	Practice    bool   `json:"practice,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type PublishRunResponse struct {
+	RunID            string `json:"run_id"`
+	TestID           string `json:"test_id"`
+	Title            string `json:"title"`
+	CreatedQuestions int    `json:"created_questions"`
+}
+
+// MaterialIngestion records where the material of a run came from. When the
+// material had to be condensed, ExtractedText keeps the full text and
+// Traces the provider calls that condensed it.
+type MaterialIngestion struct {
+	Sources       []MaterialSource     `json:"sources"`
+	TotalChars    int                  `json:"total_chars"`
+	Chunks        int                  `json:"chunks,omitempty"`
+	Condensed     bool                 `json:"condensed"`
+	ExtractedText string               `json:"extracted_text,omitempty"`
+	Traces        []LayerProviderTrace `json:"traces,omitempty"`
+}
+
+type MaterialSource struct {
+	Kind        string `json:"kind"`           // text | url | file
+	Name        string `json:"name,omitempty"` // URL or file name
+	ContentType string `json:"content_type,omitempty"`
+	Chars       int    `json:"chars"`
+}
+
+type RunSummary struct {
+	RunID       string    `json:"run_id"`
+	ParentRunID string    `json:"parent_run_id,omitempty"`
+	Title       string    `json:"title"`
+	CreatedAt   time.Time `json:"created_at"`
+	ReadyForUse bool      `json:"ready_for_use"`
+	Variants    int       `json:"variants"`
+}
+
+// RunDetails is a stored run together with the input it was generated from.
+type RunDetails struct {
+	ParentRunID      string             `json:"parent_run_id,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	Material         MaterialInput      `json:"material"`
+	GenerationConfig GenerationConfig   `json:"generation_config"`
+	Provider         *ProviderSelection `json:"provider,omitempty"`
+	Ingestion        MaterialIngestion  `json:"ingestion"`
+	PipelineRunResponse
+}
+
+// RerunLayerRequest optionally overrides the provider order stored with the run.
+type RerunLayerRequest struct {
+	Provider       *ProviderSelection `json:"provider,omitempty"`
+	PromptLanguage string             `json:"prompt_language,omitempty"`
+}
+
 type PlanOutput struct {
 	Summary            string        `json:"summary"`
 	LearningObjectives []string      `json:"learning_objectives"`
@@ -124,12 +202,35 @@ type FinalOutput struct {
 }
 
 type LayerProviderTrace struct {
-	Layer        string   `json:"layer"`
-	Provider     string   `json:"provider"`
-	Model        string   `json:"model"`
-	Attempts     int      `json:"attempts"`
-	FallbackUsed bool     `json:"fallback_used"`
-	Errors       []string `json:"errors,omitempty"`
+	Layer        string     `json:"layer"`
+	Provider     string     `json:"provider"`
+	Model        string     `json:"model"`
+	Attempts     int        `json:"attempts"`
+	Repairs      int        `json:"repairs,omitempty"`
+	FallbackUsed bool       `json:"fallback_used"`
+	Errors       []string   `json:"errors,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	DurationMs   int64      `json:"duration_ms"`
+	Usage        LayerUsage `json:"usage"`
+	// PromptVersion names the template the layer was prompted with, as
+	// "<layer>/<language>@<version>".
+	PromptVersion string `json:"prompt_version,omitempty"`
+}
+
+// LayerUsage sums the tokens and cost of every provider call of a layer,
+// including calls whose output had to be discarded.
+type LayerUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// UsageReport is a user's spending on paid providers in the current month.
+type UsageReport struct {
+	PeriodStart  time.Time `json:"period_start"`
+	SpentUSD     float64   `json:"spent_usd"`
+	BudgetUSD    float64   `json:"budget_usd,omitempty"`
+	RemainingUSD *float64  `json:"remaining_usd,omitempty"`
 }
 
 type ProviderStatus struct {
@@ -137,3 +238,108 @@ type ProviderStatus struct {
 	Configured bool   `json:"configured"`
 	Model      string `json:"model"`
 }
+
+const (
+	EventLayerStarted   = "layer_started"
+	EventLayerCompleted = "layer_completed"
+	EventLayerFailed    = "layer_failed"
+	EventFallback       = "fallback"
+	EventLayerProgress  = "layer_progress"
+	EventRepair         = "repair"
+	EventJobFinished    = "job_finished"
+)
+
+// PipelineEvent reports the progress of a pipeline job. Fallback events name
+// the provider that failed and why, repair events the checks its output
+// failed; progress events carry the characters streamed so far.
+type PipelineEvent struct {
+	Type     string    `json:"type"`
+	Layer    string    `json:"layer,omitempty"`
+	Provider string    `json:"provider,omitempty"`
+	Model    string    `json:"model,omitempty"`
+	Message  string    `json:"message,omitempty"`
+	Status   string    `json:"status,omitempty"`
+	RunID    string    `json:"run_id,omitempty"`
+	Chars    int       `json:"chars,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+type JobView struct {
+	JobID      string           `json:"job_id"`
+	Status     string           `json:"status"`
+	RunID      string           `json:"run_id,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Layers     []JobLayerStatus `json:"layers"`
+}
+
+// JobLayerStatus is pending, running, completed or failed.
+type JobLayerStatus struct {
+	Layer      string     `json:"layer"`
+	Status     string     `json:"status"`
+	Provider   string     `json:"provider,omitempty"`
+	Model      string     `json:"model,omitempty"`
+	Fallbacks  int        `json:"fallbacks"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// QuestionAssistRequest configures a suggestion for one stored question.
+// Count applies to distractors (default 3), Language to translations.
+// Only provider.order is used.
+type QuestionAssistRequest struct {
+	Count    int                `json:"count,omitempty"`
+	Language string             `json:"language,omitempty"`
+	Provider *ProviderSelection `json:"provider,omitempty"`
+}
+
+// QuestionAssistResponse holds suggestions for a question. Each suggestion
+// is a complete question payload that can replace the original in the
+// questions of an UpdateTest request.
+type QuestionAssistResponse struct {
+	QuestionID  string               `json:"question_id"`
+	TestID      string               `json:"test_id"`
+	Suggestions []QuestionSuggestion `json:"suggestions"`
+	Distractors []Distractor         `json:"distractors,omitempty"`
+	Trace       LayerProviderTrace   `json:"trace"`
+}
+
+// QuestionSuggestion kind is distractors, easier, harder or translation.
+type QuestionSuggestion struct {
+	Kind     string           `json:"kind"`
+	Note     string           `json:"note,omitempty"`
+	Question testdto.Question `json:"question"`
+}
+
+type Distractor struct {
+	Text      string `json:"text"`
+	Rationale string `json:"rationale"`
+}
+
+type DistractorsOutput struct {
+	Distractors []Distractor `json:"distractors"`
+}
+
+type RewriteOutput struct {
+	Easier RewrittenQuestion `json:"easier"`
+	Harder RewrittenQuestion `json:"harder"`
+}
+
+// RewrittenQuestion keeps the options in the original order so that the
+// correct answer indexes stay valid.
+type RewrittenQuestion struct {
+	QuestionText    string   `json:"question_text"`
+	Options         []string `json:"options"`
+	Solution        string   `json:"solution"`
+	AcceptedAnswers []string `json:"accepted_answers"`
+	Note            string   `json:"note"`
+}
@@ -0,0 +1,353 @@
+package ai
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	htmlDropBlocks = []*regexp.Regexp{
+		regexp.MustCompile(`(?is)<!--.*?-->`),
+		regexp.MustCompile(`(?is)<script\b.*?</script\s*>`),
+		regexp.MustCompile(`(?is)<style\b.*?</style\s*>`),
+		regexp.MustCompile(`(?is)<noscript\b.*?</noscript\s*>`),
+		regexp.MustCompile(`(?is)<svg\b.*?</svg\s*>`),
+		regexp.MustCompile(`(?is)<head\b.*?</head\s*>`),
+		regexp.MustCompile(`(?is)<nav\b.*?</nav\s*>`),
+		regexp.MustCompile(`(?is)<aside\b.*?</aside\s*>`),
+		regexp.MustCompile(`(?is)<footer\b.*?</footer\s*>`),
+		regexp.MustCompile(`(?is)<form\b.*?</form\s*>`),
+	}
+	htmlListItem   = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlBlockBreak = regexp.MustCompile(`(?i)</?(p|div|br|h[1-6]|tr|ul|ol|table|section|article|header|main|pre|blockquote|dd|dt)\b[^>]*>`)
+	htmlTag        = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaceRun       = regexp.MustCompile(`[ \t\f\v\p{Zs}]+`)
+	blankLines     = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText keeps the readable text of a page: scripts, styles and
+// navigation are dropped and block elements become line breaks.
+func htmlToText(page string) string {
+	for _, re := range htmlDropBlocks {
+		page = re.ReplaceAllString(page, " ")
+	}
+	page = htmlListItem.ReplaceAllString(page, "\n- ")
+	page = htmlBlockBreak.ReplaceAllString(page, "\n")
+	page = htmlTag.ReplaceAllString(page, " ")
+	return tidyText(html.UnescapeString(page))
+}
+
+func tidyText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaceRun.ReplaceAllString(line, " "))
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// maxDecompressedBytes bounds how much a compressed document may expand
+// while its text is extracted.
+const maxDecompressedBytes = 32 << 20
+
+var errDecompressedTooLarge = fmt.Errorf("document expands to more than %d MB", maxDecompressedBytes>>20)
+
+// docxText reads the paragraphs of word/document.xml.
+func docxText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("not a docx archive: %w", err)
+	}
+	var doc *zip.File
+	for _, f := range archive.File {
+		if f.Name == "word/document.xml" {
+			doc = f
+			break
+		}
+	}
+	if doc == nil {
+		return "", errors.New("docx has no word/document.xml")
+	}
+	// The header size can lie; the limited reader holds either way.
+	if doc.UncompressedSize64 > maxDecompressedBytes {
+		return "", errDecompressedTooLarge
+	}
+	rc, err := doc.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	limited := &io.LimitedReader{R: rc, N: maxDecompressedBytes + 1}
+
+	var (
+		out    strings.Builder
+		inText bool
+	)
+	decoder := xml.NewDecoder(limited)
+	for {
+		tok, err := decoder.Token()
+		if limited.N <= 0 {
+			return "", errDecompressedTooLarge
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("read docx: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				out.WriteByte('\t')
+			case "br", "cr":
+				out.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				out.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				out.Write(t)
+			}
+		}
+	}
+	return tidyText(out.String()), nil
+}
+
+var (
+	pdfStream     = regexp.MustCompile(`(?s)stream\r?\n`)
+	pdfSkipStream = regexp.MustCompile(`/Subtype\s*/Image|/FontFile|/Length1|/Type\s*/(XRef|ObjStm|Metadata)`)
+)
+
+// pdfText is a best-effort extractor for text drawn with simple fonts. Scans
+// and PDFs that only carry glyph IDs (embedded CID fonts) yield nothing
+// readable and are reported as such. All streams together may decompress to
+// at most maxDecompressedBytes.
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return "", errors.New("not a pdf file")
+	}
+	var out strings.Builder
+	budget := int64(maxDecompressedBytes)
+	for _, loc := range pdfStream.FindAllIndex(data, -1) {
+		if bytes.HasSuffix(data[:loc[0]], []byte("end")) {
+			continue
+		}
+		dictStart := bytes.LastIndex(data[:loc[0]], []byte("obj"))
+		if dictStart < 0 {
+			continue
+		}
+		dict := data[dictStart:loc[0]]
+		end := bytes.Index(data[loc[1]:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		if pdfSkipStream.Match(dict) {
+			continue
+		}
+		content := data[loc[1] : loc[1]+end]
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			zr, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			// Truncated streams still yield what was decoded so far.
+			content, _ = io.ReadAll(io.LimitReader(zr, budget+1))
+			zr.Close()
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+		budget -= int64(len(content))
+		if budget < 0 {
+			return "", errDecompressedTooLarge
+		}
+		pdfContentText(content, &out)
+	}
+
+	text := tidyText(out.String())
+	if !mostlyReadable(text) {
+		return "", errors.New("pdf has no extractable text (scanned or uses embedded font encodings)")
+	}
+	return text, nil
+}
+
+// pdfContentText appends the strings shown by the text operators of a
+// content stream, breaking lines on text positioning operators.
+func pdfContentText(content []byte, out *strings.Builder) {
+	var pending []string
+	flush := func(sep string) {
+		for _, s := range pending {
+			out.WriteString(s)
+		}
+		pending = pending[:0]
+		out.WriteString(sep)
+	}
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, next := pdfLiteralString(content, i)
+			pending = append(pending, s)
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return
+			}
+			pending = append(pending, pdfHexString(content[i+1:i+end]))
+			i += end + 1
+		case c == '-' || (c >= '0' && c <= '9') || c == '.':
+			start := i
+			for i < len(content) && (content[i] == '-' || content[i] == '.' || (content[i] >= '0' && content[i] <= '9')) {
+				i++
+			}
+			// Large negative kerning inside TJ arrays stands for a space.
+			if len(pending) > 0 && content[start] == '-' && i-start >= 4 {
+				pending = append(pending, " ")
+			}
+		case isPDFOperatorChar(c):
+			start := i
+			for i < len(content) && isPDFOperatorChar(content[i]) {
+				i++
+			}
+			switch string(content[start:i]) {
+			case "Tj", "TJ":
+				flush("")
+			case "'", "\"", "T*", "Td", "TD", "ET":
+				flush("\n")
+			case "Tm":
+				flush(" ")
+			default:
+				pending = pending[:0]
+			}
+		default:
+			i++
+		}
+	}
+}
+
+func isPDFOperatorChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '*' || c == '\'' || c == '"'
+}
+
+func pdfLiteralString(content []byte, start int) (string, int) {
+	var buf []byte
+	depth := 0
+	i := start
+	for i < len(content) {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v, n := 0, 0
+					for n < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7' {
+						v = v*8 + int(content[i]-'0')
+						i++
+						n++
+					}
+					buf = append(buf, byte(v))
+					continue
+				}
+				buf = append(buf, e)
+			}
+		case c == '(':
+			if depth > 0 {
+				buf = append(buf, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return pdfDecodeBytes(buf), i + 1
+			}
+			buf = append(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+		i++
+	}
+	return pdfDecodeBytes(buf), i
+}
+
+func pdfHexString(raw []byte) string {
+	clean := bytes.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, raw)
+	if len(clean)%2 == 1 {
+		clean = append(clean, '0')
+	}
+	buf := make([]byte, hex.DecodedLen(len(clean)))
+	if _, err := hex.Decode(buf, clean); err != nil {
+		return ""
+	}
+	return pdfDecodeBytes(buf)
+}
+
+// pdfDecodeBytes reads UTF-16BE strings marked with a BOM and treats
+// everything else as Latin-1, which covers the standard PDF encodings well
+// enough for plain text.
+func pdfDecodeBytes(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// mostlyReadable reports whether text has content and is made mostly of
+// letters, digits, punctuation and spaces rather than decoding noise.
+func mostlyReadable(text string) bool {
+	if utf8.RuneCountInString(text) < 20 {
+		return false
+	}
+	good, total := 0, 0
+	for _, r := range text {
+		total++
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsPunct(r) {
+			good++
+		}
+	}
+	return good*10 >= total*8
+}
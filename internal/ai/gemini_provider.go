@@ -42,6 +42,87 @@ func (p *geminiProvider) IsConfigured() bool {
 }
 
 func (p *geminiProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	resp, err := p.send(ctx, req, "generateContent")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed geminiResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("provider %s invalid response: %w", p.name, err)
+	}
+
+	if len(parsed.Candidates) == 0 || len(parsed.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("provider %s returned no content", p.name)
+	}
+
+	parts := make([]string, 0, len(parsed.Candidates[0].Content.Parts))
+	for _, part := range parsed.Candidates[0].Content.Parts {
+		text := strings.TrimSpace(part.Text)
+		if text != "" {
+			parts = append(parts, text)
+		}
+	}
+
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("provider %s returned empty text", p.name)
+	}
+
+	return &CompletionResponse{Text: strings.Join(parts, "\n"), Model: p.model, Usage: parsed.UsageMetadata.usage()}, nil
+}
+
+// Stream uses streamGenerateContent with server-sent events. Every chunk
+// repeats the usage counted so far, so the last one wins.
+func (p *geminiProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	resp, err := p.send(ctx, req, "streamGenerateContent")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		text  strings.Builder
+		usage Usage
+	)
+	err = readServerSentEvents(resp.Body, func(data string) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("provider %s invalid stream chunk: %w", p.name, err)
+		}
+		if chunk.UsageMetadata != nil {
+			usage = chunk.UsageMetadata.usage()
+		}
+		if len(chunk.Candidates) == 0 {
+			return nil
+		}
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			if part.Text != "" {
+				text.WriteString(part.Text)
+				if onDelta != nil {
+					onDelta(part.Text)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(text.String())
+	if content == "" {
+		return nil, fmt.Errorf("provider %s returned empty text", p.name)
+	}
+	return &CompletionResponse{Text: content, Model: p.model, Usage: usage}, nil
+}
+
+func (p *geminiProvider) send(ctx context.Context, req CompletionRequest, method string) (*http.Response, error) {
 	if !p.IsConfigured() {
 		return nil, fmt.Errorf("provider %s is not configured", p.name)
 	}
@@ -96,7 +177,10 @@ func (p *geminiProvider) Complete(ctx context.Context, req CompletionRequest) (*
 		return nil, err
 	}
 
-	u := fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s", p.baseURL, url.PathEscape(p.model), url.QueryEscape(p.apiKey))
+	u := fmt.Sprintf("%s/v1beta/models/%s:%s?key=%s", p.baseURL, url.PathEscape(p.model), method, url.QueryEscape(p.apiKey))
+	if method == "streamGenerateContent" {
+		u += "&alt=sse"
+	}
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(raw))
 	if err != nil {
 		return nil, err
@@ -107,39 +191,13 @@ func (p *geminiProvider) Complete(ctx context.Context, req CompletionRequest) (*
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
-	if err != nil {
-		return nil, err
-	}
 
 	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
 		return nil, fmt.Errorf("provider %s returned status %d: %s", p.name, resp.StatusCode, strings.TrimSpace(string(respBody)))
 	}
-
-	var parsed geminiResponse
-	if err := json.Unmarshal(respBody, &parsed); err != nil {
-		return nil, fmt.Errorf("provider %s invalid response: %w", p.name, err)
-	}
-
-	if len(parsed.Candidates) == 0 || len(parsed.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("provider %s returned no content", p.name)
-	}
-
-	parts := make([]string, 0, len(parsed.Candidates[0].Content.Parts))
-	for _, part := range parsed.Candidates[0].Content.Parts {
-		text := strings.TrimSpace(part.Text)
-		if text != "" {
-			parts = append(parts, text)
-		}
-	}
-
-	if len(parts) == 0 {
-		return nil, fmt.Errorf("provider %s returned empty text", p.name)
-	}
-
-	return &CompletionResponse{Text: strings.Join(parts, "\n"), Model: p.model}, nil
+	return resp, nil
 }
 
 type geminiRequest struct {
@@ -166,4 +224,19 @@ type geminiResponse struct {
 	Candidates []struct {
 		Content geminiContent `json:"content"`
 	} `json:"candidates"`
+	UsageMetadata *geminiUsageMetadata `json:"usageMetadata"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+}
+
+// usage counts thinking tokens as output, which is how they are billed.
+func (m *geminiUsageMetadata) usage() Usage {
+	if m == nil {
+		return Usage{}
+	}
+	return Usage{PromptTokens: m.PromptTokenCount, CompletionTokens: m.CandidatesTokenCount + m.ThoughtsTokenCount}
 }
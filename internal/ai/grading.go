@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"edu-system/internal/testAttempt"
+)
+
+const layerGrade = "grade"
+
+type gradeOutput struct {
+	Score     float64 `json:"score"`
+	Rationale string  `json:"rationale"`
+}
+
+// SuggestGrade asks the provider chain to score a free-form answer against
+// the question's reference. It satisfies testAttempt.AnswerGrader.
+func (s *service) SuggestGrade(ctx context.Context, ownerID testAttempt.UserID, req testAttempt.GradingRequest) (testAttempt.GradeSuggestion, error) {
+	owner := uint(ownerID)
+	order := s.defaultOrder
+	if err := s.ensureBudget(ctx, owner, map[string][]string{layerGrade: order}); err != nil {
+		return testAttempt.GradeSuggestion{}, err
+	}
+
+	p, err := s.prompts.render(layerGrade, "", promptData{Grading: gradingPromptOf(req)})
+	if err != nil {
+		return testAttempt.GradeSuggestion{}, err
+	}
+	var out gradeOutput
+	trace, err := s.runLayer(ctx, owner, layerGrade, order, nil, p, func(raw string) error {
+		if err := decodeModelJSON(raw, &out); err != nil {
+			return err
+		}
+		return checkGrade(&out, req.MaxScore)
+	})
+	if err != nil {
+		return testAttempt.GradeSuggestion{}, err
+	}
+	return testAttempt.GradeSuggestion{
+		Score:         out.Score,
+		Rationale:     strings.TrimSpace(out.Rationale),
+		Provider:      trace.Provider,
+		Model:         trace.Model,
+		PromptVersion: trace.PromptVersion,
+		CreatedAt:     time.Now().UTC(),
+	}, nil
+}
@@ -0,0 +1,43 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"edu-system/internal/testAttempt"
+)
+
+func TestSuggestGradeRepairsOutOfRangeScore(t *testing.T) {
+	provider := &mockProvider{
+		name:       ProviderOpenAI,
+		model:      "grader",
+		configured: true,
+		responses: []mockProviderResponse{
+			{text: `{"score": 5, "rationale": "Верно."}`},
+			{text: `{"score": 1.5, "rationale": "Определение верное, пример не приведён."}`},
+		},
+	}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: provider}, []string{ProviderOpenAI})
+
+	suggestion, err := svc.SuggestGrade(context.Background(), 3, testAttempt.GradingRequest{
+		QuestionText: "Что такое инкапсуляция?",
+		Kind:         "text",
+		Reference:    "Сокрытие состояния объекта; нужен пример.",
+		Answer:       "Сокрытие данных внутри объекта.",
+		MaxScore:     2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if suggestion.Score != 1.5 || suggestion.Provider != ProviderOpenAI || suggestion.Model != "grader" {
+		t.Fatalf("unexpected suggestion: %+v", suggestion)
+	}
+	if provider.calls != 2 {
+		t.Fatalf("expected a repair call for the out-of-range score, got %d calls", provider.calls)
+	}
+	prompt := provider.requests[0].Messages[1].Content
+	if !strings.Contains(prompt, "Сокрытие состояния объекта") || !strings.Contains(prompt, "Сокрытие данных внутри объекта.") {
+		t.Fatalf("prompt misses the reference or the answer: %s", prompt)
+	}
+}
@@ -1,13 +1,19 @@
 package ai
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
 	"edu-system/internal/ai/dto"
 	response "edu-system/internal/delivery"
+	"edu-system/internal/test"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type Handler struct {
@@ -18,9 +24,19 @@ func NewHandler(service Service) *Handler {
 	return &Handler{service: service}
 }
 
+// RunPipeline accepts either a JSON body or multipart form data with the
+// JSON request in the "request" field and documents in "files".
 func (h *Handler) RunPipeline(c *gin.Context) {
-	var req dto.PipelineRunRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	var (
+		req dto.PipelineRunRequest
+		err error
+	)
+	if c.ContentType() == "multipart/form-data" {
+		err = bindMultipartPipelineRequest(c, &req)
+	} else {
+		err = c.ShouldBindJSON(&req)
+	}
+	if err != nil {
 		c.JSON(http.StatusBadRequest, response.ErrorResponse{
 			Error:   "validation error",
 			Message: err.Error(),
@@ -34,20 +50,222 @@ func (h *Handler) RunPipeline(c *gin.Context) {
 		return
 	}
 
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		job, err := h.service.StartPipelineJob(c.Request.Context(), uint(userID), &req)
+		if err != nil {
+			c.JSON(pipelineErrStatus(err), response.ErrorResponse{
+				Error:   "ai pipeline failed",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
 	result, err := h.service.RunPipeline(c.Request.Context(), uint(userID), &req)
 	if err != nil {
-		status := http.StatusInternalServerError
+		c.JSON(pipelineErrStatus(err), response.ErrorResponse{
+			Error:   "ai pipeline failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) GetJob(c *gin.Context) {
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	job, err := h.service.GetJob(uint(userID), c.Param("job_id"))
+	if err != nil {
+		c.JSON(pipelineErrStatus(err), response.ErrorResponse{
+			Error:   "failed to get job",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// JobEvents streams the job's events as server-sent events, starting with
+// those already published, until the job finishes or the client leaves.
+func (h *Handler) JobEvents(c *gin.Context) {
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	history, events, unsubscribe, err := h.service.SubscribeJob(uint(userID), c.Param("job_id"))
+	if err != nil {
+		c.JSON(pipelineErrStatus(err), response.ErrorResponse{
+			Error:   "failed to get job",
+			Message: err.Error(),
+		})
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	for _, event := range history {
+		c.SSEvent(event.Type, event)
+	}
+	c.Writer.Flush()
+
+	c.Stream(func(io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+func (h *Handler) CancelJob(c *gin.Context) {
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	job, err := h.service.CancelJob(uint(userID), c.Param("job_id"))
+	if err != nil {
+		c.JSON(pipelineErrStatus(err), response.ErrorResponse{
+			Error:   "failed to cancel job",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *Handler) ListRuns(c *gin.Context) {
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	runs, err := h.service.ListRuns(c.Request.Context(), uint(userID), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error:   "failed to list runs",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+func (h *Handler) GetRun(c *gin.Context) {
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	run, err := h.service.GetRun(c.Request.Context(), uint(userID), c.Param("run_id"))
+	if err != nil {
+		c.JSON(pipelineErrStatus(err), response.ErrorResponse{
+			Error:   "failed to get run",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+func (h *Handler) RerunLayer(c *gin.Context) {
+	var req dto.RerunLayerRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	run, err := h.service.RerunLayer(c.Request.Context(), uint(userID), c.Param("run_id"), c.Param("layer"), &req)
+	if err != nil {
+		c.JSON(pipelineErrStatus(err), response.ErrorResponse{
+			Error:   "ai layer re-run failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, run)
+}
+
+func (h *Handler) SuggestDistractors(c *gin.Context) {
+	h.assistQuestion(c, h.service.SuggestDistractors)
+}
+
+func (h *Handler) RewriteQuestion(c *gin.Context) {
+	h.assistQuestion(c, h.service.RewriteQuestion)
+}
+
+func (h *Handler) TranslateQuestion(c *gin.Context) {
+	h.assistQuestion(c, h.service.TranslateQuestion)
+}
+
+type questionAssistFunc func(ctx context.Context, ownerID uint, questionID string, req *dto.QuestionAssistRequest) (*dto.QuestionAssistResponse, error)
+
+func (h *Handler) assistQuestion(c *gin.Context, assist questionAssistFunc) {
+	var req dto.QuestionAssistRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	result, err := assist(c.Request.Context(), uint(userID), c.Param("question_id"), &req)
+	if err != nil {
+		status := pipelineErrStatus(err)
 		switch {
-		case errors.Is(err, ErrUnsupportedProvider), errors.Is(err, ErrInvalidLayer):
+		case errors.Is(err, test.ErrQuestionNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, test.ErrForbidden):
+			status = http.StatusForbidden
+		case errors.Is(err, ErrQuestionUnsupported), errors.Is(err, ErrInvalidAssist):
 			status = http.StatusBadRequest
-		case errors.Is(err, ErrNoProviderConfigured):
-			status = http.StatusServiceUnavailable
-		case errors.Is(err, ErrLayerFailed):
-			status = http.StatusBadGateway
 		}
-
 		c.JSON(status, response.ErrorResponse{
-			Error:   "ai pipeline failed",
+			Error:   "question assist failed",
 			Message: err.Error(),
 		})
 		return
@@ -56,10 +274,126 @@ func (h *Handler) RunPipeline(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+func (h *Handler) PublishRun(c *gin.Context) {
+	var req dto.PublishRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	result, err := h.service.PublishRun(c.Request.Context(), uint(userID), authorFromCtx(c), c.Param("run_id"), &req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrRunNotFound), errors.Is(err, ErrVariantNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrNotPublishable):
+			status = http.StatusUnprocessableEntity
+		}
+
+		c.JSON(status, response.ErrorResponse{
+			Error:   "publish failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response.SuccessResponse{
+		Message: "test created from pipeline run",
+		Data:    result,
+	})
+}
+
 func (h *Handler) ListProviders(c *gin.Context) {
 	c.JSON(http.StatusOK, h.service.ProviderStatuses())
 }
 
+func (h *Handler) Usage(c *gin.Context) {
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	report, err := h.service.Usage(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error:   "failed to get usage",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// maxUploadBytes bounds a whole multipart pipeline request.
+const maxUploadBytes = 3 * MaxMaterialBytes
+
+func bindMultipartPipelineRequest(c *gin.Context, req *dto.PipelineRunRequest) error {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadBytes)
+	form, err := c.MultipartForm()
+	if err != nil {
+		return err
+	}
+	if raw := c.PostForm("request"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), req); err != nil {
+			return fmt.Errorf("request: %w", err)
+		}
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return err
+	}
+	for _, header := range form.File["files"] {
+		if header.Size > MaxMaterialBytes {
+			return fmt.Errorf("file %s is larger than %d bytes", header.Filename, MaxMaterialBytes)
+		}
+		f, err := header.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		req.Files = append(req.Files, dto.MaterialFile{Name: header.Filename, Data: data})
+	}
+	return nil
+}
+
+func pipelineErrStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrRunNotFound), errors.Is(err, ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, ErrUnsupportedProvider), errors.Is(err, ErrInvalidLayer), errors.Is(err, ErrInvalidMaterial),
+		errors.Is(err, ErrUnsupportedPromptLanguage):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrMaterialUnreadable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrBudgetExceeded):
+		return http.StatusPaymentRequired
+	case errors.Is(err, ErrTooManyJobs):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrNoProviderConfigured):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrLayerFailed):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
 func userIDFromCtx(c *gin.Context) (uint64, bool) {
 	val, ok := c.Get("user_id")
 	if !ok {
@@ -81,3 +415,12 @@ func userIDFromCtx(c *gin.Context) (uint64, bool) {
 	}
 	return 0, false
 }
+
+func authorFromCtx(c *gin.Context) string {
+	if email, ok := c.Get("email"); ok {
+		if s, ok := email.(string); ok && s != "" {
+			return s
+		}
+	}
+	return "AI pipeline"
+}
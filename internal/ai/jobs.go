@@ -0,0 +1,273 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"edu-system/internal/ai/dto"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrJobNotFound = errors.New("ai pipeline job not found")
+	ErrJobFinished = errors.New("ai pipeline job already finished")
+	ErrTooManyJobs = errors.New("too many ai pipeline jobs running")
+)
+
+const (
+	// jobRetention is how long finished jobs stay queryable.
+	jobRetention = time.Hour
+	// jobEventBuffer bounds the events queued for one subscriber; a client
+	// that falls further behind misses events but can still poll the job.
+	jobEventBuffer = 64
+	// maxRunningJobsPerOwner caps the jobs one user has queued or running;
+	// further jobs are refused until one finishes.
+	maxRunningJobsPerOwner = 3
+)
+
+func (s *service) StartPipelineJob(ctx context.Context, ownerID uint, req *dto.PipelineRunRequest) (*dto.JobView, error) {
+	state, orders, err := s.preparePipeline(ctx, ownerID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The job outlives the request that started it; only CancelJob stops it.
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	j, err := s.jobs.add(ownerID, cancel)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	state.notify = j.publish
+
+	go func() {
+		defer cancel()
+		j.start()
+		result, err := s.executePipeline(jobCtx, ownerID, state, orders)
+		switch {
+		case err == nil:
+			j.finish(dto.JobSucceeded, result.RunID, "")
+		case errors.Is(err, context.Canceled):
+			j.finish(dto.JobCanceled, "", err.Error())
+		default:
+			j.finish(dto.JobFailed, "", err.Error())
+		}
+	}()
+
+	view := j.snapshot()
+	return &view, nil
+}
+
+func (s *service) GetJob(ownerID uint, jobID string) (*dto.JobView, error) {
+	j, err := s.jobs.get(ownerID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	view := j.snapshot()
+	return &view, nil
+}
+
+// SubscribeJob returns the events published so far and a channel with the
+// following ones. The channel is closed once the job finishes; call
+// unsubscribe when done listening.
+func (s *service) SubscribeJob(ownerID uint, jobID string) ([]dto.PipelineEvent, <-chan dto.PipelineEvent, func(), error) {
+	j, err := s.jobs.get(ownerID, jobID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	history, events, unsubscribe := j.subscribe()
+	return history, events, unsubscribe, nil
+}
+
+func (s *service) CancelJob(ownerID uint, jobID string) (*dto.JobView, error) {
+	j, err := s.jobs.get(ownerID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	j.mu.Lock()
+	done := j.done
+	j.mu.Unlock()
+	if done {
+		return nil, ErrJobFinished
+	}
+	j.cancel()
+	view := j.snapshot()
+	return &view, nil
+}
+
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func newJobRegistry() *jobRegistry {
+	return &jobRegistry{jobs: make(map[string]*job)}
+}
+
+// add registers a job unless the owner already has maxRunningJobsPerOwner
+// unfinished ones.
+func (r *jobRegistry) add(ownerID uint, cancel context.CancelFunc) (*job, error) {
+	now := time.Now().UTC()
+	j := &job{
+		ownerID:     ownerID,
+		cancel:      cancel,
+		subscribers: make(map[chan dto.PipelineEvent]struct{}),
+		view: dto.JobView{
+			JobID:     uuid.New().String(),
+			Status:    dto.JobQueued,
+			CreatedAt: now,
+			Layers:    make([]dto.JobLayerStatus, 0, len(pipelineLayers)),
+		},
+	}
+	for _, layer := range pipelineLayers {
+		j.view.Layers = append(j.view.Layers, dto.JobLayerStatus{Layer: layer, Status: "pending"})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	running := 0
+	for id, old := range r.jobs {
+		old.mu.Lock()
+		expired := old.done && now.Sub(*old.view.FinishedAt) > jobRetention
+		if !old.done && old.ownerID == ownerID {
+			running++
+		}
+		old.mu.Unlock()
+		if expired {
+			delete(r.jobs, id)
+		}
+	}
+	if running >= maxRunningJobsPerOwner {
+		return nil, fmt.Errorf("%w: %d of %d", ErrTooManyJobs, running, maxRunningJobsPerOwner)
+	}
+	r.jobs[j.view.JobID] = j
+	return j, nil
+}
+
+func (r *jobRegistry) get(ownerID uint, id string) (*job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[id]
+	if !ok || j.ownerID != ownerID {
+		return nil, ErrJobNotFound
+	}
+	return j, nil
+}
+
+// job tracks one background pipeline run. Its view is updated from the
+// events runLayer publishes.
+type job struct {
+	mu          sync.Mutex
+	ownerID     uint
+	cancel      context.CancelFunc
+	view        dto.JobView
+	events      []dto.PipelineEvent
+	subscribers map[chan dto.PipelineEvent]struct{}
+	done        bool
+}
+
+func (j *job) start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.view.Status == dto.JobQueued {
+		j.view.Status = dto.JobRunning
+	}
+}
+
+func (j *job) publish(event dto.PipelineEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.apply(event)
+	j.broadcast(event)
+}
+
+func (j *job) apply(event dto.PipelineEvent) {
+	var layer *dto.JobLayerStatus
+	for i := range j.view.Layers {
+		if j.view.Layers[i].Layer == event.Layer {
+			layer = &j.view.Layers[i]
+		}
+	}
+	if layer == nil {
+		return
+	}
+	at := event.At
+	switch event.Type {
+	case dto.EventLayerStarted:
+		layer.Status = "running"
+		layer.StartedAt = &at
+	case dto.EventFallback:
+		layer.Fallbacks++
+	case dto.EventLayerCompleted:
+		layer.Status = "completed"
+		layer.Provider = event.Provider
+		layer.Model = event.Model
+		layer.FinishedAt = &at
+	case dto.EventLayerFailed:
+		layer.Status = "failed"
+		layer.FinishedAt = &at
+	}
+}
+
+func (j *job) broadcast(event dto.PipelineEvent) {
+	j.events = append(j.events, event)
+	for ch := range j.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (j *job) finish(status, runID, errMsg string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now().UTC()
+	j.view.Status = status
+	j.view.RunID = runID
+	j.view.Error = errMsg
+	j.view.FinishedAt = &now
+	j.broadcast(dto.PipelineEvent{
+		Type:    dto.EventJobFinished,
+		Status:  status,
+		RunID:   runID,
+		Message: errMsg,
+		At:      now,
+	})
+	j.done = true
+	for ch := range j.subscribers {
+		close(ch)
+		delete(j.subscribers, ch)
+	}
+}
+
+func (j *job) snapshot() dto.JobView {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	view := j.view
+	view.Layers = append([]dto.JobLayerStatus(nil), j.view.Layers...)
+	return view
+}
+
+func (j *job) subscribe() ([]dto.PipelineEvent, <-chan dto.PipelineEvent, func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	history := append([]dto.PipelineEvent(nil), j.events...)
+	ch := make(chan dto.PipelineEvent, jobEventBuffer)
+	if j.done {
+		close(ch)
+		return history, ch, func() {}
+	}
+	j.subscribers[ch] = struct{}{}
+	return history, ch, func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if _, ok := j.subscribers[ch]; ok {
+			delete(j.subscribers, ch)
+			close(ch)
+		}
+	}
+}
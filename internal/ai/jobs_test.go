@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"edu-system/internal/ai/dto"
+)
+
+type blockingProvider struct {
+	started chan struct{}
+	once    sync.Once
+}
+
+func (p *blockingProvider) Name() string       { return ProviderOpenAI }
+func (p *blockingProvider) Model() string      { return "slow" }
+func (p *blockingProvider) IsConfigured() bool { return true }
+
+func (p *blockingProvider) Complete(ctx context.Context, _ CompletionRequest) (*CompletionResponse, error) {
+	p.once.Do(func() { close(p.started) })
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestPipelineJobReportsEventsAndCancels(t *testing.T) {
+	provider := &blockingProvider{started: make(chan struct{})}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: provider}, []string{ProviderOpenAI})
+
+	job, err := svc.StartPipelineJob(context.Background(), 3, &dto.PipelineRunRequest{Material: dto.MaterialInput{Text: "material"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.GetJob(4, job.JobID); err != ErrJobNotFound {
+		t.Fatalf("expected ErrJobNotFound for another owner, got %v", err)
+	}
+
+	<-provider.started
+	history, events, unsubscribe, err := svc.SubscribeJob(3, job.JobID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer unsubscribe()
+	if len(history) != 1 || history[0].Type != dto.EventLayerStarted || history[0].Layer != layerPlan {
+		t.Fatalf("expected a layer_started event for plan, got %+v", history)
+	}
+
+	if _, err := svc.CancelJob(3, job.JobID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var last dto.PipelineEvent
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				done = true
+				break
+			}
+			last = event
+		case <-timeout:
+			t.Fatal("job did not finish after cancel")
+		}
+	}
+	if last.Type != dto.EventJobFinished || last.Status != dto.JobCanceled {
+		t.Fatalf("expected job_finished with canceled status, got %+v", last)
+	}
+
+	view, err := svc.GetJob(3, job.JobID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if view.Status != dto.JobCanceled || view.Layers[0].Status != "failed" || view.Layers[1].Status != "pending" {
+		t.Fatalf("unexpected job view: %+v", view)
+	}
+	if _, err := svc.CancelJob(3, job.JobID); err != ErrJobFinished {
+		t.Fatalf("expected ErrJobFinished, got %v", err)
+	}
+}
+
+func TestPipelineJobsAreCappedPerOwner(t *testing.T) {
+	provider := &blockingProvider{started: make(chan struct{})}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: provider}, []string{ProviderOpenAI})
+	req := &dto.PipelineRunRequest{Material: dto.MaterialInput{Text: "material"}}
+
+	var jobs []*dto.JobView
+	for range maxRunningJobsPerOwner {
+		job, err := svc.StartPipelineJob(context.Background(), 3, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	defer func() {
+		for _, job := range jobs {
+			svc.CancelJob(3, job.JobID)
+		}
+	}()
+	if _, err := svc.StartPipelineJob(context.Background(), 3, req); !errors.Is(err, ErrTooManyJobs) {
+		t.Fatalf("expected ErrTooManyJobs, got %v", err)
+	}
+	other, err := svc.StartPipelineJob(context.Background(), 4, req)
+	if err != nil {
+		t.Fatalf("another owner should not be limited: %v", err)
+	}
+	svc.CancelJob(4, other.JobID)
+
+	_, events, unsubscribe, err := svc.SubscribeJob(3, jobs[0].JobID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer unsubscribe()
+	if _, err := svc.CancelJob(3, jobs[0].JobID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case _, ok := <-events:
+			done = !ok
+		case <-timeout:
+			t.Fatal("job did not finish after cancel")
+		}
+	}
+	job, err := svc.StartPipelineJob(context.Background(), 3, req)
+	if err != nil {
+		t.Fatalf("a finished job should free its slot: %v", err)
+	}
+	jobs = append(jobs, job)
+}
@@ -0,0 +1,334 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"edu-system/internal/ai/dto"
+)
+
+var (
+	ErrInvalidMaterial    = errors.New("invalid material")
+	ErrMaterialUnreadable = errors.New("material could not be read")
+)
+
+const (
+	layerIngest = "ingest"
+
+	// MaxMaterialBytes bounds a fetched page or an uploaded file.
+	MaxMaterialBytes  = 10 << 20
+	maxMaterialChunks = 40
+)
+
+type ingestOptions struct {
+	maxChars   int
+	chunkChars int
+	client     *http.Client
+}
+
+// newMaterialClient fetches source URLs. Unless allowPrivate is set it
+// refuses to connect to loopback, private and link-local addresses, so that
+// material URLs cannot be used to reach internal services.
+func newMaterialClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+				ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+				return fmt.Errorf("refusing to fetch material from private address %s", host)
+			}
+			return nil
+		}
+		transport.Proxy = nil
+	}
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// ingestMaterial builds the material text of a run from the request text,
+// the source URL and uploaded files. Material longer than maxChars is
+// condensed chunk by chunk with the plan layer providers.
+func (s *service) ingestMaterial(ctx context.Context, st *pipelineState, order []string) error {
+	type part struct {
+		heading string
+		text    string
+	}
+	var (
+		parts  []part
+		report dto.MaterialIngestion
+	)
+	if st.material.Text != "" {
+		parts = append(parts, part{text: st.material.Text})
+		report.Sources = append(report.Sources, dto.MaterialSource{
+			Kind:  "text",
+			Chars: utf8.RuneCountInString(st.material.Text),
+		})
+	}
+	if st.material.SourceURL != "" {
+		text, contentType, err := s.fetchMaterial(ctx, st.material.SourceURL)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrMaterialUnreadable, st.material.SourceURL, err)
+		}
+		parts = append(parts, part{heading: st.material.SourceURL, text: text})
+		report.Sources = append(report.Sources, dto.MaterialSource{
+			Kind:        "url",
+			Name:        st.material.SourceURL,
+			ContentType: contentType,
+			Chars:       utf8.RuneCountInString(text),
+		})
+	}
+	for _, f := range st.files {
+		text, contentType, err := extractFile(f.Name, f.Data)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrMaterialUnreadable, f.Name, err)
+		}
+		parts = append(parts, part{heading: f.Name, text: text})
+		report.Sources = append(report.Sources, dto.MaterialSource{
+			Kind:        "file",
+			Name:        f.Name,
+			ContentType: contentType,
+			Chars:       utf8.RuneCountInString(text),
+		})
+	}
+
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if len(parts) > 1 && p.heading != "" {
+			texts = append(texts, "## "+p.heading+"\n\n"+p.text)
+			continue
+		}
+		texts = append(texts, p.text)
+	}
+	text := strings.Join(texts, "\n\n")
+	report.TotalChars = utf8.RuneCountInString(text)
+
+	if s.ingest.maxChars > 0 && report.TotalChars > s.ingest.maxChars {
+		chunks := chunkText(text, s.ingest.chunkChars)
+		if len(chunks) > maxMaterialChunks {
+			return fmt.Errorf("%w: material has %d characters, more than can be condensed", ErrInvalidMaterial, report.TotalChars)
+		}
+		condensed, traces, err := s.condenseMaterial(ctx, st, chunks, order)
+		report.Traces = traces
+		if err != nil {
+			return err
+		}
+		report.Chunks = len(chunks)
+		report.Condensed = true
+		report.ExtractedText = text
+		text = condensed
+	}
+
+	st.material.Text = text
+	st.ingestion = report
+	st.files = nil
+	return nil
+}
+
+func (s *service) condenseMaterial(ctx context.Context, st *pipelineState, chunks []string, order []string) (string, []dto.LayerProviderTrace, error) {
+	budget := s.ingest.maxChars / len(chunks)
+	summaries := make([]string, 0, len(chunks))
+	traces := make([]dto.LayerProviderTrace, 0, len(chunks))
+	for i, chunk := range chunks {
+		var out struct {
+			Summary string `json:"summary"`
+		}
+		p, err := s.prompts.render(layerIngest, st.cfg.PromptLanguage, promptData{
+			Chunk: promptChunk{Text: chunk, Index: i + 1, Total: len(chunks), MaxChars: budget},
+		})
+		if err != nil {
+			return "", traces, err
+		}
+		trace, err := s.runLayer(ctx, st.ownerID, layerIngest, order, st.notify, p, func(raw string) error {
+			if err := decodeModelJSON(raw, &out); err != nil {
+				return err
+			}
+			if strings.TrimSpace(out.Summary) == "" {
+				return errors.New("empty summary")
+			}
+			return nil
+		})
+		traces = append(traces, trace)
+		if err != nil {
+			return "", traces, err
+		}
+		summaries = append(summaries, strings.TrimSpace(out.Summary))
+	}
+	return strings.Join(summaries, "\n\n"), traces, nil
+}
+
+func (s *service) fetchMaterial(ctx context.Context, rawURL string) (string, string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", "", errors.New("only http and https URLs can be fetched")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("User-Agent", "edu-system material fetcher")
+	req.Header.Set("Accept", "text/html,text/plain,text/markdown,application/pdf,application/vnd.openxmlformats-officedocument.wordprocessingml.document;q=0.9,*/*;q=0.5")
+
+	resp, err := s.ingest.client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxMaterialBytes+1))
+	if err != nil {
+		return "", "", err
+	}
+	if len(data) > MaxMaterialBytes {
+		return "", "", fmt.Errorf("document is larger than %d bytes", MaxMaterialBytes)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	kind := documentKindForType(contentType)
+	if kind == "" {
+		kind = documentKindForName(parsed.Path)
+	}
+	if kind == "" {
+		sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+		kind = documentKindForType(sniffed)
+	}
+	if kind == "" {
+		return "", contentType, fmt.Errorf("unsupported content type %q", contentType)
+	}
+	text, err := extractDocument(kind, data)
+	return text, contentType, err
+}
+
+// extractFile reads an uploaded .txt, .md, .docx or .pdf document.
+func extractFile(name string, data []byte) (string, string, error) {
+	if len(data) > MaxMaterialBytes {
+		return "", "", fmt.Errorf("file is larger than %d bytes", MaxMaterialBytes)
+	}
+	kind := documentKindForName(name)
+	if kind == "" || kind == "html" {
+		return "", "", errors.New("unsupported file type (use .txt, .md, .docx or .pdf)")
+	}
+	text, err := extractDocument(kind, data)
+	return text, documentContentTypes[kind], err
+}
+
+var documentContentTypes = map[string]string{
+	"html": "text/html",
+	"text": "text/plain",
+	"docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"pdf":  "application/pdf",
+}
+
+func documentKindForType(contentType string) string {
+	switch strings.ToLower(contentType) {
+	case "text/html", "application/xhtml+xml":
+		return "html"
+	case "text/plain", "text/markdown", "text/x-markdown":
+		return "text"
+	case "application/pdf":
+		return "pdf"
+	case documentContentTypes["docx"]:
+		return "docx"
+	}
+	return ""
+}
+
+func documentKindForName(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".html", ".htm":
+		return "html"
+	case ".txt", ".md", ".markdown":
+		return "text"
+	case ".pdf":
+		return "pdf"
+	case ".docx":
+		return "docx"
+	}
+	return ""
+}
+
+func extractDocument(kind string, data []byte) (string, error) {
+	var (
+		text string
+		err  error
+	)
+	switch kind {
+	case "html":
+		text = htmlToText(strings.ToValidUTF8(string(data), ""))
+	case "text":
+		if !utf8.Valid(data) {
+			return "", errors.New("text is not valid UTF-8")
+		}
+		text = tidyText(string(data))
+	case "docx":
+		text, err = docxText(data)
+	case "pdf":
+		text, err = pdfText(data)
+	default:
+		return "", fmt.Errorf("unsupported document kind %q", kind)
+	}
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return "", errors.New("document contains no text")
+	}
+	return text, nil
+}
+
+// chunkText splits text into pieces of at most size characters, cutting at
+// paragraph breaks where possible.
+func chunkText(text string, size int) []string {
+	if size <= 0 || utf8.RuneCountInString(text) <= size {
+		return []string{text}
+	}
+	var (
+		chunks  []string
+		current strings.Builder
+		length  int
+	)
+	flush := func() {
+		if length > 0 {
+			chunks = append(chunks, strings.TrimSpace(current.String()))
+			current.Reset()
+			length = 0
+		}
+	}
+	for _, para := range strings.Split(text, "\n\n") {
+		runes := []rune(para)
+		for len(runes) > size {
+			flush()
+			chunks = append(chunks, string(runes[:size]))
+			runes = runes[size:]
+		}
+		if length > 0 && length+2+len(runes) > size {
+			flush()
+		}
+		if length > 0 {
+			current.WriteString("\n\n")
+			length += 2
+		}
+		current.WriteString(string(runes))
+		length += len(runes)
+	}
+	flush()
+	return chunks
+}
@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"edu-system/internal/ai/dto"
+)
+
+func TestHTMLToTextDropsMarkupAndScripts(t *testing.T) {
+	page := `<html><head><title>x</title><style>p{}</style></head><body>
+<nav>Menu</nav><h1>Matrices</h1><script>alert(1)</script>
+<p>A matrix is a&nbsp;table &amp; more.</p><ul><li>Rows</li><li>Columns</li></ul></body></html>`
+
+	got := htmlToText(page)
+	want := "Matrices\n\nA matrix is a table & more.\n\n- Rows\n- Columns"
+	if got != want {
+		t.Fatalf("unexpected text:\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestDocxText(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("word/document.xml")
+	w.Write([]byte(`<?xml version="1.0"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>First</w:t></w:r><w:r><w:t xml:space="preserve"> paragraph</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Second</w:t></w:r></w:p></w:body></w:document>`))
+	zw.Close()
+
+	got, err := docxText(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "First paragraph\nSecond" {
+		t.Fatalf("unexpected text: %q", got)
+	}
+}
+
+func TestDocxTextRejectsZipBomb(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("word/document.xml")
+	w.Write([]byte(`<w:document><w:body><w:p><w:r><w:t>`))
+	w.Write(bytes.Repeat([]byte("a"), maxDecompressedBytes))
+	w.Write([]byte(`</w:t></w:r></w:p></w:body></w:document>`))
+	zw.Close()
+
+	if _, err := docxText(buf.Bytes()); !errors.Is(err, errDecompressedTooLarge) {
+		t.Fatalf("expected the archive to be rejected, got %v", err)
+	}
+}
+
+func TestPDFTextReadsSimpleContentStream(t *testing.T) {
+	content := "BT /F1 12 Tf 72 700 Td (Linear algebra studies vectors) Tj 0 -14 Td [(and ) -250 (matrices.)] TJ ET"
+	pdf := "%PDF-1.4\n1 0 obj\n<< >>\nstream\n" + content + "\nendstream\nendobj\n%%EOF"
+
+	got, err := pdfText([]byte(pdf))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "Linear algebra studies vectors\nand matrices." {
+		t.Fatalf("unexpected text: %q", got)
+	}
+}
+
+func TestPDFTextCapsTotalStreamSize(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(bytes.Repeat([]byte(" "), 8<<20))
+	zw.Close()
+	stream := "1 0 obj\n<< /Filter /FlateDecode >>\nstream\n" + compressed.String() + "\nendstream\nendobj\n"
+	pdf := "%PDF-1.4\n" + strings.Repeat(stream, 5) + "%%EOF"
+
+	if _, err := pdfText([]byte(pdf)); !errors.Is(err, errDecompressedTooLarge) {
+		t.Fatalf("expected the streams to exceed the cap together, got %v", err)
+	}
+}
+
+func TestChunkTextKeepsParagraphs(t *testing.T) {
+	text := strings.Repeat("a", 8) + "\n\n" + strings.Repeat("b", 8) + "\n\n" + strings.Repeat("c", 25)
+	chunks := chunkText(text, 20)
+	want := []string{"aaaaaaaa\n\nbbbbbbbb", strings.Repeat("c", 20), "ccccc"}
+	if len(chunks) != len(want) {
+		t.Fatalf("unexpected chunks: %q", chunks)
+	}
+	for i := range want {
+		if chunks[i] != want[i] {
+			t.Fatalf("chunk %d: got %q, want %q", i, chunks[i], want[i])
+		}
+	}
+}
+
+func TestIngestMaterialFetchesURLAndCondenses(t *testing.T) {
+	page := "<p>" + strings.Repeat("Vectors have direction. ", 10) + "</p>" // 239 characters of text
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	provider := &mockProvider{
+		name:       ProviderOpenAI,
+		configured: true,
+		responses: []mockProviderResponse{
+			{text: `{"summary":"vectors"}`},
+			{text: `{"summary":"notes"}`},
+		},
+	}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: provider}, []string{ProviderOpenAI})
+	svc.ingest.maxChars = 100
+	svc.ingest.chunkChars = 280
+
+	st := &pipelineState{
+		material: dto.MaterialInput{SourceURL: srv.URL + "/lesson"},
+		files:    []dto.MaterialFile{{Name: "notes.md", Data: []byte("# Notes\n\nMore on vectors.")}},
+	}
+	if err := svc.ingestMaterial(context.Background(), st, []string{ProviderOpenAI}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st.material.Text != "vectors\n\nnotes" {
+		t.Fatalf("expected condensed text, got %q", st.material.Text)
+	}
+	report := st.ingestion
+	if !report.Condensed || report.Chunks != 2 || len(report.Traces) != 2 || len(report.Sources) != 2 {
+		t.Fatalf("unexpected ingestion report: %+v", report)
+	}
+	if report.Sources[0].Kind != "url" || report.Sources[0].ContentType != "text/html" || report.Sources[1].Name != "notes.md" {
+		t.Fatalf("unexpected sources: %+v", report.Sources)
+	}
+	if !strings.Contains(report.ExtractedText, "## notes.md") {
+		t.Fatalf("expected extracted text to keep file headings, got %q", report.ExtractedText)
+	}
+
+	blocked := newServiceWithProviders(nil, nil)
+	blocked.ingest.client = newMaterialClient(0, false)
+	st = &pipelineState{material: dto.MaterialInput{SourceURL: srv.URL}}
+	if err := blocked.ingestMaterial(context.Background(), st, nil); err == nil || !strings.Contains(err.Error(), "private address") {
+		t.Fatalf("expected loopback fetch to be refused, got %v", err)
+	}
+}
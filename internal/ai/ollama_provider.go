@@ -0,0 +1,155 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ollamaProvider talks to the native Ollama /api/chat endpoint, for local
+// models served without the OpenAI-compatible shim.
+type ollamaProvider struct {
+	name       string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+func newOllamaProvider(name, baseURL, model string, httpClient *http.Client) Provider {
+	return &ollamaProvider{
+		name:       strings.ToLower(strings.TrimSpace(name)),
+		baseURL:    strings.TrimRight(strings.TrimSpace(baseURL), "/"),
+		model:      strings.TrimSpace(model),
+		httpClient: httpClient,
+	}
+}
+
+func (p *ollamaProvider) Name() string {
+	return p.name
+}
+
+func (p *ollamaProvider) Model() string {
+	return p.model
+}
+
+func (p *ollamaProvider) IsConfigured() bool {
+	return p.baseURL != "" && p.model != ""
+}
+
+func (p *ollamaProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	return p.Stream(ctx, req, nil)
+}
+
+// Stream reads the newline-delimited JSON chunks of a streamed chat; the
+// final chunk carries the token counts.
+func (p *ollamaProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	if !p.IsConfigured() {
+		return nil, fmt.Errorf("provider %s is not configured", p.name)
+	}
+
+	body := ollamaChatRequest{
+		Model:    p.model,
+		Messages: req.Messages,
+		Stream:   onDelta != nil,
+		Options:  ollamaOptions{Temperature: req.Temperature},
+	}
+	if req.RequireJSON {
+		body.Format = "json"
+	}
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+		return nil, fmt.Errorf("provider %s returned status %d: %s", p.name, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var (
+		text  strings.Builder
+		model string
+		usage Usage
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("provider %s invalid response: %w", p.name, err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("provider %s error: %s", p.name, chunk.Error)
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Message.Content != "" {
+			text.WriteString(chunk.Message.Content)
+			if onDelta != nil {
+				onDelta(chunk.Message.Content)
+			}
+		}
+		if chunk.Done {
+			usage = Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(text.String())
+	if content == "" {
+		return nil, fmt.Errorf("provider %s returned empty content", p.name)
+	}
+	if model == "" {
+		model = p.model
+	}
+	return &CompletionResponse{Text: content, Model: model, Usage: usage}, nil
+}
+
+type ollamaChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Format   string        `json:"format,omitempty"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+}
+
+type ollamaChatResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
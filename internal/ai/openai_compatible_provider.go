@@ -59,6 +59,74 @@ func (p *openAICompatibleProvider) IsConfigured() bool {
 }
 
 func (p *openAICompatibleProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	resp, err := p.send(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var payload openAIChatResponse
+	if err := json.Unmarshal(respBody, &payload); err != nil {
+		return nil, fmt.Errorf("provider %s invalid response: %w", p.name, err)
+	}
+
+	if len(payload.Choices) == 0 {
+		return nil, fmt.Errorf("provider %s returned no choices", p.name)
+	}
+
+	return p.completion(payload.Choices[0].Message.Content, payload.Model, payload.Usage)
+}
+
+// Stream asks for a server-sent event stream. Usage arrives in the last
+// chunk when the server honours stream_options.include_usage.
+func (p *openAICompatibleProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	resp, err := p.send(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		text  strings.Builder
+		model string
+		usage *openAIUsage
+	)
+	err = readServerSentEvents(resp.Body, func(data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+		var chunk openAIChatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("provider %s invalid stream chunk: %w", p.name, err)
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				text.WriteString(choice.Delta.Content)
+				if onDelta != nil {
+					onDelta(choice.Delta.Content)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.completion(text.String(), model, usage)
+}
+
+func (p *openAICompatibleProvider) send(ctx context.Context, req CompletionRequest, stream bool) (*http.Response, error) {
 	if !p.IsConfigured() {
 		return nil, fmt.Errorf("provider %s is not configured", p.name)
 	}
@@ -67,10 +135,14 @@ func (p *openAICompatibleProvider) Complete(ctx context.Context, req CompletionR
 		Model:       p.model,
 		Messages:    req.Messages,
 		Temperature: req.Temperature,
+		Stream:      stream,
 	}
 	if req.RequireJSON {
 		body.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
 	}
+	if stream {
+		body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
 
 	raw, err := json.Marshal(body)
 	if err != nil {
@@ -94,37 +166,31 @@ func (p *openAICompatibleProvider) Complete(ctx context.Context, req CompletionR
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
-	if err != nil {
-		return nil, err
-	}
 
 	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
 		return nil, fmt.Errorf("provider %s returned status %d: %s", p.name, resp.StatusCode, strings.TrimSpace(string(respBody)))
 	}
+	return resp, nil
+}
 
-	var payload openAIChatResponse
-	if err := json.Unmarshal(respBody, &payload); err != nil {
-		return nil, fmt.Errorf("provider %s invalid response: %w", p.name, err)
-	}
-
-	if len(payload.Choices) == 0 {
-		return nil, fmt.Errorf("provider %s returned no choices", p.name)
-	}
-
-	content := strings.TrimSpace(payload.Choices[0].Message.Content)
+func (p *openAICompatibleProvider) completion(content, model string, usage *openAIUsage) (*CompletionResponse, error) {
+	content = strings.TrimSpace(content)
 	if content == "" {
 		return nil, fmt.Errorf("provider %s returned empty content", p.name)
 	}
 
-	model := strings.TrimSpace(payload.Model)
+	model = strings.TrimSpace(model)
 	if model == "" {
 		model = p.model
 	}
 
-	return &CompletionResponse{Text: content, Model: model}, nil
+	out := &CompletionResponse{Text: content, Model: model}
+	if usage != nil {
+		out.Usage = Usage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens}
+	}
+	return out, nil
 }
 
 type openAIChatRequest struct {
@@ -132,6 +198,12 @@ type openAIChatRequest struct {
 	Messages       []Message             `json:"messages"`
 	Temperature    float64               `json:"temperature,omitempty"`
 	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type openAIResponseFormat struct {
@@ -145,4 +217,20 @@ type openAIChatResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type openAIChatStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
 }
@@ -0,0 +1,272 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+
+	"edu-system/internal/ai/dto"
+	"edu-system/internal/testAttempt"
+)
+
+// The prompts live in templates/<language>/<layer>.tmpl. Each file defines
+// a "system" and a "user" template and may define "version"; files without
+// one are versioned by a hash of their content. repair.tmpl only defines
+// "user", the follow-up sent after a rejected answer.
+//
+//go:embed templates
+var embeddedPrompts embed.FS
+
+var ErrUnsupportedPromptLanguage = errors.New("no prompt templates for language")
+
+// promptRepair names the template of the repair message.
+const promptRepair = "repair"
+
+// promptNames lists the templates every language must provide.
+var promptNames = append(slices.Clone(pipelineLayers),
+	layerIngest, layerGrade, layerDistractors, layerRewrite, layerTranslate, promptRepair)
+
+// promptData is what the templates render. Each prompt reads its own fields;
+// outputs of pipeline layers that have not run yet are zero values.
+type promptData struct {
+	Material   dto.MaterialInput
+	Config     dto.GenerationConfig
+	Plan       dto.PlanOutput
+	Draft      dto.GenerationOutput
+	Validation dto.ValidationOutput
+
+	// Chunk is the material fragment the ingest layer condenses.
+	Chunk promptChunk
+	// Grading is the answer the grade layer scores.
+	Grading promptGrading
+	// Question, Count and Language describe a question assist request.
+	Question promptQuestion
+	Count    int
+	Language string
+	// Issues are the reasons a repair message gives.
+	Issues []string
+}
+
+type promptChunk struct {
+	Text     string
+	Index    int
+	Total    int
+	MaxChars int
+}
+
+type promptGrading struct {
+	testAttempt.GradingRequest
+	// PassedTests counts the passed CodeResults.
+	PassedTests int
+}
+
+// prompt is a rendered prompt with the template language and version it
+// came from, so that repairs stay in its language and traces name it.
+type prompt struct {
+	messages []Message
+	language string
+	version  string
+}
+
+type promptTemplate struct {
+	tmpl    *template.Template
+	version string
+}
+
+type promptLibrary struct {
+	defaultLanguage string
+	templates       map[string]map[string]*promptTemplate
+}
+
+// loadPromptLibrary parses the embedded templates and then the ones under
+// dir, which replace embedded files of the same language and layer or add
+// new languages. A broken override is logged and skipped, so the embedded
+// prompt stays in use.
+func loadPromptLibrary(dir, defaultLanguage string) *promptLibrary {
+	lib := &promptLibrary{templates: map[string]map[string]*promptTemplate{}}
+
+	embedded, err := fs.Sub(embeddedPrompts, "templates")
+	if err != nil {
+		panic(err)
+	}
+	for _, err := range lib.load(embedded) {
+		panic(fmt.Sprintf("ai: embedded prompt template: %v", err))
+	}
+	if dir != "" {
+		for _, err := range lib.load(os.DirFS(dir)) {
+			log.Printf("AI prompts: skipping override in %s: %v", dir, err)
+		}
+	}
+
+	for lang, layers := range lib.templates {
+		for _, name := range promptNames {
+			if layers[name] == nil {
+				log.Printf("AI prompts: language %q has no %s template, dropping it", lang, name)
+				delete(lib.templates, lang)
+				break
+			}
+		}
+	}
+
+	lib.defaultLanguage = promptLanguageKey(defaultLanguage)
+	if lib.templates[lib.defaultLanguage] == nil {
+		log.Printf("AI prompts: no templates for default language %q, using ru", defaultLanguage)
+		lib.defaultLanguage = "ru"
+	}
+	return lib
+}
+
+func (l *promptLibrary) load(fsys fs.FS) []error {
+	files, err := fs.Glob(fsys, "*/*.tmpl")
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, file := range files {
+		lang := promptLanguageKey(path.Dir(file))
+		layer := strings.TrimSuffix(path.Base(file), ".tmpl")
+		if !slices.Contains(promptNames, layer) {
+			errs = append(errs, fmt.Errorf("%s: unknown layer %q", file, layer))
+			continue
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pt, err := parsePromptTemplate(lang, layer, data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+		if l.templates[lang] == nil {
+			l.templates[lang] = map[string]*promptTemplate{}
+		}
+		l.templates[lang][layer] = pt
+	}
+	return errs
+}
+
+func parsePromptTemplate(lang, layer string, data []byte) (*promptTemplate, error) {
+	tmpl, err := template.New(layer).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"json": toPromptJSON}).
+		Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	parts := []string{"system", "user"}
+	if layer == promptRepair {
+		parts = []string{"user"}
+	}
+	for _, name := range parts {
+		if tmpl.Lookup(name) == nil {
+			return nil, fmt.Errorf("template %q is not defined", name)
+		}
+		// Rendering once with empty data catches references to fields
+		// that promptData does not have.
+		if err := tmpl.ExecuteTemplate(io.Discard, name, promptData{}); err != nil {
+			return nil, err
+		}
+	}
+
+	version := ""
+	if tmpl.Lookup("version") != nil {
+		var b strings.Builder
+		if err := tmpl.ExecuteTemplate(&b, "version", nil); err != nil {
+			return nil, err
+		}
+		version = strings.TrimSpace(b.String())
+	}
+	if version == "" {
+		sum := sha256.Sum256(data)
+		version = "sha256:" + hex.EncodeToString(sum[:4])
+	}
+	return &promptTemplate{tmpl: tmpl, version: fmt.Sprintf("%s/%s@%s", layer, lang, version)}, nil
+}
+
+// resolveLanguage picks the template language of a run: the requested one,
+// which must exist, else the output language when templates cover it, else
+// the deployment default.
+func (l *promptLibrary) resolveLanguage(requested, outputLanguage string) (string, error) {
+	if lang := promptLanguageKey(requested); lang != "" {
+		if l.templates[lang] == nil {
+			return "", fmt.Errorf("%w: %s", ErrUnsupportedPromptLanguage, requested)
+		}
+		return lang, nil
+	}
+	if lang := promptLanguageKey(outputLanguage); l.templates[lang] != nil {
+		return lang, nil
+	}
+	return l.defaultLanguage, nil
+}
+
+// render builds the messages of a layer. Runs saved before templates
+// existed carry no language and fall back to the default, as do prompts
+// outside the pipeline, which are rendered with an empty language.
+func (l *promptLibrary) render(layer, language string, data promptData) (prompt, error) {
+	lang := promptLanguageKey(language)
+	if l.templates[lang] == nil {
+		lang = l.defaultLanguage
+	}
+	pt := l.templates[lang][layer]
+	if pt == nil {
+		return prompt{}, fmt.Errorf("%w: %s", ErrInvalidLayer, layer)
+	}
+
+	var system, user strings.Builder
+	if err := pt.tmpl.ExecuteTemplate(&system, "system", data); err != nil {
+		return prompt{}, fmt.Errorf("render %s prompt: %w", pt.version, err)
+	}
+	if err := pt.tmpl.ExecuteTemplate(&user, "user", data); err != nil {
+		return prompt{}, fmt.Errorf("render %s prompt: %w", pt.version, err)
+	}
+	return prompt{
+		messages: []Message{
+			{Role: "system", Content: strings.TrimSpace(system.String())},
+			{Role: "user", Content: user.String()},
+		},
+		language: lang,
+		version:  pt.version,
+	}, nil
+}
+
+// repair builds the message that sends a rejected answer back with the
+// reasons, in the language of the prompt that produced it.
+func (l *promptLibrary) repair(language string, problem error) Message {
+	var issues OutputIssues
+	data := promptData{Issues: []string{problem.Error()}}
+	if errors.As(problem, &issues) {
+		data.Issues = issues
+	}
+
+	lang := promptLanguageKey(language)
+	if l.templates[lang] == nil {
+		lang = l.defaultLanguage
+	}
+	var b strings.Builder
+	if err := l.templates[lang][promptRepair].tmpl.ExecuteTemplate(&b, "user", data); err != nil {
+		// The template rendered at load time; keep the reasons if it fails now.
+		return Message{Role: "user", Content: problem.Error()}
+	}
+	return Message{Role: "user", Content: strings.TrimSpace(b.String())}
+}
+
+// promptLanguageKey reduces a language tag such as "en-US" to "en".
+func promptLanguageKey(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
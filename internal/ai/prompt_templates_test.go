@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"edu-system/internal/ai/dto"
+	"edu-system/internal/testAttempt"
+)
+
+func TestPipelinePromptsFollowLanguageAndOverrides(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "en"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	override := `{{define "system"}}Plan briefly.{{end}}{{define "user"}}{{json .Material.Title}}{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "en", "plan.tmpl"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	broken := `{{define "system"}}{{.Missing}}{{end}}{{define "user"}}{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "en", "refine.tmpl"), []byte(broken), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &mockProvider{
+		name:       ProviderOpenAI,
+		model:      "model",
+		configured: true,
+		responses: []mockProviderResponse{
+			{text: validPlanJSON},
+			{text: validDraftJSON},
+			{text: validValidationJSON},
+			{text: validFinalJSON("ready")},
+		},
+	}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: provider}, []string{ProviderOpenAI})
+	svc.prompts = loadPromptLibrary(dir, "ru")
+
+	cfg := oneQuestionConfig
+	cfg.OutputLanguage = "en-US"
+	resp, err := svc.RunPipeline(context.Background(), 1, &dto.PipelineRunRequest{
+		Material:         dto.MaterialInput{Title: "Photosynthesis", Text: "material"},
+		GenerationConfig: cfg,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	plan := provider.requests[0].Messages
+	if plan[0].Content != "Plan briefly." || plan[1].Content != `"Photosynthesis"` {
+		t.Fatalf("plan layer did not use the override: %+v", plan)
+	}
+	if !strings.HasPrefix(resp.ProviderTrace[0].PromptVersion, "plan/en@sha256:") {
+		t.Fatalf("unexpected plan prompt version %q", resp.ProviderTrace[0].PromptVersion)
+	}
+	if got := resp.ProviderTrace[3].PromptVersion; got != "refine/en@1" {
+		t.Fatalf("broken override should leave the embedded template in use, got %q", got)
+	}
+
+	_, err = svc.RunPipeline(context.Background(), 1, &dto.PipelineRunRequest{
+		Material:         dto.MaterialInput{Text: "material"},
+		GenerationConfig: dto.GenerationConfig{PromptLanguage: "de"},
+	})
+	if !errors.Is(err, ErrUnsupportedPromptLanguage) {
+		t.Fatalf("expected ErrUnsupportedPromptLanguage, got %v", err)
+	}
+}
+
+func TestGradingPromptUsesDefaultLanguageTemplates(t *testing.T) {
+	provider := &mockProvider{
+		name:       ProviderOpenAI,
+		model:      "grader",
+		configured: true,
+		responses: []mockProviderResponse{
+			{text: `{"score": 5, "rationale": "Correct."}`},
+			{text: `{"score": 1, "rationale": "One test fails."}`},
+		},
+	}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: provider}, []string{ProviderOpenAI})
+	svc.prompts = loadPromptLibrary("", "en")
+
+	suggestion, err := svc.SuggestGrade(context.Background(), 3, testAttempt.GradingRequest{
+		QuestionText: "Print the sum of two numbers.",
+		Kind:         "code",
+		Lang:         "python",
+		Answer:       "print(sum(map(int, input().split())))",
+		CodeResults:  []testAttempt.CodeCaseResult{{Passed: true}, {Passed: false}},
+		MaxScore:     2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if suggestion.PromptVersion != "grade/en@1" {
+		t.Fatalf("unexpected prompt version %q", suggestion.PromptVersion)
+	}
+	prompt := provider.requests[0].Messages[1].Content
+	if !strings.Contains(prompt, "No reference given.") || !strings.Contains(prompt, "Language: python\n") ||
+		!strings.Contains(prompt, "Automated tests: 1 of 2 passed.") || !strings.Contains(prompt, "Maximum score: 2\n") {
+		t.Fatalf("unexpected grading prompt: %s", prompt)
+	}
+	repair := provider.requests[1].Messages
+	if last := repair[len(repair)-1].Content; !strings.HasPrefix(last, "Your answer failed the checks:\n- ") {
+		t.Fatalf("expected the repair message in English, got %q", last)
+	}
+}
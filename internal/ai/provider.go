@@ -1,22 +1,36 @@
 package ai
 
-import "context"
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"edu-system/internal/platform"
+)
 
 const (
 	ProviderOpenAI     = "openai"
 	ProviderGemini     = "gemini"
+	ProviderAnthropic  = "anthropic"
 	ProviderDeepSeek   = "deepseek"
 	ProviderOpenRouter = "openrouter"
+	ProviderOllama     = "ollama"
 	ProviderLocal      = "local"
 )
 
-var supportedProviders = map[string]struct{}{
-	ProviderOpenAI:     {},
-	ProviderGemini:     {},
-	ProviderDeepSeek:   {},
-	ProviderOpenRouter: {},
-	ProviderLocal:      {},
-}
+// Kinds of providers that can be registered through AI_PROVIDERS_JSON.
+const (
+	providerKindOpenAI    = "openai"
+	providerKindAnthropic = "anthropic"
+	providerKindOllama    = "ollama"
+)
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
 
 type Message struct {
 	Role    string `json:"role"`
@@ -32,6 +46,14 @@ type CompletionRequest struct {
 type CompletionResponse struct {
 	Text  string
 	Model string
+	Usage Usage
+}
+
+// Usage is the token count a provider reports for one completion. Providers
+// that report nothing leave it zero.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
 }
 
 type Provider interface {
@@ -40,3 +62,69 @@ type Provider interface {
 	IsConfigured() bool
 	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
 }
+
+// StreamingProvider is implemented by providers that can deliver a
+// completion piece by piece. onDelta receives the text as it arrives; the
+// returned response is the same as Complete would give.
+type StreamingProvider interface {
+	Provider
+	Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error)
+}
+
+// newConfiguredProvider builds a provider registered through
+// AI_PROVIDERS_JSON.
+func newConfiguredProvider(cfg platform.AIProviderConfig, maxTokens int, client *http.Client) (Provider, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.Name))
+	if !providerNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid provider name %q", cfg.Name)
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Kind)) {
+	case "", providerKindOpenAI:
+		return newOpenAICompatibleProvider(name, cfg.BaseURL, cfg.Model, cfg.APIKey, cfg.AuthOptional, cfg.Headers, client), nil
+	case providerKindAnthropic:
+		return newAnthropicProvider(name, cfg.BaseURL, cfg.Model, cfg.APIKey, maxTokens, client), nil
+	case providerKindOllama:
+		return newOllamaProvider(name, cfg.BaseURL, cfg.Model, client), nil
+	}
+	return nil, fmt.Errorf("provider %s has unknown kind %q", name, cfg.Kind)
+}
+
+// errStreamDone ends readServerSentEvents early without an error.
+var errStreamDone = errors.New("stream done")
+
+// readServerSentEvents calls handle with the data of every event in body.
+func readServerSentEvents(body io.Reader, handle func(data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		err := handle(strings.Join(data, "\n"))
+		data = data[:0]
+		return err
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if err := dispatch(); err != nil {
+				if errors.Is(err, errStreamDone) {
+					return nil
+				}
+				return err
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := dispatch(); err != nil && !errors.Is(err, errStreamDone) {
+		return err
+	}
+	return nil
+}
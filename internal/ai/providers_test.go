@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"edu-system/internal/ai/runs"
+	"edu-system/internal/platform"
+)
+
+func TestAnthropicProvider(t *testing.T) {
+	var got anthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "key" || r.Header.Get("anthropic-version") == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		got = anthropicRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		if got.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-x\",\"usage\":{\"input_tokens\":9}}}\n\n")
+			fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"{\\\"a\\\":\"}}\n\n")
+			fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"1}\"}}\n\n")
+			fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":4}}\n\n")
+			fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+			return
+		}
+		fmt.Fprint(w, `{"model":"claude-x","content":[{"type":"text","text":"{\"a\":1}"}],"stop_reason":"end_turn","usage":{"input_tokens":11,"output_tokens":3}}`)
+	}))
+	defer server.Close()
+
+	provider := newAnthropicProvider(ProviderAnthropic, server.URL, "claude-x", "key", 0, server.Client()).(StreamingProvider)
+	req := CompletionRequest{Messages: []Message{
+		{Role: "system", Content: "be strict"},
+		{Role: "user", Content: "task"},
+		{Role: "assistant", Content: "{}"},
+		{Role: "user", Content: "fix"},
+		{Role: "user", Content: "again"},
+	}}
+
+	resp, err := provider.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text != `{"a":1}` || resp.Model != "claude-x" || resp.Usage != (Usage{11, 3}) {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if got.System != "be strict" || got.MaxTokens != 8192 || len(got.Messages) != 3 || got.Messages[2].Content != "fix\n\nagain" {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+
+	deltas := 0
+	resp, err = provider.Stream(context.Background(), req, func(string) { deltas++ })
+	if err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+	if resp.Text != `{"a":1}` || resp.Usage != (Usage{9, 4}) || deltas != 2 {
+		t.Fatalf("unexpected streamed response: %+v after %d deltas", resp, deltas)
+	}
+}
+
+func TestOllamaProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ollamaChatRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/api/chat" || body.Format != "json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if body.Stream {
+			fmt.Fprintln(w, `{"model":"llama","message":{"content":"{\"a\":"},"done":false}`)
+			fmt.Fprintln(w, `{"model":"llama","message":{"content":"1}"},"done":false}`)
+			fmt.Fprintln(w, `{"model":"llama","message":{"content":""},"done":true,"prompt_eval_count":6,"eval_count":2}`)
+			return
+		}
+		fmt.Fprint(w, `{"model":"llama","message":{"content":"{\"a\":1}"},"done":true,"prompt_eval_count":6,"eval_count":2}`)
+	}))
+	defer server.Close()
+
+	provider := newOllamaProvider(ProviderOllama, server.URL, "llama", server.Client()).(StreamingProvider)
+	req := CompletionRequest{Messages: []Message{{Role: "user", Content: "hi"}}, RequireJSON: true}
+
+	resp, err := provider.Complete(context.Background(), req)
+	if err != nil || resp.Text != `{"a":1}` || resp.Usage != (Usage{6, 2}) {
+		t.Fatalf("unexpected response: %+v, %v", resp, err)
+	}
+	deltas := 0
+	resp, err = provider.Stream(context.Background(), req, func(string) { deltas++ })
+	if err != nil || resp.Text != `{"a":1}` || resp.Usage != (Usage{6, 2}) || deltas != 2 {
+		t.Fatalf("unexpected streamed response: %+v, %v after %d deltas", resp, err, deltas)
+	}
+}
+
+func TestConfiguredProvidersAreRegistered(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer team-key" || r.Header.Get("X-Team") != "edu" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"model":"team-model","choices":[{"message":{"content":"{\"a\":1}"}}]}`)
+	}))
+	defer server.Close()
+
+	cfg := &platform.Config{
+		AIProviderOrder: []string{"team", ProviderLocal},
+		AIExtraProviders: []platform.AIProviderConfig{
+			{Name: "Team", BaseURL: server.URL + "/openai/v1", Model: "team-model", APIKey: "team-key", Headers: map[string]string{"X-Team": "edu"}},
+			{Name: "claude-eu", Kind: "anthropic", BaseURL: server.URL, Model: "m", APIKey: "k"},
+			{Name: ProviderOpenAI, BaseURL: server.URL, Model: "m"},
+			{Name: "bad name", BaseURL: server.URL, Model: "m"},
+			{Name: "other", Kind: "grpc", BaseURL: server.URL, Model: "m"},
+		},
+	}
+	svc := NewService(cfg, runs.NewMemoryStore(), nil).(*service)
+
+	if _, ok := svc.providers["claude-eu"].(*anthropicProvider); !ok {
+		t.Fatal("expected claude-eu to be registered as an anthropic provider")
+	}
+	for _, name := range []string{"bad name", "other"} {
+		if _, ok := svc.providers[name]; ok {
+			t.Fatalf("expected %q to be skipped", name)
+		}
+	}
+	if _, ok := svc.providers[ProviderOpenAI].(*openAICompatibleProvider); !ok || svc.providers[ProviderOpenAI].Model() == "m" {
+		t.Fatal("expected the built-in openai provider to be kept")
+	}
+	if len(svc.defaultOrder) != 2 || svc.defaultOrder[0] != "team" {
+		t.Fatalf("unexpected default order: %v", svc.defaultOrder)
+	}
+
+	resp, err := svc.providers["team"].Complete(context.Background(), CompletionRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil || resp.Model != "team-model" {
+		t.Fatalf("unexpected response: %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"edu-system/internal/ai/dto"
+	testdto "edu-system/internal/test/dto"
+)
+
+var (
+	ErrVariantNotFound = errors.New("test variant not found in pipeline run")
+	ErrNotPublishable  = errors.New("generated test cannot be published")
+)
+
+// TestCreator saves a test for its owner; test.TestService satisfies it.
+type TestCreator interface {
+	CreateTest(ownerID uint, req *testdto.CreateTestRequest) (string, error)
+}
+
+func (s *service) PublishRun(ctx context.Context, ownerID uint, author, runID string, req *dto.PublishRunRequest) (*dto.PublishRunResponse, error) {
+	if s.tests == nil {
+		return nil, errors.New("test publishing is not configured")
+	}
+	if req == nil {
+		req = &dto.PublishRunRequest{}
+	}
+	run, err := s.runs.GetRun(ctx, ownerID, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	createReq, err := buildCreateTestRequest(run.Result.Final, req)
+	if err != nil {
+		return nil, err
+	}
+	createReq.Author = author
+
+	testID, err := s.tests.CreateTest(ownerID, createReq)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.PublishRunResponse{
+		RunID:            run.ID,
+		TestID:           testID,
+		Title:            createReq.Title,
+		CreatedQuestions: len(createReq.Questions),
+	}, nil
+}
+
+func buildCreateTestRequest(final dto.FinalOutput, req *dto.PublishRunRequest) (*testdto.CreateTestRequest, error) {
+	var (
+		title, description string
+		questions          []dto.GeneratedQuestion
+	)
+	if req.Practice {
+		title = final.PracticeTest.Title
+		if title == "" {
+			title = "Practice test"
+		}
+		description = final.StudyNotes.Summary
+		questions = final.PracticeTest.Questions
+	} else {
+		if req.Variant < 0 || req.Variant >= len(final.TestVariants) {
+			return nil, fmt.Errorf("%w: variant %d of %d", ErrVariantNotFound, req.Variant, len(final.TestVariants))
+		}
+		variant := final.TestVariants[req.Variant]
+		title = variant.Title
+		if title == "" {
+			title = fmt.Sprintf("Variant %d", req.Variant+1)
+		}
+		description = variant.Instructions
+		questions = variant.Questions
+	}
+
+	if v := strings.TrimSpace(req.Title); v != "" {
+		title = v
+	}
+	if v := strings.TrimSpace(req.Description); v != "" {
+		description = v
+	}
+	if strings.TrimSpace(description) == "" {
+		description = final.TeacherSummary
+	}
+	if strings.TrimSpace(description) == "" {
+		description = "Generated by the AI pipeline"
+	}
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("%w: no questions", ErrNotPublishable)
+	}
+
+	out := &testdto.CreateTestRequest{
+		Title:       strings.TrimSpace(title),
+		Description: strings.TrimSpace(description),
+		Questions:   make([]testdto.Question, 0, len(questions)),
+	}
+	for i, q := range questions {
+		converted, err := convertGeneratedQuestion(q)
+		if err != nil {
+			return nil, fmt.Errorf("%w: question %d: %v", ErrNotPublishable, i+1, err)
+		}
+		out.Questions = append(out.Questions, converted)
+	}
+	return out, nil
+}
+
+// convertGeneratedQuestion maps a model-written question onto the test
+// payload. Correct answers are zero-based option indexes; options of a text
+// question become its accepted answers.
+func convertGeneratedQuestion(q dto.GeneratedQuestion) (testdto.Question, error) {
+	text := strings.TrimSpace(q.Question)
+	if text == "" {
+		return testdto.Question{}, errors.New("question text is empty")
+	}
+
+	// Blank options are dropped, so remember where the others moved.
+	options := make([]string, 0, len(q.Options))
+	index := make(map[int]int, len(q.Options))
+	for i, opt := range q.Options {
+		if opt = strings.TrimSpace(opt); opt != "" {
+			index[i] = len(options)
+			options = append(options, opt)
+		}
+	}
+
+	out := testdto.Question{
+		QuestionText: text,
+		Type:         generatedQuestionType(q.Type, len(options)),
+		Options:      make([]testdto.Answer, 0),
+		Solution:     strings.TrimSpace(q.Explanation),
+	}
+
+	switch out.Type {
+	case "single", "multi":
+		if len(options) == 0 {
+			return testdto.Question{}, errors.New("choice question has no options")
+		}
+		for i, opt := range options {
+			out.Options = append(out.Options, testdto.Answer{AnswerNumber: i, AnswerText: opt})
+		}
+		seen := make(map[int]struct{}, len(q.CorrectAnswers))
+		for _, idx := range q.CorrectAnswers {
+			mapped, ok := index[idx]
+			if !ok {
+				return testdto.Question{}, fmt.Errorf("correct answer %d is not an option", idx)
+			}
+			if _, dup := seen[mapped]; dup {
+				continue
+			}
+			seen[mapped] = struct{}{}
+			out.CorrectOptions = append(out.CorrectOptions, mapped)
+		}
+		if len(out.CorrectOptions) == 0 {
+			return testdto.Question{}, errors.New("no correct answer given")
+		}
+		if len(out.CorrectOptions) > 1 {
+			out.Type = "multi"
+		}
+		out.CorrectOption = out.CorrectOptions[0]
+	case "text":
+		out.AcceptedAnswers = options
+	}
+	return out, nil
+}
+
+func generatedQuestionType(raw string, optionCount int) string {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "single", "single_choice", "true_false":
+		return "single"
+	case "multi", "multiple", "multiple_choice", "multi_choice":
+		return "multi"
+	case "text", "open", "short_answer":
+		return "text"
+	case "code", "coding":
+		return "code"
+	}
+	if optionCount == 0 {
+		return "text"
+	}
+	return "single"
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+
+	"edu-system/internal/ai/dto"
+)
+
+func TestBuildCreateTestRequestMapsQuestionTypes(t *testing.T) {
+	final := dto.FinalOutput{
+		TestVariants: []dto.TestVariant{{
+			Title:        "V1",
+			Instructions: "instr",
+			Questions: []dto.GeneratedQuestion{
+				{Question: "Q1", Type: "single", Options: []string{"A", "", "C"}, CorrectAnswers: []int{2}, Explanation: "because C"},
+				{Question: "Q2", Type: "single", Options: []string{"A", "B", "C"}, CorrectAnswers: []int{0, 1}},
+				{Question: "Q3", Type: "text", Options: []string{"42"}, Explanation: "the answer"},
+				{Question: "Q4", Type: "code"},
+			},
+		}},
+	}
+
+	req, err := buildCreateTestRequest(final, &dto.PublishRunRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Title != "V1" || req.Description != "instr" || len(req.Questions) != 4 {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+	q1, q2, q3, q4 := req.Questions[0], req.Questions[1], req.Questions[2], req.Questions[3]
+	if q1.Type != "single" || len(q1.Options) != 2 || q1.CorrectOption != 1 || q1.Solution != "because C" {
+		t.Fatalf("single question mapped wrong: %+v", q1)
+	}
+	if q2.Type != "multi" || len(q2.CorrectOptions) != 2 {
+		t.Fatalf("expected single with two answers to become multi: %+v", q2)
+	}
+	if q3.Type != "text" || len(q3.AcceptedAnswers) != 1 || q3.AcceptedAnswers[0] != "42" || len(q3.Options) != 0 {
+		t.Fatalf("text question mapped wrong: %+v", q3)
+	}
+	if q4.Type != "code" {
+		t.Fatalf("code question mapped wrong: %+v", q4)
+	}
+
+	if _, err := buildCreateTestRequest(final, &dto.PublishRunRequest{Variant: 3}); !errors.Is(err, ErrVariantNotFound) {
+		t.Fatalf("expected ErrVariantNotFound, got %v", err)
+	}
+	final.TestVariants[0].Questions = []dto.GeneratedQuestion{{Question: "Q", Type: "single", Options: []string{"A"}, CorrectAnswers: []int{5}}}
+	if _, err := buildCreateTestRequest(final, &dto.PublishRunRequest{}); !errors.Is(err, ErrNotPublishable) {
+		t.Fatalf("expected ErrNotPublishable, got %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"edu-system/internal/ai/dto"
+	testdto "edu-system/internal/test/dto"
+)
+
+const (
+	layerDistractors = "distractors"
+	layerRewrite     = "rewrite"
+	layerTranslate   = "translate"
+
+	defaultDistractors = 3
+	maxDistractors     = 10
+)
+
+var (
+	ErrQuestionUnsupported = errors.New("question type is not supported by this action")
+	ErrInvalidAssist       = errors.New("invalid question assist request")
+)
+
+// QuestionReader loads a question for its owner; test.TestService satisfies it.
+type QuestionReader interface {
+	GetQuestion(ownerID uint, questionID string) (*testdto.QuestionResponse, error)
+}
+
+// TestStore is the part of test.TestService the ai service works with.
+type TestStore interface {
+	TestCreator
+	QuestionReader
+}
+
+// SuggestDistractors proposes wrong options for a choice question. The
+// suggestion appends them to the existing options.
+func (s *service) SuggestDistractors(ctx context.Context, ownerID uint, questionID string, req *dto.QuestionAssistRequest) (*dto.QuestionAssistResponse, error) {
+	if req == nil {
+		req = &dto.QuestionAssistRequest{}
+	}
+	count := req.Count
+	if count == 0 {
+		count = defaultDistractors
+	}
+	if count < 1 || count > maxDistractors {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidAssist, maxDistractors)
+	}
+	q, order, err := s.prepareAssist(ctx, ownerID, questionID, req, layerDistractors)
+	if err != nil {
+		return nil, err
+	}
+	if q.Type != "single" && q.Type != "multi" {
+		return nil, fmt.Errorf("%w: distractors need a single or multi choice question", ErrQuestionUnsupported)
+	}
+
+	var out dto.DistractorsOutput
+	p, err := s.prompts.render(layerDistractors, "", promptData{Question: promptQuestionOf(q), Count: count})
+	if err != nil {
+		return nil, err
+	}
+	trace, err := s.runLayer(ctx, ownerID, layerDistractors, order, nil, p, func(raw string) error {
+		if err := decodeModelJSON(raw, &out); err != nil {
+			return err
+		}
+		return checkDistractors(&out, q, count)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	suggestion := questionPayload(q)
+	for _, d := range out.Distractors {
+		suggestion.Options = append(suggestion.Options, testdto.Answer{
+			AnswerNumber: len(suggestion.Options),
+			AnswerText:   strings.TrimSpace(d.Text),
+		})
+	}
+	return &dto.QuestionAssistResponse{
+		QuestionID:  q.ID,
+		TestID:      q.TestID,
+		Suggestions: []dto.QuestionSuggestion{{Kind: "distractors", Question: suggestion}},
+		Distractors: out.Distractors,
+		Trace:       trace,
+	}, nil
+}
+
+// RewriteQuestion proposes an easier and a harder wording of the question
+// with the same answer key.
+func (s *service) RewriteQuestion(ctx context.Context, ownerID uint, questionID string, req *dto.QuestionAssistRequest) (*dto.QuestionAssistResponse, error) {
+	if req == nil {
+		req = &dto.QuestionAssistRequest{}
+	}
+	q, order, err := s.prepareAssist(ctx, ownerID, questionID, req, layerRewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	var out dto.RewriteOutput
+	p, err := s.prompts.render(layerRewrite, "", promptData{Question: promptQuestionOf(q)})
+	if err != nil {
+		return nil, err
+	}
+	trace, err := s.runLayer(ctx, ownerID, layerRewrite, order, nil, p, func(raw string) error {
+		if err := decodeModelJSON(raw, &out); err != nil {
+			return err
+		}
+		var l issueList
+		checkRewritten(&l, "easier", out.Easier, q)
+		checkRewritten(&l, "harder", out.Harder, q)
+		return l.err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.QuestionAssistResponse{
+		QuestionID: q.ID,
+		TestID:     q.TestID,
+		Suggestions: []dto.QuestionSuggestion{
+			rewrittenSuggestion("easier", q, out.Easier),
+			rewrittenSuggestion("harder", q, out.Harder),
+		},
+		Trace: trace,
+	}, nil
+}
+
+// TranslateQuestion translates the wording, options, solution and accepted
+// answers into req.Language.
+func (s *service) TranslateQuestion(ctx context.Context, ownerID uint, questionID string, req *dto.QuestionAssistRequest) (*dto.QuestionAssistResponse, error) {
+	if req == nil || strings.TrimSpace(req.Language) == "" {
+		return nil, fmt.Errorf("%w: language is required", ErrInvalidAssist)
+	}
+	language := strings.TrimSpace(req.Language)
+	q, order, err := s.prepareAssist(ctx, ownerID, questionID, req, layerTranslate)
+	if err != nil {
+		return nil, err
+	}
+
+	var out dto.RewrittenQuestion
+	p, err := s.prompts.render(layerTranslate, "", promptData{Question: promptQuestionOf(q), Language: language})
+	if err != nil {
+		return nil, err
+	}
+	trace, err := s.runLayer(ctx, ownerID, layerTranslate, order, nil, p, func(raw string) error {
+		if err := decodeModelJSON(raw, &out); err != nil {
+			return err
+		}
+		var l issueList
+		checkRewritten(&l, "translation", out, q)
+		return l.err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	suggestion := rewrittenSuggestion("translation", q, out)
+	if suggestion.Note == "" {
+		suggestion.Note = language
+	}
+	return &dto.QuestionAssistResponse{
+		QuestionID:  q.ID,
+		TestID:      q.TestID,
+		Suggestions: []dto.QuestionSuggestion{suggestion},
+		Trace:       trace,
+	}, nil
+}
+
+// prepareAssist loads the question and resolves the provider order for the
+// layer, refusing up front when the budget leaves no provider to call.
+func (s *service) prepareAssist(ctx context.Context, ownerID uint, questionID string, req *dto.QuestionAssistRequest, layer string) (*testdto.QuestionResponse, []string, error) {
+	if s.tests == nil {
+		return nil, nil, errors.New("test access is not configured")
+	}
+	order := append([]string(nil), s.defaultOrder...)
+	if req.Provider != nil && len(req.Provider.Order) > 0 {
+		strictOrder, err := s.normalizeProviderOrderWithErr(req.Provider.Order, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		order = strictOrder
+	}
+	if len(order) == 0 {
+		return nil, nil, ErrNoProviderConfigured
+	}
+	q, err := s.tests.GetQuestion(ownerID, questionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.ensureBudget(ctx, ownerID, map[string][]string{layer: order}); err != nil {
+		return nil, nil, err
+	}
+	return q, order, nil
+}
+
+// questionPayload turns a stored question into the payload UpdateTest takes.
+func questionPayload(q *testdto.QuestionResponse) testdto.Question {
+	out := testdto.Question{
+		ID:              q.ID,
+		QuestionText:    q.QuestionText,
+		Options:         make([]testdto.Answer, 0, len(q.Options)),
+		CorrectOption:   q.CorrectOption,
+		CorrectOptions:  append([]int(nil), q.CorrectOptions...),
+		Type:            q.Type,
+		Weight:          q.Weight,
+		ImageURL:        q.ImageURL,
+		Scoring:         q.Scoring,
+		Section:         q.Section,
+		Solution:        q.Solution,
+		AcceptedAnswers: append([]string(nil), q.AcceptedAnswers...),
+		MatchMode:       q.MatchMode,
+		Tolerance:       q.Tolerance,
+		ManualReview:    q.ManualReview,
+		TestCases:       append([]testdto.CodeTestCase(nil), q.TestCases...),
+	}
+	for i, opt := range q.Options {
+		out.Options = append(out.Options, testdto.Answer{AnswerNumber: i, AnswerText: opt.OptionText, ImageURL: opt.ImageURL})
+	}
+	return out
+}
+
+func rewrittenSuggestion(kind string, q *testdto.QuestionResponse, r dto.RewrittenQuestion) dto.QuestionSuggestion {
+	out := questionPayload(q)
+	out.QuestionText = strings.TrimSpace(r.QuestionText)
+	for i := range out.Options {
+		out.Options[i].AnswerText = strings.TrimSpace(r.Options[i])
+	}
+	if solution := strings.TrimSpace(r.Solution); solution != "" {
+		out.Solution = solution
+	}
+	if len(out.AcceptedAnswers) > 0 {
+		out.AcceptedAnswers = r.AcceptedAnswers
+	}
+	return dto.QuestionSuggestion{Kind: kind, Note: strings.TrimSpace(r.Note), Question: out}
+}
@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"edu-system/internal/ai/dto"
+	testdto "edu-system/internal/test/dto"
+)
+
+type fakeTestStore struct {
+	question testdto.QuestionResponse
+}
+
+func (f *fakeTestStore) CreateTest(uint, *testdto.CreateTestRequest) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (f *fakeTestStore) GetQuestion(ownerID uint, questionID string) (*testdto.QuestionResponse, error) {
+	q := f.question
+	return &q, nil
+}
+
+func TestSuggestDistractorsAppendsOptions(t *testing.T) {
+	provider := &mockProvider{
+		name:       ProviderOpenAI,
+		model:      "m",
+		configured: true,
+		responses: []mockProviderResponse{
+			{text: `{"distractors":[{"text":" париж ","rationale":"r"},{"text":"Лион","rationale":"r"}]}`},
+			{text: `{"distractors":[{"text":"Марсель","rationale":"r"},{"text":"Лион","rationale":"r"}]}`},
+		},
+	}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: provider}, []string{ProviderOpenAI})
+	svc.tests = &fakeTestStore{question: testdto.QuestionResponse{
+		ID:            "q1",
+		TestID:        "t1",
+		QuestionText:  "Столица Франции?",
+		Type:          "single",
+		Weight:        1,
+		CorrectOption: 0,
+		Options:       []testdto.OptionResponse{{OptionText: "Париж"}, {OptionText: "Берлин"}},
+	}}
+
+	resp, err := svc.SuggestDistractors(context.Background(), 1, "q1", &dto.QuestionAssistRequest{Count: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Trace.Repairs != 1 {
+		t.Fatalf("expected the repeated option to be repaired, got %d repairs", resp.Trace.Repairs)
+	}
+	q := resp.Suggestions[0].Question
+	if len(q.Options) != 4 || q.Options[2].AnswerText != "Марсель" || q.Options[3].AnswerNumber != 3 || q.CorrectOption != 0 {
+		t.Fatalf("unexpected suggested question: %+v", q)
+	}
+
+	if _, err := svc.TranslateQuestion(context.Background(), 1, "q1", &dto.QuestionAssistRequest{}); !errors.Is(err, ErrInvalidAssist) {
+		t.Fatalf("expected ErrInvalidAssist without a language, got %v", err)
+	}
+}
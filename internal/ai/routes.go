@@ -2,11 +2,22 @@ package ai
 
 import "github.com/gin-gonic/gin"
 
-func RegisterRoutes(v1 gin.IRouter, h *Handler, jwtAuth gin.HandlerFunc) {
+func RegisterRoutes(v1 gin.IRouter, h *Handler, jwtAuth, requireAuthor gin.HandlerFunc) {
 	protected := v1.Group("/ai")
-	protected.Use(jwtAuth)
+	protected.Use(jwtAuth, requireAuthor)
 	{
 		protected.GET("/providers", h.ListProviders)
+		protected.GET("/usage", h.Usage)
 		protected.POST("/pipeline", h.RunPipeline)
+		protected.POST("/pipeline/:run_id/publish", h.PublishRun)
+		protected.GET("/jobs/:job_id", h.GetJob)
+		protected.GET("/jobs/:job_id/events", h.JobEvents)
+		protected.POST("/jobs/:job_id/cancel", h.CancelJob)
+		protected.GET("/runs", h.ListRuns)
+		protected.GET("/runs/:run_id", h.GetRun)
+		protected.POST("/runs/:run_id/layers/:layer/rerun", h.RerunLayer)
+		protected.POST("/questions/:question_id/distractors", h.SuggestDistractors)
+		protected.POST("/questions/:question_id/rewrites", h.RewriteQuestion)
+		protected.POST("/questions/:question_id/translation", h.TranslateQuestion)
 	}
 }
@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"edu-system/internal/ai/dto"
+	"edu-system/internal/ai/runs"
+	"github.com/google/uuid"
+)
+
+// ErrRunNotFound is returned for unknown runs and runs of other owners.
+var ErrRunNotFound = runs.ErrNotFound
+
+// Run listings are paged; a limit outside 1..maxRunsPage falls back to
+// defaultRunsPage.
+const (
+	defaultRunsPage = 50
+	maxRunsPage     = 200
+)
+
+func (s *service) ListRuns(ctx context.Context, ownerID uint, limit, offset int) ([]dto.RunSummary, error) {
+	if limit <= 0 || limit > maxRunsPage {
+		limit = defaultRunsPage
+	}
+	offset = max(offset, 0)
+	stored, err := s.runs.ListRuns(ctx, ownerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]dto.RunSummary, 0, len(stored))
+	for _, run := range stored {
+		out = append(out, dto.RunSummary{
+			RunID:       run.ID,
+			ParentRunID: run.ParentID,
+			Title:       run.Title,
+			CreatedAt:   run.CreatedAt,
+			ReadyForUse: run.ReadyForUse,
+			Variants:    run.Variants,
+		})
+	}
+	return out, nil
+}
+
+func (s *service) GetRun(ctx context.Context, ownerID uint, runID string) (*dto.RunDetails, error) {
+	run, err := s.runs.GetRun(ctx, ownerID, runID)
+	if err != nil {
+		return nil, err
+	}
+	return runDetails(run), nil
+}
+
+// RerunLayer runs one layer again on top of the stored outputs of the earlier
+// layers, followed by every later layer so that the final output reflects the
+// new one, and saves the outcome as a new run.
+func (s *service) RerunLayer(ctx context.Context, ownerID uint, runID, layer string, req *dto.RerunLayerRequest) (*dto.RunDetails, error) {
+	layer = strings.ToLower(strings.TrimSpace(layer))
+	if !slices.Contains(pipelineLayers, layer) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidLayer, layer)
+	}
+	parent, err := s.runs.GetRun(ctx, ownerID, runID)
+	if err != nil {
+		return nil, err
+	}
+	selection := parent.Provider
+	cfg := parent.Config
+	if req != nil && req.Provider != nil {
+		selection = req.Provider
+	}
+	if req != nil && req.PromptLanguage != "" {
+		lang, err := s.prompts.resolveLanguage(req.PromptLanguage, cfg.OutputLanguage)
+		if err != nil {
+			return nil, err
+		}
+		cfg.PromptLanguage = lang
+	}
+	orders, err := s.resolveLayerOrders(selection)
+	if err != nil {
+		return nil, err
+	}
+	rerun := pipelineLayers[slices.Index(pipelineLayers, layer):]
+	rerunOrders := make(map[string][]string, len(rerun))
+	for _, l := range rerun {
+		rerunOrders[l] = orders[l]
+	}
+	if err := s.ensureBudget(ctx, ownerID, rerunOrders); err != nil {
+		return nil, err
+	}
+
+	state := &pipelineState{
+		ownerID:    ownerID,
+		material:   parent.Material,
+		cfg:        cfg,
+		plan:       parent.Result.Plan,
+		draft:      parent.Result.Draft,
+		validation: parent.Result.Validation,
+		final:      parent.Result.Final,
+	}
+	fresh := make(map[string]dto.LayerProviderTrace, len(rerun))
+	for _, l := range rerun {
+		trace, err := s.runPipelineLayer(ctx, l, orders[l], state)
+		if err != nil {
+			return nil, err
+		}
+		fresh[l] = trace
+	}
+
+	traces := make([]dto.LayerProviderTrace, 0, len(pipelineLayers))
+	for _, t := range parent.Result.ProviderTrace {
+		if _, ok := fresh[t.Layer]; !ok {
+			traces = append(traces, t)
+		}
+	}
+	for _, l := range rerun {
+		traces = append(traces, fresh[l])
+	}
+
+	run := &runs.Run{
+		ID:        uuid.New().String(),
+		OwnerID:   ownerID,
+		ParentID:  parent.ID,
+		CreatedAt: time.Now().UTC(),
+		Material:  parent.Material,
+		Config:    cfg,
+		Provider:  selection,
+		Ingestion: parent.Ingestion,
+		Result:    state.response(traces),
+	}
+	run.Result.RunID = run.ID
+	if err := s.runs.SaveRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("save pipeline run: %w", err)
+	}
+	return runDetails(run), nil
+}
+
+func runDetails(run *runs.Run) *dto.RunDetails {
+	return &dto.RunDetails{
+		ParentRunID:         run.ParentID,
+		CreatedAt:           run.CreatedAt,
+		Material:            run.Material,
+		GenerationConfig:    run.Config,
+		Provider:            run.Provider,
+		Ingestion:           run.Ingestion,
+		PipelineRunResponse: run.Result,
+	}
+}
@@ -0,0 +1,150 @@
+package runs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"edu-system/internal/ai/dto"
+)
+
+var ErrNotFound = errors.New("ai pipeline run not found")
+
+// Run is a finished pipeline execution: its input, every layer output and
+// the provider traces. Re-running a layer stores a new run whose ParentID
+// points at the run it started from.
+type Run struct {
+	ID        string
+	OwnerID   uint
+	ParentID  string
+	CreatedAt time.Time
+	Material  dto.MaterialInput
+	Config    dto.GenerationConfig
+	Provider  *dto.ProviderSelection
+	Ingestion dto.MaterialIngestion
+	Result    dto.PipelineRunResponse
+}
+
+// Summary is what run listings show of a run.
+type Summary struct {
+	ID          string
+	ParentID    string
+	Title       string
+	CreatedAt   time.Time
+	ReadyForUse bool
+	Variants    int
+}
+
+func (r *Run) Summary() Summary {
+	return Summary{
+		ID:          r.ID,
+		ParentID:    r.ParentID,
+		Title:       r.Material.Title,
+		CreatedAt:   r.CreatedAt,
+		ReadyForUse: r.Result.Final.ReadyForUse,
+		Variants:    len(r.Result.Final.TestVariants),
+	}
+}
+
+// Usage is the cost of one provider call. It is recorded as soon as the call
+// returns, so calls made by runs that later fail still count.
+type Usage struct {
+	OwnerID          uint
+	Layer            string
+	Provider         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64
+	CreatedAt        time.Time
+}
+
+type Store interface {
+	SaveRun(ctx context.Context, run *Run) error
+	GetRun(ctx context.Context, ownerID uint, id string) (*Run, error)
+	// ListRuns returns a page of the owner's run summaries, newest first.
+	ListRuns(ctx context.Context, ownerID uint, limit, offset int) ([]Summary, error)
+	RecordUsage(ctx context.Context, usage *Usage) error
+	// SpentSince sums the cost of the owner's calls made at or after since.
+	SpentSince(ctx context.Context, ownerID uint, since time.Time) (float64, error)
+}
+
+const memoryLimit = 256
+
+// memoryStore keeps the most recent runs in process memory, dropping the
+// oldest once memoryLimit is reached. It backs tests and setups without a
+// database.
+type memoryStore struct {
+	mu    sync.Mutex
+	runs  map[string]*Run
+	order []string
+	usage []Usage
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{runs: make(map[string]*Run)}
+}
+
+func (m *memoryStore) SaveRun(_ context.Context, run *Run) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.runs[run.ID]; !exists {
+		m.order = append(m.order, run.ID)
+	}
+	stored := *run
+	m.runs[run.ID] = &stored
+	for len(m.order) > memoryLimit {
+		delete(m.runs, m.order[0])
+		m.order = m.order[1:]
+	}
+	return nil
+}
+
+func (m *memoryStore) GetRun(_ context.Context, ownerID uint, id string) (*Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run, ok := m.runs[id]
+	if !ok || run.OwnerID != ownerID {
+		return nil, ErrNotFound
+	}
+	out := *run
+	return &out, nil
+}
+
+func (m *memoryStore) ListRuns(_ context.Context, ownerID uint, limit, offset int) ([]Summary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Summary, 0)
+	for i := len(m.order) - 1; i >= 0 && len(out) < limit; i-- {
+		run := m.runs[m.order[i]]
+		if run.OwnerID != ownerID {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		out = append(out, run.Summary())
+	}
+	return out, nil
+}
+
+func (m *memoryStore) RecordUsage(_ context.Context, usage *Usage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage = append(m.usage, *usage)
+	return nil
+}
+
+func (m *memoryStore) SpentSince(_ context.Context, ownerID uint, since time.Time) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	total := 0.0
+	for _, u := range m.usage {
+		if u.OwnerID == ownerID && !u.CreatedAt.Before(since) {
+			total += u.CostUSD
+		}
+	}
+	return total, nil
+}
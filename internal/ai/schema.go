@@ -0,0 +1,249 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"edu-system/internal/ai/dto"
+	testdto "edu-system/internal/test/dto"
+)
+
+// maxRepairAttempts bounds how often runLayer asks the same provider to fix
+// an output that failed its checks before falling back to the next one.
+const maxRepairAttempts = 2
+
+// OutputIssues lists what is wrong with a layer output. runLayer sends them
+// back to the provider when it asks for a repaired answer.
+type OutputIssues []string
+
+func (o OutputIssues) Error() string {
+	return "output failed checks: " + strings.Join(o, "; ")
+}
+
+type issueList struct {
+	issues OutputIssues
+}
+
+func (l *issueList) add(format string, args ...any) {
+	l.issues = append(l.issues, fmt.Sprintf(format, args...))
+}
+
+func (l *issueList) err() error {
+	if len(l.issues) == 0 {
+		return nil
+	}
+	return l.issues
+}
+
+var (
+	questionTypes   = map[string]struct{}{"single": {}, "multi": {}, "text": {}, "code": {}}
+	issueSeverities = map[string]struct{}{"low": {}, "medium": {}, "high": {}, "critical": {}}
+)
+
+func checkPlan(plan *dto.PlanOutput, cfg dto.GenerationConfig) error {
+	var l issueList
+	if strings.TrimSpace(plan.Summary) == "" {
+		l.add("summary is empty")
+	}
+	if len(plan.TopicBlocks) == 0 {
+		l.add("topic_blocks is empty")
+	}
+	for i, block := range plan.TopicBlocks {
+		if strings.TrimSpace(block.Topic) == "" {
+			l.add("topic_blocks[%d].topic is empty", i)
+		}
+		if block.WeightPercent < 0 || block.WeightPercent > 100 {
+			l.add("topic_blocks[%d].weight_percent must be between 0 and 100", i)
+		}
+	}
+	bp := plan.TestBlueprint
+	if bp.VariantsCount != 0 && bp.VariantsCount != cfg.VariantsCount {
+		l.add("test_blueprint.variants_count must be %d", cfg.VariantsCount)
+	}
+	if bp.QuestionsPerVariant != 0 && bp.QuestionsPerVariant != cfg.QuestionsPerVariant {
+		l.add("test_blueprint.questions_per_variant must be %d", cfg.QuestionsPerVariant)
+	}
+	for i, target := range bp.QuestionTypeTargets {
+		if _, ok := questionTypes[target.Type]; !ok {
+			l.add("test_blueprint.question_type_targets[%d].type must be single, multi, text or code", i)
+		}
+		if target.Count < 0 {
+			l.add("test_blueprint.question_type_targets[%d].count is negative", i)
+		}
+	}
+	return l.err()
+}
+
+func checkGeneration(out *dto.GenerationOutput, cfg dto.GenerationConfig) error {
+	var l issueList
+	checkVariants(&l, out.TestVariants, cfg)
+	checkStudyNotes(&l, out.StudyNotes)
+	checkPracticeTest(&l, out.PracticeTest, cfg)
+	return l.err()
+}
+
+func checkValidation(v *dto.ValidationOutput) error {
+	var l issueList
+	if v.AlignmentScore < 0 || v.AlignmentScore > 100 {
+		l.add("alignment_score must be between 0 and 100")
+	}
+	if strings.TrimSpace(v.Summary) == "" {
+		l.add("summary is empty")
+	}
+	for i, issue := range v.Issues {
+		if _, ok := issueSeverities[issue.Severity]; !ok {
+			l.add("issues[%d].severity must be low, medium, high or critical", i)
+		}
+		if strings.TrimSpace(issue.Problem) == "" {
+			l.add("issues[%d].problem is empty", i)
+		}
+	}
+	return l.err()
+}
+
+func checkFinal(final *dto.FinalOutput, cfg dto.GenerationConfig) error {
+	var l issueList
+	if strings.TrimSpace(final.TeacherSummary) == "" {
+		l.add("teacher_summary is empty")
+	}
+	checkVariants(&l, final.TestVariants, cfg)
+	checkStudyNotes(&l, final.StudyNotes)
+	checkPracticeTest(&l, final.PracticeTest, cfg)
+	return l.err()
+}
+
+func checkVariants(l *issueList, variants []dto.TestVariant, cfg dto.GenerationConfig) {
+	if len(variants) != cfg.VariantsCount {
+		l.add("test_variants has %d variants, expected %d", len(variants), cfg.VariantsCount)
+	}
+	for i, variant := range variants {
+		path := fmt.Sprintf("test_variants[%d]", i)
+		if strings.TrimSpace(variant.Title) == "" {
+			l.add("%s.title is empty", path)
+		}
+		if len(variant.Questions) != cfg.QuestionsPerVariant {
+			l.add("%s has %d questions, expected %d", path, len(variant.Questions), cfg.QuestionsPerVariant)
+		}
+		checkQuestions(l, path+".questions", variant.Questions)
+	}
+}
+
+func checkStudyNotes(l *issueList, notes dto.StudyNotes) {
+	if strings.TrimSpace(notes.Title) == "" {
+		l.add("study_notes.title is empty")
+	}
+	if strings.TrimSpace(notes.Summary) == "" {
+		l.add("study_notes.summary is empty")
+	}
+}
+
+func checkPracticeTest(l *issueList, practice dto.PracticeTest, cfg dto.GenerationConfig) {
+	if !boolValue(cfg.IncludePracticeTests, true) {
+		return
+	}
+	if len(practice.Questions) == 0 {
+		l.add("practice_test.questions is empty")
+	}
+	checkQuestions(l, "practice_test.questions", practice.Questions)
+}
+
+// checkQuestions requires choice questions to have at least two options and
+// valid, distinct answer indexes; single choice has exactly one answer.
+func checkQuestions(l *issueList, path string, questions []dto.GeneratedQuestion) {
+	for i, q := range questions {
+		at := fmt.Sprintf("%s[%d]", path, i)
+		if strings.TrimSpace(q.Question) == "" {
+			l.add("%s.question is empty", at)
+		}
+		if _, ok := questionTypes[q.Type]; !ok {
+			l.add("%s.type must be single, multi, text or code, got %q", at, q.Type)
+			continue
+		}
+		if q.Type != "single" && q.Type != "multi" {
+			continue
+		}
+		for j, option := range q.Options {
+			if strings.TrimSpace(option) == "" {
+				l.add("%s.options[%d] is empty", at, j)
+			}
+		}
+		if len(q.Options) < 2 {
+			l.add("%s needs at least 2 options, has %d", at, len(q.Options))
+		}
+		if len(q.CorrectAnswers) == 0 {
+			l.add("%s.correct_answers is empty", at)
+		}
+		if q.Type == "single" && len(q.CorrectAnswers) > 1 {
+			l.add("%s is single choice but has %d correct answers", at, len(q.CorrectAnswers))
+		}
+		seen := make(map[int]bool, len(q.CorrectAnswers))
+		for _, idx := range q.CorrectAnswers {
+			if idx < 0 || idx >= len(q.Options) {
+				l.add("%s.correct_answers index %d is out of range for %d options", at, idx, len(q.Options))
+			}
+			if seen[idx] {
+				l.add("%s.correct_answers repeats index %d", at, idx)
+			}
+			seen[idx] = true
+		}
+	}
+}
+
+func checkGrade(out *gradeOutput, maxScore float64) error {
+	var l issueList
+	if out.Score < 0 || out.Score > maxScore {
+		l.add("score must be between 0 and %g", maxScore)
+	}
+	if strings.TrimSpace(out.Rationale) == "" {
+		l.add("rationale is empty")
+	}
+	return l.err()
+}
+
+// checkDistractors requires count new, distinct options that do not repeat
+// the existing ones.
+func checkDistractors(out *dto.DistractorsOutput, q *testdto.QuestionResponse, count int) error {
+	var l issueList
+	if len(out.Distractors) != count {
+		l.add("distractors has %d items, expected %d", len(out.Distractors), count)
+	}
+	seen := make(map[string]bool, len(q.Options)+len(out.Distractors))
+	for _, opt := range q.Options {
+		seen[normalizeOption(opt.OptionText)] = true
+	}
+	for i, d := range out.Distractors {
+		text := normalizeOption(d.Text)
+		if text == "" {
+			l.add("distractors[%d].text is empty", i)
+			continue
+		}
+		if seen[text] {
+			l.add("distractors[%d].text repeats an existing option", i)
+		}
+		seen[text] = true
+	}
+	return l.err()
+}
+
+// checkRewritten requires a rewrite or translation to keep the options and
+// accepted answers one to one, so the answer key still applies.
+func checkRewritten(l *issueList, path string, r dto.RewrittenQuestion, q *testdto.QuestionResponse) {
+	if strings.TrimSpace(r.QuestionText) == "" {
+		l.add("%s.question_text is empty", path)
+	}
+	if len(r.Options) != len(q.Options) {
+		l.add("%s.options has %d items, expected %d in the original order", path, len(r.Options), len(q.Options))
+	}
+	for i, opt := range r.Options {
+		if strings.TrimSpace(opt) == "" {
+			l.add("%s.options[%d] is empty", path, i)
+		}
+	}
+	if len(q.AcceptedAnswers) > 0 && len(r.AcceptedAnswers) != len(q.AcceptedAnswers) {
+		l.add("%s.accepted_answers has %d items, expected %d", path, len(r.AcceptedAnswers), len(q.AcceptedAnswers))
+	}
+}
+
+func normalizeOption(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"edu-system/internal/ai/dto"
+)
+
+func TestCheckGenerationReportsBrokenQuestions(t *testing.T) {
+	out := dto.GenerationOutput{
+		TestVariants: []dto.TestVariant{{
+			Title: "V1",
+			Questions: []dto.GeneratedQuestion{
+				{Question: "Q1", Type: "single", Options: []string{"A", "B"}, CorrectAnswers: []int{2}},
+				{Question: "Q2", Type: "single"},
+			},
+		}},
+		StudyNotes:   dto.StudyNotes{Title: "Notes", Summary: "S"},
+		PracticeTest: dto.PracticeTest{Questions: []dto.GeneratedQuestion{{Question: "PQ", Type: "text"}}},
+	}
+	err := checkGeneration(&out, dto.GenerationConfig{VariantsCount: 1, QuestionsPerVariant: 3})
+	issues, ok := err.(OutputIssues)
+	if !ok {
+		t.Fatalf("expected OutputIssues, got %v", err)
+	}
+	want := []string{
+		"test_variants[0] has 2 questions, expected 3",
+		"test_variants[0].questions[0].correct_answers index 2 is out of range for 2 options",
+		"test_variants[0].questions[1] needs at least 2 options, has 0",
+		"test_variants[0].questions[1].correct_answers is empty",
+	}
+	if strings.Join(issues, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected issues:\n%s", strings.Join(issues, "\n"))
+	}
+}
+
+func TestRunLayerRequestsRepairBeforeFallback(t *testing.T) {
+	broken := `{"test_variants":[{"title":"V1","questions":[{"question":"Q1","type":"single","options":["A","B"],"correct_answers":[5]}]}],"study_notes":{"title":"Notes","summary":"S"},"practice_test":{"title":"P","questions":[{"question":"PQ1","type":"text"}]}}`
+	primary := &mockProvider{
+		name:       ProviderOpenAI,
+		model:      "primary",
+		configured: true,
+		responses: []mockProviderResponse{
+			{text: broken},
+			{text: validDraftJSON},
+			{text: `{"summary":"plan","topic_blocks":[]}`},
+			{text: `{"summary":"plan","topic_blocks":[]}`},
+			{text: `{"sumary":"plan"}`},
+		},
+	}
+	fallback := &mockProvider{
+		name:       ProviderLocal,
+		model:      "local",
+		configured: true,
+		responses:  []mockProviderResponse{{text: validPlanJSON}},
+	}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: primary, ProviderLocal: fallback}, nil)
+	st := &pipelineState{ownerID: 1, material: dto.MaterialInput{Text: "material"}, cfg: normalizeGenerationConfig(oneQuestionConfig, "")}
+
+	trace, err := svc.runPipelineLayer(context.Background(), layerGenerate, []string{ProviderOpenAI, ProviderLocal}, st)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if trace.Provider != ProviderOpenAI || trace.Repairs != 1 || trace.FallbackUsed {
+		t.Fatalf("expected one repair on the same provider, got %+v", trace)
+	}
+	repair := primary.requests[1].Messages
+	if last := repair[len(repair)-1].Content; !strings.Contains(last, "correct_answers index 5 is out of range") {
+		t.Fatalf("expected the repair request to list the problem, got %q", last)
+	}
+	if repair[len(repair)-2].Role != "assistant" || repair[len(repair)-2].Content != broken {
+		t.Fatal("expected the rejected answer to be sent back")
+	}
+
+	trace, err = svc.runPipelineLayer(context.Background(), layerPlan, []string{ProviderOpenAI, ProviderLocal}, st)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if trace.Provider != ProviderLocal || trace.Repairs != maxRepairAttempts || primary.calls != 5 {
+		t.Fatalf("expected fallback after %d repairs, got %+v after %d calls", maxRepairAttempts, trace, primary.calls)
+	}
+	if !strings.Contains(strings.Join(trace.Errors, "\n"), `unknown field "sumary"`) {
+		t.Fatalf("expected the unknown field to be reported, got %v", trace.Errors)
+	}
+}
@@ -5,13 +5,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"reflect"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"edu-system/internal/ai/dto"
+	"edu-system/internal/ai/runs"
 	"edu-system/internal/platform"
+	"edu-system/internal/testAttempt"
+	"github.com/google/uuid"
 )
 
 const (
@@ -21,6 +27,8 @@ const (
 	layerRefine   = "refine"
 )
 
+var pipelineLayers = []string{layerPlan, layerGenerate, layerValidate, layerRefine}
+
 var (
 	ErrNoProviderConfigured = errors.New("no configured ai providers")
 	ErrUnsupportedProvider  = errors.New("unsupported ai provider")
@@ -44,14 +52,35 @@ func (e *LayerError) Unwrap() error {
 type Service interface {
 	RunPipeline(ctx context.Context, ownerID uint, req *dto.PipelineRunRequest) (*dto.PipelineRunResponse, error)
 	ProviderStatuses() []dto.ProviderStatus
+	PublishRun(ctx context.Context, ownerID uint, author, runID string, req *dto.PublishRunRequest) (*dto.PublishRunResponse, error)
+	ListRuns(ctx context.Context, ownerID uint, limit, offset int) ([]dto.RunSummary, error)
+	GetRun(ctx context.Context, ownerID uint, runID string) (*dto.RunDetails, error)
+	RerunLayer(ctx context.Context, ownerID uint, runID, layer string, req *dto.RerunLayerRequest) (*dto.RunDetails, error)
+	StartPipelineJob(ctx context.Context, ownerID uint, req *dto.PipelineRunRequest) (*dto.JobView, error)
+	GetJob(ownerID uint, jobID string) (*dto.JobView, error)
+	SubscribeJob(ownerID uint, jobID string) ([]dto.PipelineEvent, <-chan dto.PipelineEvent, func(), error)
+	CancelJob(ownerID uint, jobID string) (*dto.JobView, error)
+	Usage(ctx context.Context, ownerID uint) (*dto.UsageReport, error)
+	SuggestGrade(ctx context.Context, ownerID testAttempt.UserID, req testAttempt.GradingRequest) (testAttempt.GradeSuggestion, error)
+	SuggestDistractors(ctx context.Context, ownerID uint, questionID string, req *dto.QuestionAssistRequest) (*dto.QuestionAssistResponse, error)
+	RewriteQuestion(ctx context.Context, ownerID uint, questionID string, req *dto.QuestionAssistRequest) (*dto.QuestionAssistResponse, error)
+	TranslateQuestion(ctx context.Context, ownerID uint, questionID string, req *dto.QuestionAssistRequest) (*dto.QuestionAssistResponse, error)
 }
 
 type service struct {
 	providers    map[string]Provider
 	defaultOrder []string
+	runs         runs.Store
+	tests        TestStore
+	jobs         *jobRegistry
+	ingest       ingestOptions
+	prompts      *promptLibrary
+
+	prices        map[string]platform.AIPrice
+	monthlyBudget float64
 }
 
-func NewService(cfg *platform.Config) Service {
+func NewService(cfg *platform.Config, store runs.Store, tests TestStore) Service {
 	timeout := time.Duration(cfg.AIHTTPTimeoutSec) * time.Second
 	if timeout <= 0 {
 		timeout = 90 * time.Second
@@ -101,17 +130,53 @@ func NewService(cfg *platform.Config) Service {
 			nil,
 			client,
 		),
+		ProviderAnthropic: newAnthropicProvider(
+			ProviderAnthropic,
+			cfg.AnthropicBaseURL,
+			cfg.AnthropicModel,
+			cfg.AnthropicAPIKey,
+			cfg.AnthropicMaxTokens,
+			client,
+		),
+		ProviderOllama: newOllamaProvider(
+			ProviderOllama,
+			cfg.OllamaBaseURL,
+			cfg.OllamaModel,
+			client,
+		),
 	}
-
-	order := normalizeProviderOrder(cfg.AIProviderOrder, false)
-	if len(order) == 0 {
-		order = defaultProviderOrder()
+	for _, extra := range cfg.AIExtraProviders {
+		provider, err := newConfiguredProvider(extra, cfg.AnthropicMaxTokens, client)
+		if err != nil {
+			log.Printf("AI providers: skipping %q: %v", extra.Name, err)
+			continue
+		}
+		if _, exists := providers[provider.Name()]; exists {
+			log.Printf("AI providers: skipping %q: name already in use", extra.Name)
+			continue
+		}
+		providers[provider.Name()] = provider
+	}
+
+	s := &service{
+		providers: providers,
+		runs:      store,
+		tests:     tests,
+		jobs:      newJobRegistry(),
+		ingest: ingestOptions{
+			maxChars:   cfg.AIMaterialMaxChars,
+			chunkChars: cfg.AIMaterialChunkChars,
+			client:     newMaterialClient(timeout, cfg.AIMaterialAllowPrivateURLs),
+		},
+		prompts:       loadPromptLibrary(cfg.AIPromptsDir, cfg.AIPromptLanguage),
+		prices:        cfg.AIPrices,
+		monthlyBudget: cfg.AIMonthlyBudgetUSD,
 	}
-
-	return &service{
-		providers:    providers,
-		defaultOrder: order,
+	s.defaultOrder = s.normalizeProviderOrder(cfg.AIProviderOrder, false)
+	if len(s.defaultOrder) == 0 {
+		s.defaultOrder = defaultProviderOrder()
 	}
+	return s
 }
 
 func (s *service) ProviderStatuses() []dto.ProviderStatus {
@@ -134,101 +199,179 @@ func (s *service) ProviderStatuses() []dto.ProviderStatus {
 }
 
 func (s *service) RunPipeline(ctx context.Context, ownerID uint, req *dto.PipelineRunRequest) (*dto.PipelineRunResponse, error) {
+	state, orders, err := s.preparePipeline(ctx, ownerID, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.executePipeline(ctx, ownerID, state, orders)
+}
+
+// preparePipeline validates a request and checks the owner's budget up
+// front, so that background jobs only start for requests that can run.
+func (s *service) preparePipeline(ctx context.Context, ownerID uint, req *dto.PipelineRunRequest) (*pipelineState, map[string][]string, error) {
 	if req == nil {
-		return nil, errors.New("request is required")
+		return nil, nil, errors.New("request is required")
 	}
 
 	material := normalizeMaterial(req.Material)
-	if material.Text == "" && material.SourceURL == "" {
-		return nil, errors.New("material.text or material.source_url is required")
+	if material.Text == "" && material.SourceURL == "" && len(req.Files) == 0 {
+		return nil, nil, fmt.Errorf("%w: material.text, material.source_url or a file is required", ErrInvalidMaterial)
 	}
 
 	cfg := normalizeGenerationConfig(req.GenerationConfig, material.Language)
+	lang, err := s.prompts.resolveLanguage(cfg.PromptLanguage, cfg.OutputLanguage)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.PromptLanguage = lang
 	orders, err := s.resolveLayerOrders(req.Provider)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if err := s.ensureBudget(ctx, ownerID, orders); err != nil {
+		return nil, nil, err
 	}
+	return &pipelineState{ownerID: ownerID, material: material, cfg: cfg, provider: req.Provider, files: req.Files}, orders, nil
+}
 
-	var plan dto.PlanOutput
-	planTrace, err := s.runLayer(
-		ctx,
-		layerPlan,
-		orders[layerPlan],
-		buildPlanMessages(material, cfg),
-		func(raw string) error {
-			return decodeModelJSON(raw, &plan)
-		},
-	)
-	if err != nil {
+func (s *service) executePipeline(ctx context.Context, ownerID uint, state *pipelineState, orders map[string][]string) (*dto.PipelineRunResponse, error) {
+	if err := s.ingestMaterial(ctx, state, orders[layerPlan]); err != nil {
 		return nil, err
 	}
-	normalizePlan(&plan, cfg)
 
-	var generated dto.GenerationOutput
-	generateTrace, err := s.runLayer(
-		ctx,
-		layerGenerate,
-		orders[layerGenerate],
-		buildGenerationMessages(material, cfg, plan),
-		func(raw string) error {
-			return decodeModelJSON(raw, &generated)
-		},
-	)
-	if err != nil {
-		return nil, err
+	traces := make([]dto.LayerProviderTrace, 0, len(pipelineLayers))
+	for _, layer := range pipelineLayers {
+		trace, err := s.runPipelineLayer(ctx, layer, orders[layer], state)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, trace)
 	}
-	normalizeGeneratedOutput(&generated, cfg)
 
-	var validation dto.ValidationOutput
-	validationTrace, err := s.runLayer(
-		ctx,
-		layerValidate,
-		orders[layerValidate],
-		buildValidationMessages(material, plan, generated),
-		func(raw string) error {
-			return decodeModelJSON(raw, &validation)
-		},
-	)
-	if err != nil {
-		return nil, err
+	run := &runs.Run{
+		ID:        uuid.New().String(),
+		OwnerID:   ownerID,
+		CreatedAt: time.Now().UTC(),
+		Material:  state.material,
+		Config:    state.cfg,
+		Provider:  state.provider,
+		Ingestion: state.ingestion,
+		Result:    state.response(traces),
+	}
+	run.Result.RunID = run.ID
+	if err := s.runs.SaveRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("save pipeline run: %w", err)
 	}
-	normalizeValidation(&validation)
+	result := run.Result
+	return &result, nil
+}
 
-	var finalResult dto.FinalOutput
-	refineTrace, err := s.runLayer(
-		ctx,
-		layerRefine,
-		orders[layerRefine],
-		buildRefineMessages(material, cfg, plan, generated, validation),
-		func(raw string) error {
-			return decodeModelJSON(raw, &finalResult)
-		},
-	)
-	if err != nil {
-		return nil, err
+// pipelineState carries the input of a run and the output of every layer
+// finished so far; each layer builds its prompt from the earlier ones.
+type pipelineState struct {
+	ownerID    uint
+	material   dto.MaterialInput
+	cfg        dto.GenerationConfig
+	provider   *dto.ProviderSelection
+	files      []dto.MaterialFile
+	ingestion  dto.MaterialIngestion
+	notify     func(dto.PipelineEvent)
+	plan       dto.PlanOutput
+	draft      dto.GenerationOutput
+	validation dto.ValidationOutput
+	final      dto.FinalOutput
+}
+
+func (st *pipelineState) response(traces []dto.LayerProviderTrace) dto.PipelineRunResponse {
+	return dto.PipelineRunResponse{
+		Plan:          st.plan,
+		Draft:         st.draft,
+		Validation:    st.validation,
+		Final:         st.final,
+		ProviderTrace: traces,
 	}
-	normalizeFinal(&finalResult, generated, validation)
+}
 
-	_ = ownerID // retained for future audit/logging use
+func (s *service) runPipelineLayer(ctx context.Context, layer string, order []string, st *pipelineState) (dto.LayerProviderTrace, error) {
+	p, err := s.prompts.render(layer, st.cfg.PromptLanguage, promptData{
+		Material:   st.material,
+		Config:     st.cfg,
+		Plan:       st.plan,
+		Draft:      st.draft,
+		Validation: st.validation,
+	})
+	if err != nil {
+		return dto.LayerProviderTrace{Layer: layer}, err
+	}
+	return s.runLayerPrompt(ctx, layer, order, st, p)
+}
 
-	return &dto.PipelineRunResponse{
-		Plan:       plan,
-		Draft:      generated,
-		Validation: validation,
-		Final:      finalResult,
-		ProviderTrace: []dto.LayerProviderTrace{
-			planTrace,
-			generateTrace,
-			validationTrace,
-			refineTrace,
-		},
-	}, nil
+func (s *service) runLayerPrompt(ctx context.Context, layer string, order []string, st *pipelineState, p prompt) (dto.LayerProviderTrace, error) {
+	switch layer {
+	case layerPlan:
+		var plan dto.PlanOutput
+		trace, err := s.runLayer(ctx, st.ownerID, layer, order, st.notify, p, func(raw string) error {
+			if err := decodeModelJSON(raw, &plan); err != nil {
+				return err
+			}
+			return checkPlan(&plan, st.cfg)
+		})
+		if err != nil {
+			return trace, err
+		}
+		normalizePlan(&plan, st.cfg)
+		st.plan = plan
+		return trace, nil
+	case layerGenerate:
+		var generated dto.GenerationOutput
+		trace, err := s.runLayer(ctx, st.ownerID, layer, order, st.notify, p, func(raw string) error {
+			if err := decodeModelJSON(raw, &generated); err != nil {
+				return err
+			}
+			return checkGeneration(&generated, st.cfg)
+		})
+		if err != nil {
+			return trace, err
+		}
+		normalizeGeneratedOutput(&generated, st.cfg)
+		st.draft = generated
+		return trace, nil
+	case layerValidate:
+		var validation dto.ValidationOutput
+		trace, err := s.runLayer(ctx, st.ownerID, layer, order, st.notify, p, func(raw string) error {
+			if err := decodeModelJSON(raw, &validation); err != nil {
+				return err
+			}
+			return checkValidation(&validation)
+		})
+		if err != nil {
+			return trace, err
+		}
+		normalizeValidation(&validation)
+		st.validation = validation
+		return trace, nil
+	case layerRefine:
+		var finalResult dto.FinalOutput
+		trace, err := s.runLayer(ctx, st.ownerID, layer, order, st.notify, p, func(raw string) error {
+			if err := decodeModelJSON(raw, &finalResult); err != nil {
+				return err
+			}
+			return checkFinal(&finalResult, st.cfg)
+		})
+		if err != nil {
+			return trace, err
+		}
+		normalizeFinal(&finalResult, st.draft, st.validation)
+		st.final = finalResult
+		return trace, nil
+	}
+	return dto.LayerProviderTrace{}, fmt.Errorf("%w: %s", ErrInvalidLayer, layer)
 }
 
 func (s *service) resolveLayerOrders(selection *dto.ProviderSelection) (map[string][]string, error) {
 	baseOrder := append([]string(nil), s.defaultOrder...)
 	if selection != nil && len(selection.Order) > 0 {
-		strictOrder, err := normalizeProviderOrderWithErr(selection.Order, true)
+		strictOrder, err := s.normalizeProviderOrderWithErr(selection.Order, true)
 		if err != nil {
 			return nil, err
 		}
@@ -238,9 +381,8 @@ func (s *service) resolveLayerOrders(selection *dto.ProviderSelection) (map[stri
 		return nil, ErrNoProviderConfigured
 	}
 
-	layers := []string{layerPlan, layerGenerate, layerValidate, layerRefine}
-	result := make(map[string][]string, len(layers))
-	for _, layer := range layers {
+	result := make(map[string][]string, len(pipelineLayers))
+	for _, layer := range pipelineLayers {
 		result[layer] = append([]string(nil), baseOrder...)
 	}
 
@@ -253,7 +395,7 @@ func (s *service) resolveLayerOrders(selection *dto.ProviderSelection) (map[stri
 		if _, exists := result[layer]; !exists {
 			return nil, fmt.Errorf("%w: %s", ErrInvalidLayer, layerName)
 		}
-		normalized, err := normalizeProviderOrderWithErr(order, true)
+		normalized, err := s.normalizeProviderOrderWithErr(order, true)
 		if err != nil {
 			return nil, err
 		}
@@ -266,22 +408,43 @@ func (s *service) resolveLayerOrders(selection *dto.ProviderSelection) (map[stri
 	return result, nil
 }
 
+// runLayer tries the providers in order until one returns output that
+// decodeFn accepts. A rejected output is sent back to the same provider with
+// the reasons, up to maxRepairAttempts times, before moving on. Paid
+// providers are skipped once the owner's budget is spent. Progress is
+// reported through notify, which may be nil.
 func (s *service) runLayer(
 	ctx context.Context,
+	ownerID uint,
 	layer string,
 	order []string,
-	messages []Message,
+	notify func(dto.PipelineEvent),
+	p prompt,
 	decodeFn func(raw string) error,
 ) (dto.LayerProviderTrace, error) {
-	trace := dto.LayerProviderTrace{Layer: layer}
-	if len(order) == 0 {
-		return trace, &LayerError{Layer: layer, Err: ErrNoProviderConfigured}
-	}
+	started := time.Now()
+	trace := dto.LayerProviderTrace{Layer: layer, StartedAt: started.UTC(), PromptVersion: p.version}
+	emit(notify, dto.PipelineEvent{Type: dto.EventLayerStarted, Layer: layer})
 
 	errorMessages := make([]string, 0)
 	attempts := 0
+	budgetSkipped := false
+	fail := func(err error) (dto.LayerProviderTrace, error) {
+		trace.Attempts = attempts
+		trace.Errors = errorMessages
+		trace.DurationMs = time.Since(started).Milliseconds()
+		emit(notify, dto.PipelineEvent{Type: dto.EventLayerFailed, Layer: layer, Message: err.Error()})
+		return trace, &LayerError{Layer: layer, Err: err}
+	}
+	if len(order) == 0 {
+		return fail(ErrNoProviderConfigured)
+	}
 
+providers:
 	for idx, providerName := range order {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
 		provider, exists := s.providers[providerName]
 		if !exists {
 			errorMessages = append(errorMessages, fmt.Sprintf("provider %s is not registered", providerName))
@@ -292,41 +455,89 @@ func (s *service) runLayer(
 			continue
 		}
 
-		attempts++
-		layerCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
-		completion, err := provider.Complete(layerCtx, CompletionRequest{
-			Messages:    messages,
-			Temperature: 0.2,
-			RequireJSON: true,
-		})
-		cancel()
-		if err != nil {
-			errorMessages = append(errorMessages, fmt.Sprintf("provider %s request error: %v", providerName, err))
-			continue
-		}
+		conversation := p.messages
+		for repair := 0; ; repair++ {
+			if s.paid(providerName) {
+				if err := s.checkBudget(ctx, ownerID); err != nil {
+					errorMessages = append(errorMessages, fmt.Sprintf("provider %s skipped: %v", providerName, err))
+					budgetSkipped = budgetSkipped || errors.Is(err, ErrBudgetExceeded)
+					continue providers
+				}
+			}
+			if repair == 0 {
+				attempts++
+			}
 
-		if err := decodeFn(completion.Text); err != nil {
-			errorMessages = append(errorMessages, fmt.Sprintf("provider %s parse error: %v", providerName, err))
-			continue
-		}
+			layerCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+			completion, err := s.complete(layerCtx, provider, layer, notify, CompletionRequest{
+				Messages:    conversation,
+				Temperature: 0.2,
+				RequireJSON: true,
+			})
+			cancel()
+			if err == nil {
+				addUsage(&trace.Usage, s.recordUsage(ctx, ownerID, layer, providerName, completion))
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return fail(ctxErr)
+			}
+			if err != nil {
+				msg := fmt.Sprintf("provider %s request error: %v", providerName, err)
+				errorMessages = append(errorMessages, msg)
+				emit(notify, dto.PipelineEvent{Type: dto.EventFallback, Layer: layer, Provider: providerName, Message: msg})
+				continue providers
+			}
 
-		trace.Provider = providerName
-		trace.Model = completion.Model
-		trace.Attempts = attempts
-		trace.FallbackUsed = idx > 0 || attempts > 1
-		trace.Errors = errorMessages
-		return trace, nil
+			if err := decodeFn(completion.Text); err != nil {
+				if repair < maxRepairAttempts {
+					// Show the provider its own answer and what is wrong with it.
+					trace.Repairs++
+					errorMessages = append(errorMessages, fmt.Sprintf("provider %s output rejected, repair requested: %v", providerName, err))
+					emit(notify, dto.PipelineEvent{Type: dto.EventRepair, Layer: layer, Provider: providerName, Message: err.Error()})
+					conversation = append(slices.Clip(conversation),
+						Message{Role: "assistant", Content: completion.Text},
+						s.prompts.repair(p.language, err),
+					)
+					continue
+				}
+				msg := fmt.Sprintf("provider %s parse error: %v", providerName, err)
+				errorMessages = append(errorMessages, msg)
+				emit(notify, dto.PipelineEvent{Type: dto.EventFallback, Layer: layer, Provider: providerName, Message: msg})
+				continue providers
+			}
+
+			trace.Provider = providerName
+			trace.Model = completion.Model
+			trace.Attempts = attempts
+			trace.FallbackUsed = idx > 0 || attempts > 1
+			trace.Errors = errorMessages
+			trace.DurationMs = time.Since(started).Milliseconds()
+			emit(notify, dto.PipelineEvent{Type: dto.EventLayerCompleted, Layer: layer, Provider: providerName, Model: completion.Model})
+			return trace, nil
+		}
 	}
 
-	trace.Attempts = attempts
-	trace.Errors = errorMessages
+	if attempts == 0 && budgetSkipped {
+		return fail(ErrBudgetExceeded)
+	}
 	if attempts == 0 {
-		return trace, &LayerError{Layer: layer, Err: ErrNoProviderConfigured}
+		return fail(ErrNoProviderConfigured)
 	}
 
-	return trace, &LayerError{Layer: layer, Err: ErrLayerFailed}
+	return fail(ErrLayerFailed)
 }
 
+func emit(notify func(dto.PipelineEvent), event dto.PipelineEvent) {
+	if notify == nil {
+		return
+	}
+	event.At = time.Now().UTC()
+	notify(event)
+}
+
+// decodeModelJSON finds the JSON object in a model response and decodes it
+// into out, rejecting fields the schema does not know so that misspelt keys
+// are caught instead of silently lost.
 func decodeModelJSON(raw string, out any) error {
 	candidates := make([]string, 0, 3)
 	clean := strings.TrimSpace(raw)
@@ -342,6 +553,7 @@ func decodeModelJSON(raw string, out any) error {
 		candidates = append(candidates, jsonObj)
 	}
 
+	var schemaErr error
 	seen := map[string]struct{}{}
 	for _, candidate := range candidates {
 		candidate = strings.TrimSpace(candidate)
@@ -352,11 +564,24 @@ func decodeModelJSON(raw string, out any) error {
 			continue
 		}
 		seen[candidate] = struct{}{}
-		if err := json.Unmarshal([]byte(candidate), out); err == nil {
+		if !json.Valid([]byte(candidate)) {
+			continue
+		}
+		reflect.ValueOf(out).Elem().SetZero()
+		decoder := json.NewDecoder(strings.NewReader(candidate))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(out)
+		if err == nil {
 			return nil
 		}
+		if schemaErr == nil {
+			schemaErr = err
+		}
 	}
 
+	if schemaErr != nil {
+		return fmt.Errorf("response does not match the schema: %w", schemaErr)
+	}
 	return errors.New("could not parse JSON from model response")
 }
 
@@ -432,10 +657,6 @@ func normalizeMaterial(material dto.MaterialInput) dto.MaterialInput {
 	material.Language = strings.TrimSpace(material.Language)
 	material.AdditionalNote = strings.TrimSpace(material.AdditionalNote)
 
-	if material.Text == "" && material.SourceURL != "" {
-		material.Text = fmt.Sprintf("Primary source URL: %s", material.SourceURL)
-	}
-
 	return material
 }
 
@@ -574,15 +795,18 @@ func maxInt(a, b int) int {
 	return b
 }
 
-func normalizeProviderOrder(values []string, strict bool) []string {
-	order, err := normalizeProviderOrderWithErr(values, strict)
+func (s *service) normalizeProviderOrder(values []string, strict bool) []string {
+	order, err := s.normalizeProviderOrderWithErr(values, strict)
 	if err != nil {
 		return nil
 	}
 	return order
 }
 
-func normalizeProviderOrderWithErr(values []string, strict bool) ([]string, error) {
+// normalizeProviderOrderWithErr keeps the registered provider names of
+// values, lowercased and without duplicates. In strict mode an unknown name
+// is an error.
+func (s *service) normalizeProviderOrderWithErr(values []string, strict bool) ([]string, error) {
 	if len(values) == 0 {
 		return nil, nil
 	}
@@ -595,7 +819,7 @@ func normalizeProviderOrderWithErr(values []string, strict bool) ([]string, erro
 		if name == "" {
 			continue
 		}
-		if _, ok := supportedProviders[name]; !ok {
+		if _, ok := s.providers[name]; !ok {
 			if strict {
 				return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, raw)
 			}
@@ -615,8 +839,10 @@ func defaultProviderOrder() []string {
 	return []string{
 		ProviderOpenAI,
 		ProviderGemini,
+		ProviderAnthropic,
 		ProviderDeepSeek,
 		ProviderOpenRouter,
+		ProviderOllama,
 		ProviderLocal,
 	}
 }
@@ -626,5 +852,9 @@ func newServiceWithProviders(providers map[string]Provider, defaultOrder []strin
 	if len(order) == 0 {
 		order = defaultProviderOrder()
 	}
-	return &service{providers: providers, defaultOrder: order}
+	return &service{providers: providers, defaultOrder: order, runs: runs.NewMemoryStore(), jobs: newJobRegistry(),
+		ingest:  ingestOptions{maxChars: 30000, chunkChars: 12000, client: newMaterialClient(10*time.Second, true)},
+		prices:  map[string]platform.AIPrice{},
+		prompts: loadPromptLibrary("", "ru"),
+	}
 }
@@ -14,11 +14,13 @@ type mockProvider struct {
 	configured bool
 	responses  []mockProviderResponse
 	calls      int
+	requests   []CompletionRequest
 }
 
 type mockProviderResponse struct {
-	text string
-	err  error
+	text  string
+	usage Usage
+	err   error
 }
 
 func (m *mockProvider) Name() string {
@@ -33,7 +35,8 @@ func (m *mockProvider) IsConfigured() bool {
 	return m.configured
 }
 
-func (m *mockProvider) Complete(_ context.Context, _ CompletionRequest) (*CompletionResponse, error) {
+func (m *mockProvider) Complete(_ context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	m.requests = append(m.requests, req)
 	if m.calls >= len(m.responses) {
 		m.calls++
 		return nil, errors.New("no mock response")
@@ -43,7 +46,21 @@ func (m *mockProvider) Complete(_ context.Context, _ CompletionRequest) (*Comple
 	if resp.err != nil {
 		return nil, resp.err
 	}
-	return &CompletionResponse{Text: resp.text, Model: m.model}, nil
+	return &CompletionResponse{Text: resp.text, Model: m.model, Usage: resp.usage}, nil
+}
+
+// Layer outputs that pass the checks for a run of one variant with one
+// question.
+const (
+	validPlanJSON       = `{"summary":"plan","topic_blocks":[{"topic":"T1","weight_percent":100}]}`
+	validDraftJSON      = `{"test_variants":[{"title":"V1","questions":[{"question":"Q1","type":"single","options":["A","B"],"correct_answers":[0]}]}],"study_notes":{"title":"Notes","summary":"S"},"practice_test":{"title":"Practice","questions":[{"question":"PQ1","type":"text"}]}}`
+	validValidationJSON = `{"is_aligned":true,"alignment_score":90,"summary":"validated"}`
+)
+
+var oneQuestionConfig = dto.GenerationConfig{VariantsCount: 1, QuestionsPerVariant: 1}
+
+func validFinalJSON(summary string) string {
+	return `{"teacher_summary":"` + summary + `",` + validDraftJSON[1:]
 }
 
 func TestRunPipelineFallbackProviders(t *testing.T) {
@@ -64,7 +81,7 @@ func TestRunPipelineFallbackProviders(t *testing.T) {
 		model:      "local-model",
 		configured: true,
 		responses: []mockProviderResponse{
-			{text: `{"summary":"plan","learning_objectives":["obj"],"topic_blocks":[{"topic":"T1","weight_percent":100,"key_facts":["f1"]}],"test_blueprint":{"variants_count":2,"questions_per_variant":5,"question_type_targets":[{"type":"single","count":5,"focus":"core"}]},"assumptions":[],"risks":[]}`},
+			{text: `{"summary":"plan","learning_objectives":["obj"],"topic_blocks":[{"topic":"T1","weight_percent":100,"key_facts":["f1"]}],"test_blueprint":{"variants_count":1,"questions_per_variant":1,"question_type_targets":[{"type":"single","count":1,"focus":"core"}]},"assumptions":[],"risks":[]}`},
 			{text: `{"test_variants":[{"title":"V1","instructions":"instr","questions":[{"question":"Q1","type":"single","options":["A","B"],"correct_answers":[0],"explanation":"exp"}]}],"study_notes":{"title":"Notes","summary":"S","key_points":["K"],"common_mistakes":["M"],"preparation_advice":["P"]},"practice_test":{"title":"Practice","questions":[{"question":"PQ1","type":"single","options":["A","B"],"correct_answers":[1],"explanation":"pexp"}]}}`},
 			{text: `{"is_aligned":true,"alignment_score":96,"issues":[],"missing_topics":[],"extra_topics":[],"summary":"validated"}`},
 			{text: `{"teacher_summary":"ready","ready_for_use":true,"applied_fixes":["none"],"unresolved_warnings":[],"test_variants":[{"title":"V1","instructions":"instr","questions":[{"question":"Q1","type":"single","options":["A","B"],"correct_answers":[0],"explanation":"exp"}]}],"study_notes":{"title":"Notes","summary":"S","key_points":["K"],"common_mistakes":["M"],"preparation_advice":["P"]},"practice_test":{"title":"Practice","questions":[{"question":"PQ1","type":"single","options":["A","B"],"correct_answers":[1],"explanation":"pexp"}]}}`},
@@ -82,8 +99,8 @@ func TestRunPipelineFallbackProviders(t *testing.T) {
 	req := &dto.PipelineRunRequest{
 		Material: dto.MaterialInput{Text: "methodical material"},
 		GenerationConfig: dto.GenerationConfig{
-			VariantsCount:       2,
-			QuestionsPerVariant: 5,
+			VariantsCount:       1,
+			QuestionsPerVariant: 1,
 		},
 	}
 
@@ -123,3 +140,87 @@ func TestDecodeModelJSONWithMarkdownFence(t *testing.T) {
 		t.Fatalf("expected summary=ok, got %q", out.Summary)
 	}
 }
+
+func TestRerunLayerKeepsEarlierLayers(t *testing.T) {
+	provider := &mockProvider{
+		name:       ProviderOpenAI,
+		model:      "model",
+		configured: true,
+		responses: []mockProviderResponse{
+			{text: validPlanJSON},
+			{text: validDraftJSON},
+			{text: `{"is_aligned":false,"alignment_score":40,"summary":"needs work"}`},
+			{text: validFinalJSON("first")},
+			{text: validFinalJSON("second")},
+		},
+	}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: provider}, []string{ProviderOpenAI})
+
+	first, err := svc.RunPipeline(context.Background(), 7, &dto.PipelineRunRequest{Material: dto.MaterialInput{Title: "M", Text: "material"}, GenerationConfig: oneQuestionConfig})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rerun, err := svc.RerunLayer(context.Background(), 7, first.RunID, "refine", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider.calls != 5 {
+		t.Fatalf("expected only the refine layer to call the provider again, got %d calls", provider.calls)
+	}
+	if rerun.ParentRunID != first.RunID || rerun.RunID == first.RunID {
+		t.Fatalf("expected a new run pointing at %s, got %s (parent %s)", first.RunID, rerun.RunID, rerun.ParentRunID)
+	}
+	if rerun.Plan.Summary != "plan" || rerun.Validation.Summary != "needs work" || rerun.Final.TeacherSummary != "second" {
+		t.Fatalf("unexpected rerun outputs: %+v", rerun.PipelineRunResponse)
+	}
+	if len(rerun.ProviderTrace) != 4 || rerun.ProviderTrace[3].Layer != layerRefine {
+		t.Fatalf("unexpected traces: %+v", rerun.ProviderTrace)
+	}
+
+	if _, err := svc.RerunLayer(context.Background(), 8, first.RunID, "refine", nil); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound for another owner, got %v", err)
+	}
+	history, err := svc.ListRuns(context.Background(), 7, 0, 0)
+	if err != nil || len(history) != 2 || history[0].RunID != rerun.RunID {
+		t.Fatalf("unexpected history: %+v, %v", history, err)
+	}
+	if page, _ := svc.ListRuns(context.Background(), 7, 1, 1); len(page) != 1 || page[0].RunID != first.RunID {
+		t.Fatalf("unexpected second page: %+v", page)
+	}
+}
+
+func TestRerunLayerRerunsLaterLayers(t *testing.T) {
+	provider := &mockProvider{
+		name:       ProviderOpenAI,
+		model:      "model",
+		configured: true,
+		responses: []mockProviderResponse{
+			{text: validPlanJSON},
+			{text: validDraftJSON},
+			{text: `{"is_aligned":false,"alignment_score":40,"summary":"needs work"}`},
+			{text: validFinalJSON("first")},
+			{text: `{"is_aligned":true,"alignment_score":90,"summary":"fine"}`},
+			{text: validFinalJSON("second")},
+		},
+	}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: provider}, []string{ProviderOpenAI})
+
+	first, err := svc.RunPipeline(context.Background(), 7, &dto.PipelineRunRequest{Material: dto.MaterialInput{Title: "M", Text: "material"}, GenerationConfig: oneQuestionConfig})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rerun, err := svc.RerunLayer(context.Background(), 7, first.RunID, "validate", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if provider.calls != 6 {
+		t.Fatalf("expected validate and refine to run again, got %d calls", provider.calls)
+	}
+	if rerun.Validation.Summary != "fine" || rerun.Final.TeacherSummary != "second" {
+		t.Fatalf("final output must follow the new validation: %+v", rerun.PipelineRunResponse)
+	}
+	if len(rerun.ProviderTrace) != 4 || rerun.ProviderTrace[2].Layer != layerValidate || rerun.ProviderTrace[3].Layer != layerRefine {
+		t.Fatalf("unexpected traces: %+v", rerun.ProviderTrace)
+	}
+}
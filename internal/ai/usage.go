@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"edu-system/internal/ai/dto"
+	"edu-system/internal/ai/runs"
+)
+
+var ErrBudgetExceeded = errors.New("monthly ai budget exceeded")
+
+// progressInterval throttles the layer_progress events of a streamed
+// completion.
+const progressInterval = time.Second
+
+func (s *service) Usage(ctx context.Context, ownerID uint) (*dto.UsageReport, error) {
+	since := monthStart(time.Now())
+	spent, err := s.runs.SpentSince(ctx, ownerID, since)
+	if err != nil {
+		return nil, err
+	}
+	report := &dto.UsageReport{PeriodStart: since, SpentUSD: spent}
+	if s.monthlyBudget > 0 {
+		remaining := max(s.monthlyBudget-spent, 0)
+		report.BudgetUSD = s.monthlyBudget
+		report.RemainingUSD = &remaining
+	}
+	return report, nil
+}
+
+// paid reports whether calls to the provider cost money and so count
+// against the budget.
+func (s *service) paid(provider string) bool {
+	price := s.prices[provider]
+	return price.InputPerMTok > 0 || price.OutputPerMTok > 0
+}
+
+func (s *service) cost(provider string, usage Usage) float64 {
+	price := s.prices[provider]
+	return (float64(usage.PromptTokens)*price.InputPerMTok + float64(usage.CompletionTokens)*price.OutputPerMTok) / 1e6
+}
+
+// checkBudget fails with ErrBudgetExceeded once the owner has spent the
+// monthly budget.
+func (s *service) checkBudget(ctx context.Context, ownerID uint) error {
+	if s.monthlyBudget <= 0 {
+		return nil
+	}
+	spent, err := s.runs.SpentSince(ctx, ownerID, monthStart(time.Now()))
+	if err != nil {
+		return err
+	}
+	if spent >= s.monthlyBudget {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// ensureBudget refuses a run up front when the budget is spent and some
+// layer has no free provider to fall back to.
+func (s *service) ensureBudget(ctx context.Context, ownerID uint, orders map[string][]string) error {
+	err := s.checkBudget(ctx, ownerID)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		return err
+	}
+	for _, order := range orders {
+		free := false
+		for _, name := range order {
+			if provider, ok := s.providers[name]; ok && provider.IsConfigured() && !s.paid(name) {
+				free = true
+				break
+			}
+		}
+		if !free {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordUsage stores the cost of a call and returns it. A failure to store
+// is logged rather than failing a call that has already been paid for.
+func (s *service) recordUsage(ctx context.Context, ownerID uint, layer, provider string, completion *CompletionResponse) dto.LayerUsage {
+	usage := dto.LayerUsage{
+		PromptTokens:     completion.Usage.PromptTokens,
+		CompletionTokens: completion.Usage.CompletionTokens,
+		CostUSD:          s.cost(provider, completion.Usage),
+	}
+	err := s.runs.RecordUsage(context.WithoutCancel(ctx), &runs.Usage{
+		OwnerID:          ownerID,
+		Layer:            layer,
+		Provider:         provider,
+		Model:            completion.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          usage.CostUSD,
+		CreatedAt:        time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("AI usage: failed to record %s call for user %d: %v", provider, ownerID, err)
+	}
+	return usage
+}
+
+// complete streams the completion when someone listens for progress and the
+// provider supports it, and makes a plain call otherwise.
+func (s *service) complete(ctx context.Context, provider Provider, layer string, notify func(dto.PipelineEvent), req CompletionRequest) (*CompletionResponse, error) {
+	streamer, ok := provider.(StreamingProvider)
+	if notify == nil || !ok {
+		return provider.Complete(ctx, req)
+	}
+	chars := 0
+	last := time.Now()
+	return streamer.Stream(ctx, req, func(delta string) {
+		chars += utf8.RuneCountInString(delta)
+		if time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+		emit(notify, dto.PipelineEvent{Type: dto.EventLayerProgress, Layer: layer, Provider: provider.Name(), Chars: chars})
+	})
+}
+
+func monthStart(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func addUsage(total *dto.LayerUsage, usage dto.LayerUsage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.CostUSD += usage.CostUSD
+}
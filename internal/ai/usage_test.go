@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"edu-system/internal/ai/dto"
+	"edu-system/internal/ai/runs"
+	"edu-system/internal/platform"
+)
+
+func TestProvidersReportUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/chat/completions" && strings.Contains(readBody(r), `"stream":true`):
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"model\":\"m\",\"choices\":[{\"delta\":{\"content\":\"{\\\"a\\\":\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"1}\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":5}}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		case r.URL.Path == "/v1/chat/completions":
+			fmt.Fprint(w, `{"model":"m","choices":[{"message":{"content":"{\"a\":1}"}}],"usage":{"prompt_tokens":10,"completion_tokens":4}}`)
+		case strings.HasSuffix(r.URL.Path, ":streamGenerateContent"):
+			fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"{\\\"a\\\":\"}]}}],\"usageMetadata\":{\"promptTokenCount\":7}}\n\n")
+			fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"1}\"}]}}],\"usageMetadata\":{\"promptTokenCount\":7,\"candidatesTokenCount\":3}}\n\n")
+		default:
+			fmt.Fprint(w, `{"candidates":[{"content":{"parts":[{"text":"{\"a\":1}"}]}}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":2,"thoughtsTokenCount":5}}`)
+		}
+	}))
+	defer server.Close()
+
+	openai := newOpenAICompatibleProvider(ProviderOpenAI, server.URL+"/v1", "m", "key", false, nil, server.Client()).(StreamingProvider)
+	gemini := newGeminiProvider(server.URL, "g", "key", server.Client()).(StreamingProvider)
+	req := CompletionRequest{Messages: []Message{{Role: "user", Content: "hi"}}, RequireJSON: true}
+
+	cases := []struct {
+		name   string
+		call   func(func(string)) (*CompletionResponse, error)
+		stream bool
+		want   Usage
+	}{
+		{"openai", func(func(string)) (*CompletionResponse, error) { return openai.Complete(context.Background(), req) }, false, Usage{10, 4}},
+		{"openai stream", func(d func(string)) (*CompletionResponse, error) { return openai.Stream(context.Background(), req, d) }, true, Usage{12, 5}},
+		{"gemini", func(func(string)) (*CompletionResponse, error) { return gemini.Complete(context.Background(), req) }, false, Usage{8, 7}},
+		{"gemini stream", func(d func(string)) (*CompletionResponse, error) { return gemini.Stream(context.Background(), req, d) }, true, Usage{7, 3}},
+	}
+	for _, tc := range cases {
+		deltas := 0
+		resp, err := tc.call(func(string) { deltas++ })
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if resp.Text != `{"a":1}` || resp.Usage != tc.want {
+			t.Fatalf("%s: got text %q usage %+v, want usage %+v", tc.name, resp.Text, resp.Usage, tc.want)
+		}
+		if tc.stream && deltas != 2 {
+			t.Fatalf("%s: expected 2 deltas, got %d", tc.name, deltas)
+		}
+	}
+}
+
+func readBody(r *http.Request) string {
+	data, _ := io.ReadAll(r.Body)
+	return string(data)
+}
+
+func TestBudgetSkipsPaidProviders(t *testing.T) {
+	paid := &mockProvider{
+		name:       ProviderOpenAI,
+		model:      "paid",
+		configured: true,
+		responses: []mockProviderResponse{
+			{text: validPlanJSON, usage: Usage{PromptTokens: 1_000_000, CompletionTokens: 500_000}},
+		},
+	}
+	local := &mockProvider{
+		name:       ProviderLocal,
+		model:      "free",
+		configured: true,
+		responses: []mockProviderResponse{
+			{text: validDraftJSON},
+			{text: validValidationJSON},
+			{text: validFinalJSON("ready")},
+		},
+	}
+	svc := newServiceWithProviders(map[string]Provider{ProviderOpenAI: paid, ProviderLocal: local}, []string{ProviderOpenAI, ProviderLocal})
+	svc.prices = map[string]platform.AIPrice{ProviderOpenAI: {InputPerMTok: 1, OutputPerMTok: 2}}
+	svc.monthlyBudget = 1.5
+
+	resp, err := svc.RunPipeline(context.Background(), 5, &dto.PipelineRunRequest{Material: dto.MaterialInput{Text: "material"}, GenerationConfig: oneQuestionConfig})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resp.ProviderTrace[0].Usage; got.PromptTokens != 1_000_000 || got.CostUSD != 2 {
+		t.Fatalf("unexpected plan usage: %+v", got)
+	}
+	for _, trace := range resp.ProviderTrace[1:] {
+		if trace.Provider != ProviderLocal {
+			t.Fatalf("expected layer %s to fall back to the free provider once over budget, got %s", trace.Layer, trace.Provider)
+		}
+	}
+	if paid.calls != 1 {
+		t.Fatalf("expected the paid provider to be called once, got %d", paid.calls)
+	}
+
+	report, err := svc.Usage(context.Background(), 5)
+	if err != nil || report.SpentUSD != 2 || *report.RemainingUSD != 0 {
+		t.Fatalf("unexpected usage report: %+v, %v", report, err)
+	}
+
+	paidOnly := newServiceWithProviders(map[string]Provider{ProviderOpenAI: paid}, []string{ProviderOpenAI})
+	paidOnly.prices = svc.prices
+	paidOnly.monthlyBudget = 1
+	_ = paidOnly.runs.RecordUsage(context.Background(), &runs.Usage{OwnerID: 5, CostUSD: 1, CreatedAt: time.Now().UTC()})
+	if _, err := paidOnly.RunPipeline(context.Background(), 5, &dto.PipelineRunRequest{Material: dto.MaterialInput{Text: "material"}}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if paid.calls != 1 {
+		t.Fatal("expected no provider call once the budget is spent")
+	}
+}
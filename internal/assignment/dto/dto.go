@@ -1,10 +1,22 @@
 package dto
 
+import "time"
+
 type CreateAssignmentRequest struct {
-	TestID string                `json:"test_id" binding:"required"`
-	Title  string                `json:"title"`
-	Comment string               `json:"comment"`
-	Fields []AssignmentFieldSpec `json:"fields"`
+	TestID  string                `json:"test_id" binding:"required"`
+	Title   string                `json:"title"`
+	Comment string                `json:"comment"`
+	Fields  []AssignmentFieldSpec `json:"fields"`
+}
+
+// UpdateAssignmentRequest replaces every editable setting, so title and
+// comment must be sent even when unchanged. A null or omitted window bound
+// means the assignment uses the bound of the test window.
+type UpdateAssignmentRequest struct {
+	Title          string     `json:"title" binding:"required"`
+	Comment        *string    `json:"comment" binding:"required"`
+	AvailableFrom  *time.Time `json:"available_from"`
+	AvailableUntil *time.Time `json:"available_until"`
 }
 
 type AssignmentView struct {
@@ -18,10 +30,17 @@ type AssignmentView struct {
 	DurationSec       int                   `json:"duration_sec,omitempty"`
 	MaxAttemptTimeSec int64                 `json:"max_attempt_time_sec,omitempty"`
 	IsOwner           bool                  `json:"is_owner"`
+	AvailableFrom     *time.Time            `json:"available_from,omitempty"`
+	AvailableUntil    *time.Time            `json:"available_until,omitempty"`
+	Closed            bool                  `json:"closed"`
+	ClosedAt          *time.Time            `json:"closed_at,omitempty"`
 }
 
 type AssignmentFieldSpec struct {
-	Key      string `json:"key"`
-	Label    string `json:"label"`
-	Required bool   `json:"required"`
+	Key       string   `json:"key"`
+	Label     string   `json:"label"`
+	Required  bool     `json:"required"`
+	Pattern   string   `json:"pattern,omitempty"`
+	MaxLength int      `json:"max_length,omitempty"`
+	Options   []string `json:"options,omitempty"`
 }
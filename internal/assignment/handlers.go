@@ -1,6 +1,7 @@
 package assignment
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -35,9 +36,12 @@ func (h *Handlers) Create(c *gin.Context) {
 	fields := make([]TemplateField, 0, len(req.Fields))
 	for _, f := range req.Fields {
 		fields = append(fields, TemplateField{
-			Key:      strings.TrimSpace(f.Key),
-			Label:    strings.TrimSpace(f.Label),
-			Required: f.Required,
+			Key:       strings.TrimSpace(f.Key),
+			Label:     strings.TrimSpace(f.Label),
+			Required:  f.Required,
+			Pattern:   strings.TrimSpace(f.Pattern),
+			MaxLength: f.MaxLength,
+			Options:   f.Options,
 		})
 	}
 
@@ -48,6 +52,10 @@ func (h *Handlers) Create(c *gin.Context) {
 		if err == ErrForbidden {
 			status = http.StatusForbidden
 			msg = "not allowed"
+		} else if errors.Is(err, ErrPoolTooSmall) {
+			status = http.StatusUnprocessableEntity
+		} else if errors.Is(err, ErrInvalidField) {
+			status = http.StatusBadRequest
 		}
 		c.JSON(status, response.ErrorResponse{Error: "assignment_create_failed", Message: msg})
 		return
@@ -112,6 +120,87 @@ func (h *Handlers) Get(c *gin.Context) {
 	c.JSON(http.StatusOK, toView(*assignment, settings, isOwner))
 }
 
+func (h *Handlers) Update(c *gin.Context) {
+	var req dto.UpdateAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{Error: "invalid payload", Message: err.Error()})
+		return
+	}
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	assignment, err := h.svc.Update(c, uint(ownerID), c.Param("id"), UpdateParams{
+		Title:          req.Title,
+		Comment:        *req.Comment,
+		AvailableFrom:  req.AvailableFrom,
+		AvailableUntil: req.AvailableUntil,
+	})
+	if err != nil {
+		writeErr(c, "assignment_update_failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, toView(*assignment, nil, true))
+}
+
+func (h *Handlers) Close(c *gin.Context) {
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+	assignment, err := h.svc.Close(c, uint(ownerID), c.Param("id"))
+	if err != nil {
+		writeErr(c, "assignment_close_failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, toView(*assignment, nil, true))
+}
+
+func (h *Handlers) Reopen(c *gin.Context) {
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+	assignment, err := h.svc.Reopen(c, uint(ownerID), c.Param("id"))
+	if err != nil {
+		writeErr(c, "assignment_reopen_failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, toView(*assignment, nil, true))
+}
+
+func (h *Handlers) Delete(c *gin.Context) {
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+	if err := h.svc.Delete(c, uint(ownerID), c.Param("id")); err != nil {
+		writeErr(c, "assignment_delete_failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessResponse{Message: "assignment deleted"})
+}
+
+func writeErr(c *gin.Context, code string, err error) {
+	status := http.StatusInternalServerError
+	msg := err.Error()
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrForbidden):
+		status = http.StatusForbidden
+		msg = "not allowed"
+	case errors.Is(err, ErrInvalidWindow), errors.Is(err, ErrTitleRequired):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, response.ErrorResponse{Error: code, Message: msg})
+}
+
 func toView(a Assignment, settings *TestSettingsSummary, isOwner bool) dto.AssignmentView {
 	view := dto.AssignmentView{
 		AssignmentID: a.ID,
@@ -120,14 +209,15 @@ func toView(a Assignment, settings *TestSettingsSummary, isOwner bool) dto.Assig
 		Comment:      a.Comment,
 		ShareURL:     "/take-test?assignmentId=" + a.ID,
 		IsOwner:      isOwner,
+
+		AvailableFrom:  a.AvailableFrom,
+		AvailableUntil: a.AvailableUntil,
+		Closed:         a.Closed(),
+		ClosedAt:       a.ClosedAt,
 	}
 	if tpl, _ := DecodeTemplateSnapshot(a.Template); tpl != nil {
 		for _, f := range tpl.Fields {
-			view.Fields = append(view.Fields, dto.AssignmentFieldSpec{
-				Key:      f.Key,
-				Label:    f.Label,
-				Required: f.Required,
-			})
+			view.Fields = append(view.Fields, dto.AssignmentFieldSpec(f))
 		}
 	}
 	if isOwner {
@@ -12,9 +12,22 @@ type Assignment struct {
 	Title     string
 	Comment   string
 	CreatedAt time.Time
+	UpdatedAt time.Time
 	Template  json.RawMessage
+
+	// AvailableFrom and AvailableUntil override the window frozen in the
+	// template; nil keeps the template bound.
+	AvailableFrom  *time.Time
+	AvailableUntil *time.Time
+	// ClosedAt is set when the owner closed the assignment to new attempts.
+	ClosedAt *time.Time
+	// DeletedAt is set once the owner deleted the assignment. Its attempts
+	// are kept, so it behaves like a closed assignment for them.
+	DeletedAt *time.Time
 }
 
+func (a Assignment) Closed() bool { return a.ClosedAt != nil || a.DeletedAt != nil }
+
 type AssignmentDescriptor struct {
 	ID      string
 	TestID  string
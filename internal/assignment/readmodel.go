@@ -16,7 +16,7 @@ func NewReadModel(svc *Service) testAttempt.AssignmentReadModel {
 }
 
 func (a assignmentReadModel) GetAssignment(ctx context.Context, id testAttempt.AssignmentID) (testAttempt.AssignmentDescriptor, error) {
-	asg, err := a.svc.Get(ctx, string(id))
+	asg, err := a.svc.getIncludingDeleted(ctx, string(id))
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrNotFound):
@@ -42,5 +42,9 @@ func (a assignmentReadModel) GetAssignment(ctx context.Context, id testAttempt.A
 		Title:    asg.Title,
 		Comment:  asg.Comment,
 		Template: template,
+
+		AvailableFrom:  asg.AvailableFrom,
+		AvailableUntil: asg.AvailableUntil,
+		Closed:         asg.Closed(),
 	}, nil
 }
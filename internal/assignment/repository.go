@@ -5,5 +5,10 @@ import "context"
 type Repository interface {
 	Create(ctx context.Context, a *Assignment) error
 	GetByID(ctx context.Context, id string) (*Assignment, error)
+	// GetByIDIncludingDeleted also returns soft-deleted assignments.
+	GetByIDIncludingDeleted(ctx context.Context, id string) (*Assignment, error)
 	ListByOwner(ctx context.Context, ownerID uint) ([]Assignment, error)
+	Update(ctx context.Context, a *Assignment) error
+	// Delete soft-deletes the assignment and leaves its attempts in place.
+	Delete(ctx context.Context, id string) error
 }
@@ -2,14 +2,21 @@ package assignment
 
 import "github.com/gin-gonic/gin"
 
-func RegisterRoutes(v1 gin.IRouter, h *Handlers, auth gin.HandlerFunc, optionalAuth gin.HandlerFunc) {
+func RegisterRoutes(v1 gin.IRouter, h *Handlers, auth gin.HandlerFunc, optionalAuth gin.HandlerFunc, requireAuthor gin.HandlerFunc) {
 	secured := v1.Group("/assignments")
 	if auth != nil {
 		secured.Use(auth)
 	}
+	if requireAuthor != nil {
+		secured.Use(requireAuthor)
+	}
 	{
 		secured.POST("", h.Create)
 		secured.GET("", h.ListMine)
+		secured.PUT("/:id", h.Update)
+		secured.DELETE("/:id", h.Delete)
+		secured.POST("/:id/close", h.Close)
+		secured.POST("/:id/reopen", h.Reopen)
 	}
 
 	public := v1.Group("/assignments")
@@ -3,6 +3,8 @@ package assignment
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -14,16 +16,29 @@ import (
 var (
 	ErrForbidden = errors.New("forbidden")
 	ErrNotFound  = errors.New("assignment not found")
+	// ErrPoolTooSmall means a test draw rule asks for more questions than the
+	// owner's bank holds for that tag once earlier rules took theirs.
+	ErrPoolTooSmall = errors.New("question pool too small")
+	ErrInvalidField = errors.New("invalid participant field")
+	// ErrInvalidWindow means AvailableUntil does not come after AvailableFrom.
+	ErrInvalidWindow = errors.New("available_until must be after available_from")
+	ErrTitleRequired = errors.New("title is required")
 )
 
+// QuestionBank resolves test draw rules against the owner's question bank.
+type QuestionBank interface {
+	PoolQuestions(ctx context.Context, ownerID uint, tag, difficulty string) ([]test.Question, error)
+}
+
 type Service struct {
 	repo  Repository
 	tests test.TestRepository
+	bank  QuestionBank
 	clock func() time.Time
 }
 
-func NewService(repo Repository, tests test.TestRepository) *Service {
-	return &Service{repo: repo, tests: tests, clock: func() time.Time { return time.Now().UTC() }}
+func NewService(repo Repository, tests test.TestRepository, bank QuestionBank) *Service {
+	return &Service{repo: repo, tests: tests, bank: bank, clock: func() time.Time { return time.Now().UTC() }}
 }
 
 type TestSettingsSummary struct {
@@ -53,9 +68,15 @@ func (s *Service) CreateWithTemplate(ctx context.Context, ownerID uint, testID s
 	if err != nil {
 		return nil, err
 	}
+	if err := s.addPools(ctx, ownerID, t, snapshot); err != nil {
+		return nil, err
+	}
 	if len(fields) == 0 {
 		fields = defaultFields()
 	}
+	if err := validateFields(fields); err != nil {
+		return nil, err
+	}
 	snapshot.Fields = fields
 	rawSnapshot, err := snapshot.Marshal()
 	if err != nil {
@@ -86,6 +107,12 @@ func (s *Service) Get(ctx context.Context, id string) (*Assignment, error) {
 	return a, nil
 }
 
+// getIncludingDeleted resolves an assignment for its attempts, which outlive
+// the assignment being deleted.
+func (s *Service) getIncludingDeleted(ctx context.Context, id string) (*Assignment, error) {
+	return s.repo.GetByIDIncludingDeleted(ctx, id)
+}
+
 func (s *Service) GetTestSettings(ctx context.Context, testID string) (*TestSettingsSummary, error) {
 	durationSec, _, _, _, policy, err := s.tests.GetTestSettings(ctx, testID)
 	if err != nil {
@@ -108,6 +135,154 @@ func (s *Service) ListByOwner(ctx context.Context, ownerID uint) ([]Assignment,
 	return s.repo.ListByOwner(ctx, ownerID)
 }
 
+// UpdateParams replaces every editable setting of an assignment. A nil bound
+// falls back to the window of the test snapshot.
+type UpdateParams struct {
+	Title          string
+	Comment        string
+	AvailableFrom  *time.Time
+	AvailableUntil *time.Time
+}
+
+func (s *Service) Update(ctx context.Context, ownerID uint, id string, p UpdateParams) (*Assignment, error) {
+	a, err := s.getOwned(ctx, ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.AvailableFrom != nil && p.AvailableUntil != nil && !p.AvailableUntil.After(*p.AvailableFrom) {
+		return nil, ErrInvalidWindow
+	}
+	title := strings.TrimSpace(p.Title)
+	if title == "" {
+		return nil, ErrTitleRequired
+	}
+	a.Title = title
+	a.Comment = strings.TrimSpace(p.Comment)
+	a.AvailableFrom = utcPtr(p.AvailableFrom)
+	a.AvailableUntil = utcPtr(p.AvailableUntil)
+	if err := s.repo.Update(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Close stops new attempts; attempts already running can still be finished.
+func (s *Service) Close(ctx context.Context, ownerID uint, id string) (*Assignment, error) {
+	a, err := s.getOwned(ctx, ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.ClosedAt != nil {
+		return a, nil
+	}
+	now := s.clock()
+	a.ClosedAt = &now
+	if err := s.repo.Update(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *Service) Reopen(ctx context.Context, ownerID uint, id string) (*Assignment, error) {
+	a, err := s.getOwned(ctx, ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.ClosedAt == nil {
+		return a, nil
+	}
+	a.ClosedAt = nil
+	if err := s.repo.Update(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Delete hides the assignment from its owner and stops new attempts. Attempts
+// already made, with their grades, are kept.
+func (s *Service) Delete(ctx context.Context, ownerID uint, id string) error {
+	if _, err := s.getOwned(ctx, ownerID, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *Service) getOwned(ctx context.Context, ownerID uint, id string) (*Assignment, error) {
+	a, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.OwnerID != ownerID {
+		return nil, ErrForbidden
+	}
+	return a, nil
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+func (s *Service) addPools(ctx context.Context, ownerID uint, t *test.Test, snapshot *TemplateSnapshot) error {
+	rules, err := t.DecodeDrawRules()
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 && s.bank == nil {
+		return fmt.Errorf("%w: question bank is not configured", ErrPoolTooSmall)
+	}
+	// A question is drawn by the first rule that matches it only, so pools
+	// never compete for questions and every attempt gets the configured count.
+	taken := make(map[string]struct{}, len(snapshot.Questions))
+	for _, q := range snapshot.Questions {
+		taken[q.ID] = struct{}{}
+	}
+	for _, rule := range rules {
+		pool, err := s.bank.PoolQuestions(ctx, ownerID, rule.Tag, rule.Difficulty)
+		if err != nil {
+			return err
+		}
+		free := make([]test.Question, 0, len(pool))
+		for _, q := range pool {
+			if _, ok := taken[q.ID]; ok {
+				continue
+			}
+			taken[q.ID] = struct{}{}
+			free = append(free, q)
+		}
+		if len(free) < rule.Count {
+			return fmt.Errorf("%w: tag %q has %d questions not used by another rule, %d required", ErrPoolTooSmall, rule.Tag, len(free), rule.Count)
+		}
+		snapshot.AddPool(rule.Tag, rule.Difficulty, rule.Section, rule.Count, free)
+	}
+	return nil
+}
+
+func validateFields(fields []TemplateField) error {
+	seen := make(map[string]struct{}, len(fields))
+	for i, f := range fields {
+		if f.Key == "" {
+			return fmt.Errorf("%w: field %d: key is required", ErrInvalidField, i+1)
+		}
+		if _, dup := seen[f.Key]; dup {
+			return fmt.Errorf("%w: duplicate key %q", ErrInvalidField, f.Key)
+		}
+		seen[f.Key] = struct{}{}
+		if f.MaxLength < 0 {
+			return fmt.Errorf("%w: %s: max_length must be >= 0", ErrInvalidField, f.Key)
+		}
+		if f.Pattern != "" {
+			if _, err := regexp.Compile(f.Pattern); err != nil {
+				return fmt.Errorf("%w: %s: pattern: %v", ErrInvalidField, f.Key, err)
+			}
+		}
+	}
+	return nil
+}
+
 func defaultFields() []TemplateField {
 	return []TemplateField{
 		{Key: "first_name", Label: "First name", Required: true},
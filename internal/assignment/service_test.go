@@ -0,0 +1,185 @@
+package assignment_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"edu-system/internal/assignment"
+	"edu-system/internal/platform"
+	"edu-system/internal/platform/assignmentrepo"
+	"edu-system/internal/platform/testattemptrepo"
+	"edu-system/internal/platform/testrepo"
+	"edu-system/internal/test"
+	"edu-system/internal/testAttempt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const owner, student uint = 1, 2
+
+type env struct {
+	svc      *assignment.Service
+	attempts testAttempt.Repository
+	starts   *testAttempt.Service
+	tests    test.TestRepository
+}
+
+// newEnv wires the assignment service and the attempt service that starts
+// attempts on its assignments to a fresh sqlite database.
+func newEnv(t *testing.T) *env {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "assignment.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&test.Test{}, &test.Question{}, &test.Option{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := assignmentrepo.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := testattemptrepo.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	e := &env{tests: testrepo.NewTestRepository(db), attempts: testattemptrepo.NewTestAttemptRepository(db)}
+	e.svc = assignment.NewService(assignmentrepo.NewRepository(db), e.tests, nil)
+	e.starts = testAttempt.NewTestAttemptService(
+		e.attempts,
+		e.tests,
+		assignment.NewReadModel(e.svc),
+		platform.GormTransactor{DB: db},
+		platform.SystemClock{},
+		platform.AllowGuestsAndOwnerPolicy{Tests: e.tests},
+		platform.GormUserDirectory{DB: db},
+		nil,
+		nil,
+	)
+	return e
+}
+
+// assign creates an assignment of a one-question test available between
+// from and until.
+func (e *env) assign(t *testing.T, from, until *time.Time) *assignment.Assignment {
+	t.Helper()
+	tst := &test.Test{
+		AuthorID: owner, Author: "Owner", Title: "Fractions",
+		AvailableFrom: from, AvailableUntil: until,
+		Questions: []test.Question{{
+			QuestionText: "1/2 + 1/2?",
+			Options:      []test.Option{{OptionText: "1"}, {OptionText: "2"}},
+		}},
+	}
+	if err := e.tests.Create(tst); err != nil {
+		t.Fatal(err)
+	}
+	a, err := e.svc.Create(context.Background(), owner, tst.ID, "")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return a
+}
+
+func (e *env) start(a *assignment.Assignment) error {
+	user := testAttempt.UserID(student)
+	id, err := e.starts.StartAttempt(context.Background(), &user, nil,
+		map[string]string{"first_name": "S", "last_name": "T"},
+		testAttempt.AssignmentID(a.ID), testAttempt.AttemptMetadata{})
+	if err != nil {
+		return err
+	}
+	// Each check starts from a student without a running attempt.
+	running, err := e.attempts.GetByID(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	_, err = e.starts.Cancel(context.Background(), &user, id, running.Version())
+	return err
+}
+
+func TestClosedAssignmentRefusesNewAttempts(t *testing.T) {
+	e := newEnv(t)
+	ctx := context.Background()
+	a := e.assign(t, nil, nil)
+
+	closed, err := e.svc.Close(ctx, owner, a.ID)
+	if err != nil || closed.ClosedAt == nil {
+		t.Fatalf("expected the assignment to close, got %+v, %v", closed, err)
+	}
+	if err := e.start(a); !errors.Is(err, testAttempt.ErrAssignmentClosed) {
+		t.Fatalf("expected a closed assignment to refuse attempts, got %v", err)
+	}
+
+	reopened, err := e.svc.Reopen(ctx, owner, a.ID)
+	if err != nil || reopened.ClosedAt != nil {
+		t.Fatalf("expected the assignment to reopen, got %+v, %v", reopened, err)
+	}
+	if err := e.start(a); err != nil {
+		t.Fatalf("expected a reopened assignment to take attempts, got %v", err)
+	}
+
+	if err := e.svc.Delete(ctx, owner, a.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := e.start(a); !errors.Is(err, testAttempt.ErrAssignmentClosed) {
+		t.Fatalf("expected a deleted assignment to refuse attempts, got %v", err)
+	}
+}
+
+func TestAssignmentWindowOverridesTestWindow(t *testing.T) {
+	e := newEnv(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	lastWeek, yesterday, tomorrow := now.AddDate(0, 0, -7), now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+	a := e.assign(t, &lastWeek, &yesterday)
+
+	if err := e.start(a); !errors.Is(err, testAttempt.ErrNotAvailable) {
+		t.Fatalf("expected the test window to apply, got %v", err)
+	}
+	if _, err := e.svc.Update(ctx, owner, a.ID, assignment.UpdateParams{Title: "Fractions", AvailableUntil: &tomorrow}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := e.start(a); err != nil {
+		t.Fatalf("expected the assignment to extend the window, got %v", err)
+	}
+	if _, err := e.svc.Update(ctx, owner, a.ID, assignment.UpdateParams{Title: "Fractions", AvailableFrom: &tomorrow}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := e.start(a); !errors.Is(err, testAttempt.ErrNotAvailable) {
+		t.Fatalf("expected the assignment to open later than the test, got %v", err)
+	}
+
+	if _, err := e.svc.Update(ctx, owner, a.ID, assignment.UpdateParams{Title: "Fractions", AvailableFrom: &tomorrow, AvailableUntil: &yesterday}); !errors.Is(err, assignment.ErrInvalidWindow) {
+		t.Fatalf("expected a window that ends before it starts to be refused, got %v", err)
+	}
+}
+
+func TestOnlyOwnerManagesAssignment(t *testing.T) {
+	e := newEnv(t)
+	ctx := context.Background()
+	a := e.assign(t, nil, nil)
+
+	if _, err := e.svc.Update(ctx, student, a.ID, assignment.UpdateParams{Title: "Mine"}); !errors.Is(err, assignment.ErrForbidden) {
+		t.Fatalf("update: expected ErrForbidden, got %v", err)
+	}
+	if _, err := e.svc.Close(ctx, student, a.ID); !errors.Is(err, assignment.ErrForbidden) {
+		t.Fatalf("close: expected ErrForbidden, got %v", err)
+	}
+	if _, err := e.svc.Reopen(ctx, student, a.ID); !errors.Is(err, assignment.ErrForbidden) {
+		t.Fatalf("reopen: expected ErrForbidden, got %v", err)
+	}
+	if err := e.svc.Delete(ctx, student, a.ID); !errors.Is(err, assignment.ErrForbidden) {
+		t.Fatalf("delete: expected ErrForbidden, got %v", err)
+	}
+
+	got, err := e.svc.Get(ctx, a.ID)
+	if err != nil || got.Title != a.Title || got.Closed() {
+		t.Fatalf("expected the assignment to be untouched, got %+v, %v", got, err)
+	}
+	if _, err := e.svc.Create(ctx, student, a.TestID, ""); !errors.Is(err, assignment.ErrForbidden) {
+		t.Fatalf("expected assigning someone else's test to be refused, got %v", err)
+	}
+}
@@ -19,6 +19,8 @@ type TemplateSnapshot struct {
 	AvailableUntil *time.Time                 `json:"available_until,omitempty"`
 	AttemptPolicy  testAttempt.AttemptPolicy  `json:"attempt_policy"`
 	Questions      []TemplateQuestionSnapshot `json:"questions"`
+	Pools          []TemplatePoolSnapshot     `json:"pools,omitempty"`
+	Sections       []TemplateSectionSnapshot  `json:"sections,omitempty"`
 	Fields         []TemplateField            `json:"fields,omitempty"`
 }
 
@@ -26,17 +28,42 @@ type TemplateField struct {
 	Key      string `json:"key"`
 	Label    string `json:"label"`
 	Required bool   `json:"required"`
+	// Pattern must match the whole value; Options restricts it to a fixed set.
+	Pattern   string   `json:"pattern,omitempty"`
+	MaxLength int      `json:"max_length,omitempty"`
+	Options   []string `json:"options,omitempty"`
 }
 
 type TemplateQuestionSnapshot struct {
-	ID             string                   `json:"id"`
-	Type           string                   `json:"type,omitempty"`
-	QuestionText   string                   `json:"question_text"`
-	ImageURL       string                   `json:"image_url,omitempty"`
-	CorrectOption  int                      `json:"correct_option"`
-	CorrectOptions []int                    `json:"correct_options,omitempty"`
-	Weight         float64                  `json:"weight,omitempty"`
-	Options        []TemplateOptionSnapshot `json:"options"`
+	ID             string                     `json:"id"`
+	Type           string                     `json:"type,omitempty"`
+	QuestionText   string                     `json:"question_text"`
+	ImageURL       string                     `json:"image_url,omitempty"`
+	CorrectOption  int                        `json:"correct_option"`
+	CorrectOptions []int                      `json:"correct_options,omitempty"`
+	Weight         float64                    `json:"weight,omitempty"`
+	Scoring        string                     `json:"scoring,omitempty"`
+	TextAnswer     *testAttempt.TextAnswerKey `json:"text_answer,omitempty"`
+	CodeAnswer     *testAttempt.CodeAnswerKey `json:"code_answer,omitempty"`
+	Solution       string                     `json:"solution,omitempty"`
+	Section        string                     `json:"section,omitempty"`
+	Options        []TemplateOptionSnapshot   `json:"options"`
+}
+
+type TemplateSectionSnapshot struct {
+	Name         string `json:"name"`
+	DrawCount    int    `json:"draw_count,omitempty"`
+	Shuffle      bool   `json:"shuffle,omitempty"`
+	TimeLimitSec int    `json:"time_limit_sec,omitempty"`
+}
+
+// TemplatePoolSnapshot freezes the bank questions matching a test draw rule
+// at assignment creation; each attempt draws Count of them.
+type TemplatePoolSnapshot struct {
+	Tag         string   `json:"tag"`
+	Difficulty  string   `json:"difficulty,omitempty"`
+	Count       int      `json:"count"`
+	QuestionIDs []string `json:"question_ids"`
 }
 
 type TemplateOptionSnapshot struct {
@@ -53,6 +80,10 @@ func BuildTemplateSnapshot(src *test.Test) (*TemplateSnapshot, error) {
 	if err != nil {
 		return nil, err
 	}
+	sections, err := src.DecodeSections()
+	if err != nil {
+		return nil, err
+	}
 	snapshot := &TemplateSnapshot{
 		TestID:         src.ID,
 		Title:          src.Title,
@@ -65,30 +96,67 @@ func BuildTemplateSnapshot(src *test.Test) (*TemplateSnapshot, error) {
 		Questions:      make([]TemplateQuestionSnapshot, 0, len(src.Questions)),
 	}
 	for _, q := range src.Questions {
-		qType := normalizeQuestionType(q.Type, len(q.Options))
-		weight := normalizeWeight(q.Weight)
-		tq := TemplateQuestionSnapshot{
-			ID:             q.ID,
-			Type:           qType,
-			QuestionText:   q.QuestionText,
-			ImageURL:       q.ImageURL,
-			CorrectOption:  q.CorrectOption,
-			CorrectOptions: decodeCorrectOptions(q.CorrectJSON),
-			Weight:         weight,
-			Options:        make([]TemplateOptionSnapshot, 0, len(q.Options)),
-		}
-		for _, o := range q.Options {
-			tq.Options = append(tq.Options, TemplateOptionSnapshot{
-				ID:         o.ID,
-				OptionText: o.OptionText,
-				ImageURL:   o.ImageURL,
-			})
-		}
-		snapshot.Questions = append(snapshot.Questions, tq)
+		snapshot.Questions = append(snapshot.Questions, snapshotQuestion(q))
+	}
+	for _, sec := range sections {
+		snapshot.Sections = append(snapshot.Sections, TemplateSectionSnapshot(sec))
 	}
 	return snapshot, nil
 }
 
+// AddPool appends a draw pool and snapshots its questions into the given
+// section, skipping those already part of the template.
+func (tpl *TemplateSnapshot) AddPool(tag, difficulty, section string, count int, questions []test.Question) {
+	known := make(map[string]struct{}, len(tpl.Questions))
+	for _, q := range tpl.Questions {
+		known[q.ID] = struct{}{}
+	}
+	pool := TemplatePoolSnapshot{
+		Tag:         tag,
+		Difficulty:  difficulty,
+		Count:       count,
+		QuestionIDs: make([]string, 0, len(questions)),
+	}
+	for _, q := range questions {
+		pool.QuestionIDs = append(pool.QuestionIDs, q.ID)
+		if _, ok := known[q.ID]; ok {
+			continue
+		}
+		known[q.ID] = struct{}{}
+		tq := snapshotQuestion(q)
+		tq.Section = section
+		tpl.Questions = append(tpl.Questions, tq)
+	}
+	tpl.Pools = append(tpl.Pools, pool)
+}
+
+func snapshotQuestion(q test.Question) TemplateQuestionSnapshot {
+	qType := normalizeQuestionType(q.Type, len(q.Options))
+	tq := TemplateQuestionSnapshot{
+		ID:             q.ID,
+		Type:           qType,
+		QuestionText:   q.QuestionText,
+		ImageURL:       q.ImageURL,
+		CorrectOption:  q.CorrectOption,
+		CorrectOptions: decodeCorrectOptions(q.CorrectJSON),
+		Weight:         normalizeWeight(q.Weight),
+		Scoring:        decodeScoring(qType, q.CorrectJSON),
+		TextAnswer:     decodeTextAnswer(qType, q.CorrectJSON),
+		CodeAnswer:     decodeCodeAnswer(qType, q.CorrectJSON),
+		Solution:       q.Solution,
+		Section:        q.Section,
+		Options:        make([]TemplateOptionSnapshot, 0, len(q.Options)),
+	}
+	for _, o := range q.Options {
+		tq.Options = append(tq.Options, TemplateOptionSnapshot{
+			ID:         o.ID,
+			OptionText: o.OptionText,
+			ImageURL:   o.ImageURL,
+		})
+	}
+	return tq
+}
+
 func (tpl *TemplateSnapshot) Marshal() (json.RawMessage, error) {
 	if tpl == nil {
 		return nil, nil
@@ -125,15 +193,35 @@ func (tpl *TemplateSnapshot) ToAssignmentTemplate() *testAttempt.AssignmentTempl
 		AvailableUntil: tpl.AvailableUntil,
 		Policy:         tpl.AttemptPolicy,
 		Questions:      make([]testAttempt.TemplateQuestion, 0, len(tpl.Questions)),
+		Pools:          make([]testAttempt.QuestionPool, 0, len(tpl.Pools)),
+		Sections:       make([]testAttempt.TemplateSection, 0, len(tpl.Sections)),
 		Fields:         make([]testAttempt.AssignmentFieldSpec, 0, len(tpl.Fields)),
 	}
 	for _, f := range tpl.Fields {
 		out.Fields = append(out.Fields, testAttempt.AssignmentFieldSpec{
-			Key:      f.Key,
-			Label:    f.Label,
-			Required: f.Required,
+			Key:       f.Key,
+			Label:     f.Label,
+			Required:  f.Required,
+			Pattern:   f.Pattern,
+			MaxLength: f.MaxLength,
+			Options:   f.Options,
 		})
 	}
+	for _, sec := range tpl.Sections {
+		out.Sections = append(out.Sections, testAttempt.TemplateSection{
+			Name:      sec.Name,
+			DrawCount: sec.DrawCount,
+			Shuffle:   sec.Shuffle,
+			TimeLimit: time.Duration(sec.TimeLimitSec) * time.Second,
+		})
+	}
+	for _, p := range tpl.Pools {
+		pool := testAttempt.QuestionPool{Count: p.Count, QuestionIDs: make([]testAttempt.QuestionID, 0, len(p.QuestionIDs))}
+		for _, id := range p.QuestionIDs {
+			pool.QuestionIDs = append(pool.QuestionIDs, testAttempt.QuestionID(id))
+		}
+		out.Pools = append(out.Pools, pool)
+	}
 	for _, q := range tpl.Questions {
 		tq := testAttempt.TemplateQuestion{
 			ID:             testAttempt.QuestionID(q.ID),
@@ -143,6 +231,11 @@ func (tpl *TemplateSnapshot) ToAssignmentTemplate() *testAttempt.AssignmentTempl
 			CorrectOption:  q.CorrectOption,
 			CorrectOptions: q.CorrectOptions,
 			Weight:         normalizeWeight(q.Weight),
+			Scoring:        testAttempt.MultiScoringRule(q.Scoring),
+			TextAnswer:     q.TextAnswer,
+			CodeAnswer:     q.CodeAnswer,
+			Solution:       q.Solution,
+			Section:        q.Section,
 			Options:        make([]testAttempt.TemplateOption, 0, len(q.Options)),
 		}
 		for _, o := range q.Options {
@@ -213,3 +306,38 @@ func decodeCorrectOptions(raw []byte) []int {
 	}
 	return out
 }
+
+func decodeTextAnswer(qType string, raw []byte) *testAttempt.TextAnswerKey {
+	if qType != "text" || len(raw) == 0 {
+		return nil
+	}
+	var key testAttempt.TextAnswerKey
+	if err := json.Unmarshal(raw, &key); err != nil || len(key.AcceptedAnswers) == 0 {
+		return nil
+	}
+	return &key
+}
+
+func decodeCodeAnswer(qType string, raw []byte) *testAttempt.CodeAnswerKey {
+	if qType != "code" || len(raw) == 0 {
+		return nil
+	}
+	var key testAttempt.CodeAnswerKey
+	if err := json.Unmarshal(raw, &key); err != nil || len(key.TestCases) == 0 {
+		return nil
+	}
+	return &key
+}
+
+func decodeScoring(qType string, raw []byte) string {
+	if qType != "multi" || len(raw) == 0 {
+		return ""
+	}
+	var key struct {
+		Scoring string `json:"scoring"`
+	}
+	if err := json.Unmarshal(raw, &key); err != nil {
+		return ""
+	}
+	return key.Scoring
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound         = errors.New("user not found")
+	ErrOrganisationNotFound = errors.New("organisation not found")
+	ErrSchoolNotFound       = errors.New("school not found")
+	ErrSchoolMismatch       = errors.New("school belongs to another organisation")
+	ErrLastAdmin            = errors.New("cannot remove the last admin")
+	// ErrDuplicateName is returned by OrganisationRepository when the name
+	// of an organisation, or of a school within its organisation, is taken.
+	ErrDuplicateName = errors.New("name already in use")
+)
+
+// AdminService is what the /admin endpoints use to manage accounts and the
+// organisations and schools they belong to.
+type AdminService interface {
+	ListUsers(filter UserFilter) ([]UserResponse, error)
+	GetUser(id uint) (*UserResponse, error)
+	UpdateUser(id uint, req *UpdateUserRequest) (*UserResponse, error)
+	CreateOrganisation(req *CreateOrganisationRequest) (*Organisation, error)
+	ListOrganisations() ([]Organisation, error)
+	CreateSchool(organisationID uint, req *CreateSchoolRequest) (*School, error)
+	ListSchools(organisationID uint) ([]School, error)
+}
+
+type adminService struct {
+	users     UserRepository
+	orgs      OrganisationRepository
+	tokens    TokenRepository
+	accessTTL time.Duration
+}
+
+// NewAdminService needs the access token lifetime to keep the sessions it
+// ends on the revocation list for as long as their access tokens last.
+func NewAdminService(users UserRepository, orgs OrganisationRepository, tokens TokenRepository, accessTTL time.Duration) AdminService {
+	return &adminService{users: users, orgs: orgs, tokens: tokens, accessTTL: accessTTL}
+}
+
+func (s *adminService) ListUsers(filter UserFilter) ([]UserResponse, error) {
+	users, err := s.users.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]UserResponse, 0, len(users))
+	for i := range users {
+		out = append(out, toUserResponse(&users[i]))
+	}
+	return out, nil
+}
+
+func (s *adminService) GetUser(id uint) (*UserResponse, error) {
+	user, err := s.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := toUserResponse(user)
+	return &resp, nil
+}
+
+// UpdateUser changes the role and memberships of an account. Setting a school
+// also sets its organisation; the last admin cannot be demoted, so the
+// deployment always keeps someone who can manage it. A role change ends the
+// user's sessions, as their access tokens still carry the old role.
+func (s *adminService) UpdateUser(id uint, req *UpdateUserRequest) (*UserResponse, error) {
+	user, err := s.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	roleChanged := req.Role != nil && *req.Role != user.Role
+	if roleChanged {
+		if user.Role == RoleAdmin {
+			admins, err := s.users.CountByRole(RoleAdmin)
+			if err != nil {
+				return nil, err
+			}
+			if admins <= 1 {
+				return nil, ErrLastAdmin
+			}
+		}
+		user.Role = *req.Role
+	}
+
+	if req.OrganisationID != nil {
+		user.OrganisationID = nil
+		if *req.OrganisationID != 0 {
+			if _, err := s.orgs.GetOrganisation(*req.OrganisationID); err != nil {
+				return nil, notFound(err, ErrOrganisationNotFound)
+			}
+			user.OrganisationID = req.OrganisationID
+		}
+		if req.SchoolID == nil {
+			user.SchoolID = nil
+		}
+	}
+	if req.SchoolID != nil {
+		user.SchoolID = nil
+		if *req.SchoolID != 0 {
+			school, err := s.orgs.GetSchool(*req.SchoolID)
+			if err != nil {
+				return nil, notFound(err, ErrSchoolNotFound)
+			}
+			if user.OrganisationID != nil && req.OrganisationID != nil && *user.OrganisationID != school.OrganisationID {
+				return nil, ErrSchoolMismatch
+			}
+			orgID := school.OrganisationID
+			user.OrganisationID = &orgID
+			user.SchoolID = &school.ID
+		}
+	}
+
+	if err := s.users.Update(user); err != nil {
+		return nil, err
+	}
+	if roleChanged {
+		if err := revokeSessions(s.tokens, user.ID, nil, s.accessTTL); err != nil {
+			return nil, err
+		}
+	}
+	resp := toUserResponse(user)
+	return &resp, nil
+}
+
+func (s *adminService) CreateOrganisation(req *CreateOrganisationRequest) (*Organisation, error) {
+	org := &Organisation{Name: strings.TrimSpace(req.Name)}
+	if err := s.orgs.CreateOrganisation(org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (s *adminService) ListOrganisations() ([]Organisation, error) {
+	return s.orgs.ListOrganisations()
+}
+
+func (s *adminService) CreateSchool(organisationID uint, req *CreateSchoolRequest) (*School, error) {
+	if _, err := s.orgs.GetOrganisation(organisationID); err != nil {
+		return nil, notFound(err, ErrOrganisationNotFound)
+	}
+	school := &School{OrganisationID: organisationID, Name: strings.TrimSpace(req.Name)}
+	if err := s.orgs.CreateSchool(school); err != nil {
+		return nil, err
+	}
+	return school, nil
+}
+
+func (s *adminService) ListSchools(organisationID uint) ([]School, error) {
+	if _, err := s.orgs.GetOrganisation(organisationID); err != nil {
+		return nil, notFound(err, ErrOrganisationNotFound)
+	}
+	return s.orgs.ListSchools(organisationID)
+}
+
+func (s *adminService) getUser(id uint) (*User, error) {
+	user, err := s.users.GetByID(id)
+	if err != nil {
+		return nil, notFound(err, ErrUserNotFound)
+	}
+	return user, nil
+}
+
+func notFound(err, sentinel error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return sentinel
+	}
+	return err
+}
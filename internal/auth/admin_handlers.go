@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"edu-system/internal/delivery"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	adminService AdminService
+}
+
+func NewAdminHandler(adminService AdminService) *AdminHandler {
+	return &AdminHandler{
+		adminService: adminService,
+	}
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description List accounts, optionally filtered by role, organisation or school
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param role query string false "admin, teacher or student"
+// @Param organisation_id query int false "Organisation ID"
+// @Param school_id query int false "School ID"
+// @Success 200 {array} dto.UserResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Router /admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	filter := UserFilter{Role: c.Query("role")}
+	if filter.Role != "" && !ValidRole(filter.Role) {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: "unknown role",
+		})
+		return
+	}
+	for param, dst := range map[string]*uint{"organisation_id": &filter.OrganisationID, "school_id": &filter.SchoolID} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.ErrorResponse{
+				Error:   "validation error",
+				Message: "invalid " + param,
+			})
+			return
+		}
+		*dst = uint(id)
+	}
+
+	users, err := h.adminService.ListUsers(filter)
+	if err != nil {
+		writeAdminErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// GetUser godoc
+// @Summary Get a user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} dto.UserResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /admin/users/{id} [get]
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	user, err := h.adminService.GetUser(id)
+	if err != nil {
+		writeAdminErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUser godoc
+// @Summary Change a user's role or memberships
+// @Description Role changes apply to tokens issued after the change
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param user body dto.UpdateUserRequest true "Fields to change"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Router /admin/users/{id} [patch]
+func (h *AdminHandler) UpdateUser(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+	user, err := h.adminService.UpdateUser(id, &req)
+	if err != nil {
+		writeAdminErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// ListOrganisations godoc
+// @Summary List organisations
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} auth.Organisation
+// @Router /admin/organisations [get]
+func (h *AdminHandler) ListOrganisations(c *gin.Context) {
+	orgs, err := h.adminService.ListOrganisations()
+	if err != nil {
+		writeAdminErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// CreateOrganisation godoc
+// @Summary Create an organisation
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param organisation body dto.CreateOrganisationRequest true "Organisation"
+// @Success 201 {object} auth.Organisation
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Router /admin/organisations [post]
+func (h *AdminHandler) CreateOrganisation(c *gin.Context) {
+	var req CreateOrganisationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+	org, err := h.adminService.CreateOrganisation(&req)
+	if err != nil {
+		writeAdminErr(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, org)
+}
+
+// ListSchools godoc
+// @Summary List the schools of an organisation
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organisation ID"
+// @Success 200 {array} auth.School
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /admin/organisations/{id}/schools [get]
+func (h *AdminHandler) ListSchools(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	schools, err := h.adminService.ListSchools(id)
+	if err != nil {
+		writeAdminErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schools)
+}
+
+// CreateSchool godoc
+// @Summary Add a school to an organisation
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organisation ID"
+// @Param school body dto.CreateSchoolRequest true "School"
+// @Success 201 {object} auth.School
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Router /admin/organisations/{id}/schools [post]
+func (h *AdminHandler) CreateSchool(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	var req CreateSchoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+	school, err := h.adminService.CreateSchool(id, &req)
+	if err != nil {
+		writeAdminErr(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, school)
+}
+
+func pathID(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: "invalid " + name,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func writeAdminErr(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrOrganisationNotFound), errors.Is(err, ErrSchoolNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrSchoolMismatch):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrLastAdmin), errors.Is(err, ErrDuplicateName):
+		status = http.StatusConflict
+	}
+	c.JSON(status, response.ErrorResponse{
+		Error:   "admin request failed",
+		Message: err.Error(),
+	})
+}
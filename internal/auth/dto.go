@@ -1,5 +1,7 @@
 package auth
 
+import "time"
+
 type RegisterRequest struct {
 	Email     string `json:"email" binding:"required,email"`
 	Password  string `json:"password" binding:"required,min=6"`
@@ -12,9 +14,20 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// LoginResponse carries a short-lived access token in Token and the refresh
+// token that replaces it once it expires.
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token        string       `json:"token"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+	RefreshToken string       `json:"refresh_token"`
+	User         UserResponse `json:"user"`
+	// ReauthToken is set after single sign-on and stands in for the password
+	// of ChangePasswordRequest and DeleteAccountRequest for a few minutes.
+	ReauthToken string `json:"reauth_token,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 type UserResponse struct {
@@ -23,4 +36,76 @@ type UserResponse struct {
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	Role      string `json:"role"`
+
+	OrganisationID *uint `json:"organisation_id,omitempty"`
+	SchoolID       *uint `json:"school_id,omitempty"`
+	EmailVerified  bool  `json:"email_verified"`
+	// PendingEmail awaits confirmation; the account keeps Email until then.
+	PendingEmail *string `json:"pending_email,omitempty"`
+}
+
+// UpdateUserRequest is what an admin may change on an account. A zero
+// organisation_id or school_id removes the membership.
+type UpdateUserRequest struct {
+	Role           *string `json:"role" binding:"omitempty,oneof=admin teacher student"`
+	OrganisationID *uint   `json:"organisation_id"`
+	SchoolID       *uint   `json:"school_id"`
+}
+
+type CreateOrganisationRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type CreateSchoolRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// SSOStartResponse tells the client where to send the browser. The IdP
+// redirects back with code and state, which the client posts to the
+// callback endpoint. Binding travels in a cookie, not in the body.
+type SSOStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	Binding          string `json:"-"`
+}
+
+type SSOCallbackRequest struct {
+	Code    string `json:"code" binding:"required"`
+	State   string `json:"state" binding:"required"`
+	Binding string `json:"-"`
+}
+
+// UpdateProfileRequest changes the caller's own account; fields left out
+// stay as they are. A new email takes effect once it is confirmed.
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name" binding:"omitempty,min=1,max=255"`
+	LastName  *string `json:"last_name" binding:"omitempty,min=1,max=255"`
+	Email     *string `json:"email" binding:"omitempty,email"`
+}
+
+// ChangePasswordRequest proves the caller is the owner either with the
+// current password or, for accounts that sign in through the IdP, with the
+// reauth token of a fresh sign-on.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required_without=ReauthToken"`
+	ReauthToken     string `json:"reauth_token"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type DeleteAccountRequest struct {
+	Password    string `json:"password" binding:"required_without=ReauthToken"`
+	ReauthToken string `json:"reauth_token"`
 }
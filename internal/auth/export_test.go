@@ -0,0 +1,6 @@
+package auth
+
+// SendMailInline makes svc send mail before its methods return.
+func SendMailInline(svc AuthService) {
+	svc.(*authService).background = func(fn func()) { fn() }
+}
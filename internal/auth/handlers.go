@@ -2,7 +2,11 @@ package auth
 
 import (
 	"edu-system/internal/delivery"
+	"errors"
 	"net/http"
+	"path"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -83,6 +87,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		if err.Error() == "invalid credentials" {
 			statusCode = http.StatusUnauthorized
 		}
+		if errors.Is(err, ErrEmailNotVerified) {
+			statusCode = http.StatusForbidden
+		}
 
 		c.JSON(statusCode, response.ErrorResponse{
 			Error:   "login failed",
@@ -94,25 +101,517 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// Refresh godoc
+// @Summary Refresh the access token
+// @Description Trade a refresh token for a new access token and refresh token. Each refresh token works once; reusing one ends its session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body dto.RefreshRequest true "Refresh token"
+// @Success 200 {object} dto.LoginResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.authService.Refresh(&req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			statusCode = http.StatusUnauthorized
+		}
+
+		c.JSON(statusCode, response.ErrorResponse{
+			Error:   "refresh failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description End the session of the refresh token; its access tokens stop working too
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body dto.RefreshRequest true "Refresh token"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.Logout(&req); err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			statusCode = http.StatusUnauthorized
+		}
+
+		c.JSON(statusCode, response.ErrorResponse{
+			Error:   "logout failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse{
+		Message: "logged out",
+	})
+}
+
+// LogoutAll godoc
+// @Summary Log out of all sessions
+// @Description End every session of the current user on every device
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	if err := h.authService.LogoutAll(uint(userID)); err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error:   "logout failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse{
+		Message: "logged out of all sessions",
+	})
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Mail a single-use reset link if the address belongs to an account. The response is the same either way.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body dto.EmailRequest true "Account email"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Router /auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ForgotPassword(&req); err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error:   "password reset failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse{
+		Message: "if the account exists, a reset link has been sent",
+	})
+}
+
+// ResetPassword godoc
+// @Summary Reset the password
+// @Description Set a new password with the token from the reset email; every session of the account is logged out
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body dto.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Router /auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ResetPassword(&req); err != nil {
+		writeTokenErr(c, "password reset failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse{
+		Message: "password changed",
+	})
+}
+
+// VerifyEmail godoc
+// @Summary Verify the email address
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body dto.TokenRequest true "Token from the verification email"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(&req); err != nil {
+		writeTokenErr(c, "email verification failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse{
+		Message: "email verified",
+	})
+}
+
+// ResendVerification godoc
+// @Summary Send the verification email again
+// @Description The response is the same whether or not the account exists
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body dto.EmailRequest true "Account email"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Router /auth/verify-email/resend [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ResendVerification(&req); err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error:   "email verification failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse{
+		Message: "if the account needs verification, a new link has been sent",
+	})
+}
+
+// StartSSO godoc
+// @Summary Start single sign-on
+// @Description Return the identity provider URL to send the browser to and set the HttpOnly cookie the callback checks
+// @Tags auth
+// @Produce json
+// @Success 200 {object} dto.SSOStartResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 502 {object} dto.ErrorResponse
+// @Router /auth/oidc/login [get]
+func (h *AuthHandler) StartSSO(c *gin.Context) {
+	resp, err := h.authService.StartSSO(c.Request.Context())
+	if err != nil {
+		writeSSOErr(c, err)
+		return
+	}
+
+	setSSOCookie(c, resp.Binding, int(ssoStateTTL/time.Second))
+	c.JSON(http.StatusOK, resp)
+}
+
+// CompleteSSO godoc
+// @Summary Finish single sign-on
+// @Description Redeem the code and state the identity provider redirected back with; the browser must send the cookie set by /auth/oidc/login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param callback body dto.SSOCallbackRequest true "Code and state from the redirect"
+// @Success 200 {object} dto.LoginResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 502 {object} dto.ErrorResponse
+// @Router /auth/oidc/callback [post]
+func (h *AuthHandler) CompleteSSO(c *gin.Context) {
+	var req SSOCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	req.Binding, _ = c.Cookie(ssoCookie)
+	setSSOCookie(c, "", -1)
+
+	resp, err := h.authService.CompleteSSO(c.Request.Context(), &req)
+	if err != nil {
+		writeSSOErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ssoCookie binds a sign-on to the browser that started it.
+const ssoCookie = "sso_binding"
+
+// setSSOCookie scopes the cookie to the oidc routes, which share a parent
+// path behind any proxy prefix.
+func setSSOCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoCookie, value, maxAge, path.Dir(c.Request.URL.Path), "", secure, true)
+}
+
 // Profile godoc
 // @Summary Get user profile
-// @Description Get current user profile information
+// @Description Get the stored account of the current user
 // @Tags auth
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} dto.UserResponse
 // @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
 // @Router /auth/profile [get]
 func (h *AuthHandler) Profile(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	email, _ := c.Get("email")
-	role, _ := c.Get("role")
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	user, err := h.authService.GetProfile(uint(userID))
+	if err != nil {
+		writeProfileErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile godoc
+// @Summary Update user profile
+// @Description Change the names or email of the current user. A new email replaces the current one once the link mailed to it is confirmed through /auth/verify-email.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param profile body dto.UpdateProfileRequest true "Fields to change"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Router /auth/profile [patch]
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
 
-	user := UserResponse{
-		ID:    uint(userID.(float64)),
-		Email: email.(string),
-		Role:  role.(string),
+	user, err := h.authService.UpdateProfile(uint(userID), &req)
+	if err != nil {
+		writeProfileErr(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Replace the password of the current user, confirmed with the current password or the reauth_token of a fresh single sign-on. Every session ends; the response carries a new token pair.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param password body dto.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} dto.LoginResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Router /auth/password [post]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.authService.ChangePassword(uint(userID), &req)
+	if err != nil {
+		writeProfileErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteAccount godoc
+// @Summary Delete account
+// @Description Delete the current user's account, confirmed with the password or the reauth_token of a fresh single sign-on. Their attempts are kept without personal data.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param password body dto.DeleteAccountRequest true "Current password or reauth token"
+// @Success 200 {object} dto.SuccessResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Router /auth/account [delete]
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	userID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Error: "unauthorized",
+		})
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error:   "validation error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.DeleteAccount(c.Request.Context(), uint(userID), &req); err != nil {
+		writeProfileErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse{
+		Message: "account deleted",
+	})
+}
+
+func writeTokenErr(c *gin.Context, message string, err error) {
+	statusCode := http.StatusInternalServerError
+	if errors.Is(err, ErrInvalidActionToken) {
+		statusCode = http.StatusBadRequest
+	}
+	c.JSON(statusCode, response.ErrorResponse{
+		Error:   message,
+		Message: err.Error(),
+	})
+}
+
+func writeProfileErr(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, ErrWrongPassword), errors.Is(err, ErrReauthRequired):
+		statusCode = http.StatusForbidden
+	case errors.Is(err, ErrEmailTaken), errors.Is(err, ErrLastAdmin):
+		statusCode = http.StatusConflict
+	}
+	c.JSON(statusCode, response.ErrorResponse{
+		Error:   "account update failed",
+		Message: err.Error(),
+	})
+}
+
+func writeSSOErr(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrSSODisabled):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, ErrInvalidSSOState):
+		statusCode = http.StatusBadRequest
+	case errors.Is(err, ErrSSOEmailRequired), errors.Is(err, ErrSSONoAccount):
+		statusCode = http.StatusForbidden
+	case errors.Is(err, ErrSSOFailed):
+		statusCode = http.StatusBadGateway
+	}
+	c.JSON(statusCode, response.ErrorResponse{
+		Error:   "single sign-on failed",
+		Message: err.Error(),
+	})
+}
+
+func userIDFromCtx(c *gin.Context) (uint64, bool) {
+	val, ok := c.Get("user_id")
+	if !ok {
+		return 0, false
+	}
+	switch v := val.(type) {
+	case uint64:
+		return v, true
+	case uint:
+		return uint64(v), true
+	case int:
+		return uint64(v), true
+	case float64:
+		return uint64(v), true
+	case string:
+		if parsed, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return parsed, true
+		}
+	}
+	return 0, false
+}
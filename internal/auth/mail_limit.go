@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Account emails sent on request are limited per address, so the public
+// recovery endpoints cannot be used to flood someone's mailbox.
+const (
+	mailsPerAddress  = 3
+	mailLimitWindow  = 15 * time.Minute
+	mailLimitMaxKeys = 10000
+)
+
+// mailLimiter counts recent emails per address and purpose in memory.
+type mailLimiter struct {
+	mu   sync.Mutex
+	sent map[string][]time.Time
+}
+
+func newMailLimiter() *mailLimiter {
+	return &mailLimiter{sent: make(map[string][]time.Time)}
+}
+
+// allow records an email to key and reports whether it is within the limit.
+func (l *mailLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.sent) >= mailLimitMaxKeys {
+		l.prune(now)
+	}
+	recent := l.sent[key][:0]
+	for _, at := range l.sent[key] {
+		if now.Sub(at) < mailLimitWindow {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= mailsPerAddress {
+		l.sent[key] = recent
+		return false
+	}
+	l.sent[key] = append(recent, now)
+	return true
+}
+
+func (l *mailLimiter) prune(now time.Time) {
+	for key, times := range l.sent {
+		if len(times) == 0 || now.Sub(times[len(times)-1]) >= mailLimitWindow {
+			delete(l.sent, key)
+		}
+	}
+}
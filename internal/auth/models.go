@@ -16,5 +16,43 @@ type User struct {
 	Password  string `json:"-" gorm:"not null"`
 	FirstName string `json:"first_name" gorm:"not null"`
 	LastName  string `json:"last_name" gorm:"not null"`
-	Role      string `json:"role" gorm:"default:user"`
+	Role      string `json:"role" gorm:"default:student"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// PendingEmail is a new address that replaces Email once it is confirmed.
+	PendingEmail *string `json:"pending_email,omitempty"`
+	// OIDCSubject links the account to its identity at the SSO provider.
+	OIDCSubject *string `json:"-" gorm:"column:oidc_subject;uniqueIndex"`
+
+	OrganisationID *uint `json:"organisation_id,omitempty" gorm:"index"`
+	SchoolID       *uint `json:"school_id,omitempty" gorm:"index"`
+}
+
+const (
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+	RoleStudent = "student"
+)
+
+// ValidRole reports whether role is one of the roles an account can hold.
+func ValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleTeacher, RoleStudent:
+		return true
+	}
+	return false
+}
+
+// Organisation groups schools, e.g. a district or a university.
+type Organisation struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
+}
+
+type School struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time `json:"created_at"`
+	OrganisationID uint      `json:"organisation_id" gorm:"not null;uniqueIndex:idx_school_org_name"`
+	Name           string    `json:"name" gorm:"not null;uniqueIndex:idx_school_org_name"`
 }
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWrongPassword  = errors.New("current password is incorrect")
+	ErrEmailTaken     = errors.New("user with this email already exists")
+	ErrReauthRequired = errors.New("sign in again to confirm this change")
+)
+
+func (s *authService) GetProfile(userID uint) (*UserResponse, error) {
+	user, err := s.profileUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	resp := toUserResponse(user)
+	return &resp, nil
+}
+
+// UpdateProfile changes the names and email of the account. A new email is
+// kept as pending and only replaces the current one, which stays in use for
+// signing in, once the link mailed to it is opened.
+func (s *authService) UpdateProfile(userID uint, req *UpdateProfileRequest) (*UserResponse, error) {
+	user, err := s.profileUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.FirstName != nil {
+		user.FirstName = strings.TrimSpace(*req.FirstName)
+	}
+	if req.LastName != nil {
+		user.LastName = strings.TrimSpace(*req.LastName)
+	}
+
+	var newEmail string
+	if req.Email != nil {
+		if email := normalizeEmail(*req.Email); email == user.Email {
+			user.PendingEmail = nil
+		} else {
+			if _, err := s.userRepo.GetByEmail(email); err == nil {
+				return nil, ErrEmailTaken
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			user.PendingEmail = &email
+			newEmail = email
+		}
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	if newEmail != "" {
+		if err := s.sendEmailChange(user, newEmail); err != nil {
+			log.Printf("auth: sending email change confirmation to user %d: %v", user.ID, err)
+		}
+	}
+	resp := toUserResponse(user)
+	return &resp, nil
+}
+
+// ChangePassword replaces the password after checking the current one or a
+// fresh sign-on. Every session of the account ends, so the caller gets a
+// fresh token pair.
+func (s *authService) ChangePassword(userID uint, req *ChangePasswordRequest) (*LoginResponse, error) {
+	user, err := s.profileUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reauthenticate(user, req.CurrentPassword, req.ReauthToken); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = string(hashedPassword)
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	if err := s.revokeSessions(user.ID, nil); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, uuid.New().String())
+}
+
+// DeleteAccount removes the account after checking the password or a fresh
+// sign-on. The row is scrubbed and soft-deleted rather than removed, so
+// attempts keep pointing at a "Deleted user" instead of an ID that no longer
+// resolves.
+func (s *authService) DeleteAccount(ctx context.Context, userID uint, req *DeleteAccountRequest) error {
+	user, err := s.profileUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.reauthenticate(user, req.Password, req.ReauthToken); err != nil {
+		return err
+	}
+	if user.Role == RoleAdmin {
+		admins, err := s.userRepo.CountByRole(RoleAdmin)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	user.Email = fmt.Sprintf("deleted-%d@deleted.invalid", user.ID)
+	user.Password = ""
+	user.FirstName = "Deleted"
+	user.LastName = "user"
+	user.EmailVerifiedAt = nil
+	user.PendingEmail = nil
+	user.OIDCSubject = nil
+	user.OrganisationID = nil
+	user.SchoolID = nil
+	now := time.Now()
+	var revoked []string
+	deleteAccount := func(ctx context.Context) error {
+		revoked, err = s.userRepo.DeleteAccount(ctx, user, now)
+		if err != nil || s.settings.AnonymiseAttempts == nil {
+			return err
+		}
+		return s.settings.AnonymiseAttempts(ctx, user.ID)
+	}
+	if s.settings.Tx != nil {
+		err = s.settings.Tx.WithinTx(ctx, deleteAccount)
+	} else {
+		err = deleteAccount(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if len(revoked) > 0 {
+		if err := s.tokens.Revoke(revoked, now.Add(s.settings.TTL.Access)); err != nil {
+			return err
+		}
+	}
+	log.Printf("auth: deleted account of user %d", user.ID)
+	return nil
+}
+
+// reauthenticate checks that the caller still knows the password of the
+// account, or has just signed in through the IdP when reauthToken is set.
+func (s *authService) reauthenticate(user *User, password, reauthToken string) error {
+	if reauthToken == "" {
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+			return ErrWrongPassword
+		}
+		return nil
+	}
+	stored, err := s.useActionToken(reauthToken, PurposeReauth)
+	if errors.Is(err, ErrInvalidActionToken) || (err == nil && stored.UserID != user.ID) {
+		return ErrReauthRequired
+	}
+	return err
+}
+
+func (s *authService) profileUser(userID uint) (*User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, notFound(err, ErrUserNotFound)
+	}
+	return user, nil
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+	PurposeChangeEmail   = "change_email"
+	PurposeReauth        = "reauth"
+)
+
+var (
+	ErrEmailNotVerified   = errors.New("email is not verified")
+	ErrInvalidActionToken = errors.New("invalid or expired token")
+)
+
+// Mailer delivers account emails.
+type Mailer interface {
+	Send(msg MailMessage) error
+}
+
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// ActionToken is a single-use token mailed to a user to verify an address or
+// reset a password. Email is the address being verified.
+type ActionToken struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"type:varchar(32);not null"`
+	Email     string    `gorm:"not null"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+// ForgotPassword mails a reset link when the address belongs to an account.
+// It succeeds either way, and the lookup and mail happen after it returns,
+// so that neither the answer nor its timing reveals whether the account
+// exists.
+func (s *authService) ForgotPassword(req *EmailRequest) error {
+	email := normalizeEmail(req.Email)
+	if !s.mailLimit.allow(PurposeResetPassword+":"+email, time.Now()) {
+		return nil
+	}
+	s.background(func() {
+		if err := s.sendPasswordReset(email); err != nil {
+			log.Printf("auth: sending password reset email: %v", err)
+		}
+	})
+	return nil
+}
+
+func (s *authService) sendPasswordReset(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	token, err := s.createActionToken(user, user.Email, PurposeResetPassword, s.settings.TTL.PasswordReset)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(MailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nUse the link below to choose a new password. It works once and expires in %s.\n\n%s\n\nIf you did not ask for this, ignore this email.\n",
+			user.FirstName, formatTTL(s.settings.TTL.PasswordReset), s.link("/reset-password", token)),
+	})
+}
+
+// ResetPassword sets a new password and ends every session of the account,
+// since whoever knew the old password may still be logged in. Other reset
+// links of the account stop working as well.
+func (s *authService) ResetPassword(req *ResetPasswordRequest) error {
+	token, err := s.useActionToken(req.Token, PurposeResetPassword)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(token.UserID)
+	if err != nil {
+		return ErrInvalidActionToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.Password = string(hashedPassword)
+	// The reset link reached this address, which proves it as well.
+	if user.EmailVerifiedAt == nil && strings.EqualFold(user.Email, token.Email) {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+	if err := s.tokens.UseActionTokens(user.ID, PurposeResetPassword, time.Now()); err != nil {
+		return err
+	}
+	return s.revokeSessions(user.ID, nil)
+}
+
+// VerifyEmail confirms the account's address, or a new address it asked to
+// change to; both links lead to the same page.
+func (s *authService) VerifyEmail(req *TokenRequest) error {
+	token, err := s.useActionToken(req.Token, PurposeVerifyEmail)
+	if errors.Is(err, ErrInvalidActionToken) {
+		token, err = s.useActionToken(req.Token, PurposeChangeEmail)
+	}
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(token.UserID)
+	if err != nil {
+		return ErrInvalidActionToken
+	}
+	if token.Purpose == PurposeChangeEmail {
+		return s.confirmEmailChange(user, token)
+	}
+	// A link mailed to an address the account no longer uses proves nothing.
+	if !strings.EqualFold(user.Email, token.Email) {
+		return ErrInvalidActionToken
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	s.applyAdminEmails(user)
+	return s.userRepo.Update(user)
+}
+
+// confirmEmailChange switches the account to the address the token was
+// mailed to, provided it is still the one the user asked for last.
+func (s *authService) confirmEmailChange(user *User, token *ActionToken) error {
+	if user.PendingEmail == nil || *user.PendingEmail != token.Email {
+		return ErrInvalidActionToken
+	}
+	if other, err := s.userRepo.GetByEmail(token.Email); err == nil && other.ID != user.ID {
+		return ErrEmailTaken
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	now := time.Now()
+	user.Email = token.Email
+	user.EmailVerifiedAt = &now
+	user.PendingEmail = nil
+	s.applyAdminEmails(user)
+	return s.userRepo.Update(user)
+}
+
+func (s *authService) sendEmailChange(user *User, email string) error {
+	token, err := s.createActionToken(user, email, PurposeChangeEmail, s.settings.TTL.EmailVerification)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(MailMessage{
+		To:      email,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf("Hello %s,\n\nOpen the link below to use this address for your account. Until then you keep signing in with %s. The link expires in %s.\n\n%s\n",
+			user.FirstName, user.Email, formatTTL(s.settings.TTL.EmailVerification), s.link("/verify-email", token)),
+	})
+}
+
+// ResendVerification mails a new verification link to an unverified account
+// and, like ForgotPassword, does not reveal whether the account exists.
+func (s *authService) ResendVerification(req *EmailRequest) error {
+	email := normalizeEmail(req.Email)
+	if !s.mailLimit.allow(PurposeVerifyEmail+":"+email, time.Now()) {
+		return nil
+	}
+	s.background(func() {
+		user, err := s.userRepo.GetByEmail(email)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("auth: resending verification email: %v", err)
+			}
+			return
+		}
+		if user.EmailVerifiedAt != nil {
+			return
+		}
+		if err := s.sendVerification(user); err != nil {
+			log.Printf("auth: sending verification email to user %d: %v", user.ID, err)
+		}
+	})
+	return nil
+}
+
+func (s *authService) sendVerification(user *User) error {
+	token, err := s.createActionToken(user, user.Email, PurposeVerifyEmail, s.settings.TTL.EmailVerification)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(MailMessage{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hello %s,\n\nConfirm that this address belongs to you by opening the link below. It expires in %s.\n\n%s\n",
+			user.FirstName, formatTTL(s.settings.TTL.EmailVerification), s.link("/verify-email", token)),
+	})
+}
+
+func (s *authService) createActionToken(user *User, email, purpose string, ttl time.Duration) (string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.tokens.CreateActionToken(&ActionToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     email,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	return token, err
+}
+
+func (s *authService) useActionToken(token, purpose string) (*ActionToken, error) {
+	stored, err := s.tokens.UseActionToken(hashToken(token), purpose, time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidActionToken
+		}
+		return nil, err
+	}
+	return stored, nil
+}
+
+func (s *authService) link(path, token string) string {
+	return s.settings.AppBaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func formatTTL(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d hours", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%d minutes", int(d/time.Minute))
+}
@@ -1,9 +1,36 @@
 package auth
 
+import (
+	"context"
+	"time"
+)
+
 type UserRepository interface {
 	Create(user *User) error
 	GetByEmail(email string) (*User, error)
 	GetByID(id uint) (*User, error)
+	GetBySubject(subject string) (*User, error)
+	List(filter UserFilter) ([]User, error)
+	CountByRole(role string) (int64, error)
 	Update(user *User) error
-	Delete(id uint) error
+	// DeleteAccount saves the scrubbed user, soft-deletes it and ends its
+	// sessions in one transaction, joining the one ctx carries. It returns
+	// the session IDs it ended.
+	DeleteAccount(ctx context.Context, user *User, at time.Time) ([]string, error)
+}
+
+// UserFilter narrows List; zero fields match every user.
+type UserFilter struct {
+	Role           string
+	OrganisationID uint
+	SchoolID       uint
+}
+
+type OrganisationRepository interface {
+	CreateOrganisation(org *Organisation) error
+	GetOrganisation(id uint) (*Organisation, error)
+	ListOrganisations() ([]Organisation, error)
+	CreateSchool(school *School) error
+	GetSchool(id uint) (*School, error)
+	ListSchools(organisationID uint) ([]School, error)
 }
@@ -10,12 +10,40 @@ func RegisterRoutes(v1 gin.IRouter, h *AuthHandler, jwtAuth gin.HandlerFunc) {
 		// Public auth routes
 		auth.POST("/register", h.Register)
 		auth.POST("/login", h.Login)
+		auth.POST("/refresh", h.Refresh)
+		auth.POST("/logout", h.Logout)
+		auth.POST("/password/forgot", h.ForgotPassword)
+		auth.POST("/password/reset", h.ResetPassword)
+		auth.POST("/verify-email", h.VerifyEmail)
+		auth.POST("/verify-email/resend", h.ResendVerification)
+		auth.GET("/oidc/login", h.StartSSO)
+		auth.POST("/oidc/callback", h.CompleteSSO)
 
 		// Protected auth routes
 		protected := auth.Group("")
 		protected.Use(jwtAuth)
 		{
 			protected.GET("/profile", h.Profile)
+			protected.PATCH("/profile", h.UpdateProfile)
+			protected.POST("/password", h.ChangePassword)
+			protected.DELETE("/account", h.DeleteAccount)
+			protected.POST("/logout-all", h.LogoutAll)
 		}
 	}
 }
+
+// RegisterAdminRoutes registers the account management routes; requireAdmin
+// must reject every role but admin.
+func RegisterAdminRoutes(v1 gin.IRouter, h *AdminHandler, jwtAuth, requireAdmin gin.HandlerFunc) {
+	admin := v1.Group("/admin")
+	admin.Use(jwtAuth, requireAdmin)
+	{
+		admin.GET("/users", h.ListUsers)
+		admin.GET("/users/:id", h.GetUser)
+		admin.PATCH("/users/:id", h.UpdateUser)
+		admin.GET("/organisations", h.ListOrganisations)
+		admin.POST("/organisations", h.CreateOrganisation)
+		admin.GET("/organisations/:id/schools", h.ListSchools)
+		admin.POST("/organisations/:id/schools", h.CreateSchool)
+	}
+}
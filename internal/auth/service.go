@@ -1,10 +1,12 @@
 package auth
 
 import (
+	"context"
 	"errors"
-	"time"
+	"log"
+	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -12,22 +14,85 @@ import (
 type AuthService interface {
 	Register(req *RegisterRequest) error
 	Login(req *LoginRequest) (*LoginResponse, error)
+	Refresh(req *RefreshRequest) (*LoginResponse, error)
+	Logout(req *RefreshRequest) error
+	LogoutAll(userID uint) error
+	ForgotPassword(req *EmailRequest) error
+	ResetPassword(req *ResetPasswordRequest) error
+	VerifyEmail(req *TokenRequest) error
+	ResendVerification(req *EmailRequest) error
+	StartSSO(ctx context.Context) (*SSOStartResponse, error)
+	CompleteSSO(ctx context.Context, req *SSOCallbackRequest) (*LoginResponse, error)
+	GetProfile(userID uint) (*UserResponse, error)
+	UpdateProfile(userID uint, req *UpdateProfileRequest) (*UserResponse, error)
+	ChangePassword(userID uint, req *ChangePasswordRequest) (*LoginResponse, error)
+	DeleteAccount(ctx context.Context, userID uint, req *DeleteAccountRequest) error
+}
+
+// Settings configures the auth service.
+type Settings struct {
+	JWTSecret string
+	TTL       TokenTTL
+	// AdminEmails are given the admin role when they register or log in,
+	// which is how the first administrator of a deployment is created.
+	AdminEmails []string
+	// AppBaseURL is the frontend address the links in emails point to.
+	AppBaseURL string
+	// RequireEmailVerification makes Login fail until the email is verified.
+	RequireEmailVerification bool
+	// SSO enables OpenID Connect sign-on when its Provider is set.
+	SSO SSOSettings
+	// AnonymiseAttempts strips personal data from the test attempts of a
+	// deleted account. It runs through Tx in the transaction that deletes
+	// the account.
+	AnonymiseAttempts func(ctx context.Context, userID uint) error
+	Tx                Transactor
+}
+
+// Transactor runs fn in one transaction that the repositories called with
+// its ctx take part in.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type authService struct {
-	userRepo  UserRepository
-	jwtSecret string
+	userRepo    UserRepository
+	tokens      TokenRepository
+	mailer      Mailer
+	settings    Settings
+	adminEmails map[string]bool
+	mailLimit   *mailLimiter
+	// background runs work that must not delay or reveal anything in the
+	// response, such as recovery mail; tests run it inline.
+	background func(func())
 }
 
-func NewAuthService(userRepo UserRepository, jwtSecret string) AuthService {
+func NewAuthService(userRepo UserRepository, tokens TokenRepository, mailer Mailer, settings Settings) AuthService {
+	admins := make(map[string]bool, len(settings.AdminEmails))
+	for _, email := range settings.AdminEmails {
+		admins[normalizeEmail(email)] = true
+	}
+	settings.AppBaseURL = strings.TrimRight(settings.AppBaseURL, "/")
+	for group, role := range settings.SSO.GroupRoles {
+		if !ValidRole(role) {
+			log.Printf("auth: ignoring unknown role %q for group %q", role, group)
+			delete(settings.SSO.GroupRoles, group)
+		}
+	}
 	return &authService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:    userRepo,
+		tokens:      tokens,
+		mailer:      mailer,
+		settings:    settings,
+		adminEmails: admins,
+		mailLimit:   newMailLimiter(),
+		background:  func(fn func()) { go fn() },
 	}
 }
 
 func (s *authService) Register(req *RegisterRequest) error {
-	_, err := s.userRepo.GetByEmail(req.Email)
+	email := normalizeEmail(req.Email)
+	_, err := s.userRepo.GetByEmail(email)
 	if err == nil {
 		return errors.New("user with this email already exists")
 	}
@@ -41,18 +106,25 @@ func (s *authService) Register(req *RegisterRequest) error {
 	}
 
 	user := &User{
-		Email:     req.Email,
+		Email:     email,
 		Password:  string(hashedPassword),
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
-		Role:      "user",
+		Role:      RoleStudent,
 	}
 
-	return s.userRepo.Create(user)
+	if err := s.userRepo.Create(user); err != nil {
+		return err
+	}
+	// The account exists either way; a lost email can be sent again.
+	if err := s.sendVerification(user); err != nil {
+		log.Printf("auth: sending verification email to user %d: %v", user.ID, err)
+	}
+	return nil
 }
 
 func (s *authService) Login(req *LoginRequest) (*LoginResponse, error) {
-	user, err := s.userRepo.GetByEmail(req.Email)
+	user, err := s.userRepo.GetByEmail(normalizeEmail(req.Email))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("invalid credentials")
@@ -64,32 +136,44 @@ func (s *authService) Login(req *LoginRequest) (*LoginResponse, error) {
 		return nil, errors.New("invalid credentials")
 	}
 
-	token, err := s.generateJWT(user)
-	if err != nil {
-		return nil, err
+	if s.settings.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+
+	if s.applyAdminEmails(user) {
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, err
+		}
 	}
 
-	return &LoginResponse{
-		Token: token,
-		User: UserResponse{
-			ID:        user.ID,
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Role:      user.Role,
-		},
-	}, nil
+	return s.issueTokens(user, uuid.New().String())
 }
 
-func (s *authService) generateJWT(user *User) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-		"iat":     time.Now().Unix(),
+// applyAdminEmails gives the admin role to a listed email once the account
+// has proven it owns the address, and reports whether the role changed.
+func (s *authService) applyAdminEmails(user *User) bool {
+	if user.Role == RoleAdmin || user.EmailVerifiedAt == nil || !s.adminEmails[normalizeEmail(user.Email)] {
+		return false
 	}
+	user.Role = RoleAdmin
+	return true
+}
+
+// normalizeEmail is applied to every email before it is stored or looked up.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtSecret))
+func toUserResponse(user *User) UserResponse {
+	return UserResponse{
+		ID:             user.ID,
+		Email:          user.Email,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		Role:           user.Role,
+		OrganisationID: user.OrganisationID,
+		SchoolID:       user.SchoolID,
+		EmailVerified:  user.EmailVerifiedAt != nil,
+		PendingEmail:   user.PendingEmail,
+	}
 }
@@ -0,0 +1,306 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"edu-system/internal/auth"
+	"edu-system/internal/platform"
+	"edu-system/internal/platform/authrepo"
+	"edu-system/internal/platform/testattemptrepo"
+	"edu-system/internal/testAttempt"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// outbox keeps the mail a service sent.
+type outbox struct {
+	mu   sync.Mutex
+	sent []auth.MailMessage
+}
+
+func (o *outbox) Send(msg auth.MailMessage) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+func (o *outbox) count() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.sent)
+}
+
+// lastToken returns the token of the newest link to path.
+func (o *outbox) lastToken(t *testing.T, path string) string {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.sent) - 1; i >= 0; i-- {
+		_, rest, ok := strings.Cut(o.sent[i].Body, "http://app"+path+"?token=")
+		if !ok {
+			continue
+		}
+		token, _, _ := strings.Cut(rest, "\n")
+		unescaped, err := url.QueryUnescape(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return unescaped
+	}
+	t.Fatalf("no %s link mailed", path)
+	return ""
+}
+
+type env struct {
+	db     *gorm.DB
+	svc    auth.AuthService
+	admin  auth.AdminService
+	tokens auth.TokenRepository
+	mail   *outbox
+}
+
+// newEnv wires the auth service to a fresh sqlite database. Mail is sent
+// before service calls return.
+func newEnv(t *testing.T, settings auth.Settings) *env {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "auth.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := authrepo.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := testattemptrepo.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	settings.JWTSecret = "secret"
+	settings.AppBaseURL = "http://app"
+	if settings.TTL == (auth.TokenTTL{}) {
+		settings.TTL = auth.TokenTTL{Access: time.Minute, Refresh: time.Hour, EmailVerification: time.Hour, PasswordReset: time.Hour}
+	}
+	e := &env{db: db, tokens: authrepo.NewTokenRepository(db), mail: &outbox{}}
+	attempts := testattemptrepo.NewTestAttemptRepository(db)
+	settings.AnonymiseAttempts = func(ctx context.Context, userID uint) error {
+		return attempts.AnonymiseUser(ctx, testAttempt.UserID(userID))
+	}
+	settings.Tx = platform.GormTransactor{DB: db}
+	users := authrepo.NewUserRepository(db)
+	e.svc = auth.NewAuthService(users, e.tokens, e.mail, settings)
+	e.admin = auth.NewAdminService(users, authrepo.NewOrganisationRepository(db), e.tokens, settings.TTL.Access)
+	auth.SendMailInline(e.svc)
+	return e
+}
+
+func (e *env) register(t *testing.T, email, password string) {
+	t.Helper()
+	if err := e.svc.Register(&auth.RegisterRequest{Email: email, Password: password, FirstName: "S", LastName: "T"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (e *env) login(t *testing.T, email, password string) *auth.LoginResponse {
+	t.Helper()
+	resp, err := e.svc.Login(&auth.LoginRequest{Email: email, Password: password})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func sessionOf(t *testing.T, token string) string {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		t.Fatal(err)
+	}
+	sid, _ := claims["sid"].(string)
+	return sid
+}
+
+func TestRefreshTokenReuseEndsSession(t *testing.T) {
+	e := newEnv(t, auth.Settings{})
+	e.register(t, "s@example.com", "secret1")
+	login := e.login(t, "s@example.com", "secret1")
+	other := e.login(t, "s@example.com", "secret1")
+
+	rotated, err := e.svc.Refresh(&auth.RefreshRequest{RefreshToken: login.RefreshToken})
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if _, err := e.svc.Refresh(&auth.RefreshRequest{RefreshToken: login.RefreshToken}); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Fatalf("expected a replayed token to be rejected, got %v", err)
+	}
+	if _, err := e.svc.Refresh(&auth.RefreshRequest{RefreshToken: rotated.RefreshToken}); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Fatalf("expected the replay to end the session, got %v", err)
+	}
+
+	if revoked, err := e.tokens.IsRevoked(context.Background(), sessionOf(t, rotated.Token)); err != nil || !revoked {
+		t.Fatalf("expected the session's access tokens to be revoked, got %v, %v", revoked, err)
+	}
+	if revoked, err := e.tokens.IsRevoked(context.Background(), sessionOf(t, other.Token)); err != nil || revoked {
+		t.Fatalf("expected the other session to stay valid, got %v, %v", revoked, err)
+	}
+}
+
+func TestRoleChangeEndsSessions(t *testing.T) {
+	e := newEnv(t, auth.Settings{})
+	e.register(t, "s@example.com", "secret1")
+	login := e.login(t, "s@example.com", "secret1")
+
+	same := login.User.Role
+	if _, err := e.admin.UpdateUser(login.User.ID, &auth.UpdateUserRequest{Role: &same}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if revoked, err := e.tokens.IsRevoked(context.Background(), sessionOf(t, login.Token)); err != nil || revoked {
+		t.Fatalf("expected the session to survive an update that keeps the role, got %v, %v", revoked, err)
+	}
+
+	role := auth.RoleTeacher
+	if _, err := e.admin.UpdateUser(login.User.ID, &auth.UpdateUserRequest{Role: &role}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if revoked, err := e.tokens.IsRevoked(context.Background(), sessionOf(t, login.Token)); err != nil || !revoked {
+		t.Fatalf("expected the access token with the old role to be revoked, got %v, %v", revoked, err)
+	}
+	if _, err := e.svc.Refresh(&auth.RefreshRequest{RefreshToken: login.RefreshToken}); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Fatalf("expected the session to end, got %v", err)
+	}
+}
+
+func TestPasswordResetAndVerificationTokens(t *testing.T) {
+	e := newEnv(t, auth.Settings{RequireEmailVerification: true})
+	e.register(t, "s@example.com", "secret1")
+	if _, err := e.svc.Login(&auth.LoginRequest{Email: "s@example.com", Password: "secret1"}); !errors.Is(err, auth.ErrEmailNotVerified) {
+		t.Fatalf("expected login to wait for verification, got %v", err)
+	}
+	if err := e.svc.VerifyEmail(&auth.TokenRequest{Token: e.mail.lastToken(t, "/verify-email")}); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	if err := e.svc.ForgotPassword(&auth.EmailRequest{Email: "s@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	reset := e.mail.lastToken(t, "/reset-password")
+	if err := e.svc.ResetPassword(&auth.ResetPasswordRequest{Token: reset, Password: "changed1"}); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if err := e.svc.ResetPassword(&auth.ResetPasswordRequest{Token: reset, Password: "again12"}); !errors.Is(err, auth.ErrInvalidActionToken) {
+		t.Fatalf("expected the reset token to work once, got %v", err)
+	}
+	e.login(t, "s@example.com", "changed1")
+
+	// Using one reset link retires the others.
+	if err := e.svc.ForgotPassword(&auth.EmailRequest{Email: "s@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	first := e.mail.lastToken(t, "/reset-password")
+	if err := e.svc.ForgotPassword(&auth.EmailRequest{Email: "s@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	second := e.mail.lastToken(t, "/reset-password")
+	if err := e.svc.ResetPassword(&auth.ResetPasswordRequest{Token: second, Password: "changed2"}); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if err := e.svc.ResetPassword(&auth.ResetPasswordRequest{Token: first, Password: "changed3"}); !errors.Is(err, auth.ErrInvalidActionToken) {
+		t.Fatalf("expected the older reset link to be retired, got %v", err)
+	}
+
+	// A fourth request within the window mails nothing.
+	sent := e.mail.count()
+	if err := e.svc.ForgotPassword(&auth.EmailRequest{Email: "S@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if e.mail.count() != sent {
+		t.Fatal("expected the per-address limit to hold back the email")
+	}
+}
+
+func TestProfileChangesAndAccountDeletion(t *testing.T) {
+	e := newEnv(t, auth.Settings{})
+	e.register(t, "s@example.com", "secret1")
+	login := e.login(t, "s@example.com", "secret1")
+	id := login.User.ID
+
+	email, name := "new@example.com", "Sam"
+	profile, err := e.svc.UpdateProfile(id, &auth.UpdateProfileRequest{FirstName: &name, Email: &email})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if profile.FirstName != "Sam" || profile.LastName != "T" || profile.Email != "s@example.com" ||
+		profile.PendingEmail == nil || *profile.PendingEmail != "new@example.com" {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	if _, err := e.svc.Login(&auth.LoginRequest{Email: "new@example.com", Password: "secret1"}); err == nil {
+		t.Fatal("expected the new address to wait for confirmation")
+	}
+	if err := e.svc.VerifyEmail(&auth.TokenRequest{Token: e.mail.lastToken(t, "/verify-email")}); err != nil {
+		t.Fatalf("verify the new address: %v", err)
+	}
+	if profile, err = e.svc.GetProfile(id); err != nil || profile.Email != "new@example.com" || !profile.EmailVerified || profile.PendingEmail != nil {
+		t.Fatalf("expected the new address to be in use, got %+v, %v", profile, err)
+	}
+
+	if _, err := e.svc.ChangePassword(id, &auth.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "changed1"}); !errors.Is(err, auth.ErrWrongPassword) {
+		t.Fatalf("expected the current password to be checked, got %v", err)
+	}
+	if _, err := e.svc.ChangePassword(id, &auth.ChangePasswordRequest{CurrentPassword: "secret1", NewPassword: "changed1"}); err != nil {
+		t.Fatalf("change password: %v", err)
+	}
+	if _, err := e.svc.Refresh(&auth.RefreshRequest{RefreshToken: login.RefreshToken}); !errors.Is(err, auth.ErrInvalidRefreshToken) {
+		t.Fatalf("expected the old session to end, got %v", err)
+	}
+
+	if err := e.db.Table("test_attempts").Create(map[string]any{
+		"id": "a1", "assignment_id": "as1", "test_id": "t1", "user_id": id, "started_at": time.Now(),
+		"order_json": "[]", "client_ip": "10.0.0.1", "score": 3,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := e.svc.DeleteAccount(context.Background(), id, &auth.DeleteAccountRequest{Password: "changed1"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	var attempt struct {
+		ClientIP string
+		Score    float64
+	}
+	if err := e.db.Table("test_attempts").Where("id = ?", "a1").Take(&attempt).Error; err != nil || attempt.ClientIP != "" || attempt.Score != 3 {
+		t.Fatalf("expected the attempt to keep its score without client details, got %+v, %v", attempt, err)
+	}
+	var deleted auth.User
+	if err := e.db.Unscoped().First(&deleted, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if deleted.FirstName != "Deleted" || deleted.Email == "new@example.com" || !deleted.DeletedAt.Valid {
+		t.Fatalf("expected an anonymised, deleted row, got %+v", deleted)
+	}
+	e.register(t, "new@example.com", "secret1")
+}
+
+func TestAdminEmailsNeedVerifiedAddress(t *testing.T) {
+	e := newEnv(t, auth.Settings{AdminEmails: []string{"Boss@Example.com"}})
+	e.register(t, " BOSS@example.com", "secret1")
+	if err := e.svc.Register(&auth.RegisterRequest{Email: "boss@EXAMPLE.com", Password: "secret1", FirstName: "B", LastName: "O"}); err == nil {
+		t.Fatal("expected the same address in another case to be taken")
+	}
+	login := e.login(t, "Boss@Example.com", "secret1")
+	if login.User.Email != "boss@example.com" || login.User.Role != auth.RoleStudent {
+		t.Fatalf("expected an unverified student, got %+v", login.User)
+	}
+
+	if err := e.svc.VerifyEmail(&auth.TokenRequest{Token: e.mail.lastToken(t, "/verify-email")}); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if login = e.login(t, "boss@example.com", "secret1"); login.User.Role != auth.RoleAdmin {
+		t.Fatalf("expected the verified address to be promoted, got %+v", login.User)
+	}
+}
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	ssoStateTTL = 10 * time.Minute
+	// ssoReauthTTL is how long a sign-on counts as fresh for changes that
+	// otherwise ask for the password.
+	ssoReauthTTL = 5 * time.Minute
+)
+
+var (
+	ErrSSODisabled      = errors.New("single sign-on is not configured")
+	ErrInvalidSSOState  = errors.New("invalid or expired sign-on state")
+	ErrSSOFailed        = errors.New("identity provider sign-on failed")
+	ErrSSOEmailRequired = errors.New("identity provider did not return a verified email")
+	ErrSSONoAccount     = errors.New("no account for this identity")
+)
+
+// IdentityProvider is an OpenID Connect provider using the authorization
+// code flow with PKCE.
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange redeems the code and returns the identity from the verified
+	// ID token, whose nonce must match.
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalIdentity, error)
+}
+
+type ExternalIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	Groups        []string
+}
+
+type SSOSettings struct {
+	Provider IdentityProvider
+	// GroupRoles maps IdP groups to the role of a provisioned account; a user
+	// in several mapped groups gets the strongest role. Existing accounts keep
+	// the role an administrator gave them.
+	GroupRoles map[string]string
+	// AutoProvision creates accounts for identities that match no user.
+	AutoProvision bool
+	// TrustUnverifiedEmail links and provisions accounts by email even
+	// when the IdP does not mark the address verified.
+	TrustUnverifiedEmail bool
+}
+
+// SSOState is a pending sign-on, kept server-side so the PKCE verifier never
+// reaches the browser. BindingHash ties it to the browser that started it,
+// which holds the binding in a cookie, so a victim cannot be made to finish
+// someone else's sign-on.
+type SSOState struct {
+	StateHash    string `gorm:"primaryKey;type:varchar(64)"`
+	BindingHash  string `gorm:"type:varchar(64);not null;default:''"`
+	Nonce        string `gorm:"not null"`
+	CodeVerifier string `gorm:"not null"`
+	ExpiresAt    time.Time
+}
+
+func (s *authService) StartSSO(ctx context.Context) (*SSOStartResponse, error) {
+	idp := s.settings.SSO.Provider
+	if idp == nil {
+		return nil, ErrSSODisabled
+	}
+
+	var values [4]string
+	for i := range values {
+		v, err := newOpaqueToken()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	state, nonce, verifier, binding := values[0], values[1], values[2], values[3]
+	challenge := sha256.Sum256([]byte(verifier))
+
+	url, err := idp.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSSOFailed, err)
+	}
+	if err := s.tokens.CreateSSOState(&SSOState{
+		StateHash:    hashToken(state),
+		BindingHash:  hashToken(binding),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(ssoStateTTL),
+	}); err != nil {
+		return nil, err
+	}
+	return &SSOStartResponse{AuthorizationURL: url, State: state, Binding: binding}, nil
+}
+
+// CompleteSSO redeems the code the IdP redirected back with and logs the
+// user in, linking or creating the account as configured. The response also
+// carries a reauth token, since such accounts have no password to confirm
+// account changes with.
+func (s *authService) CompleteSSO(ctx context.Context, req *SSOCallbackRequest) (*LoginResponse, error) {
+	idp := s.settings.SSO.Provider
+	if idp == nil {
+		return nil, ErrSSODisabled
+	}
+	pending, err := s.tokens.TakeSSOState(hashToken(req.State), time.Now())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSSOState
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(req.Binding)), []byte(pending.BindingHash)) != 1 {
+		return nil, ErrInvalidSSOState
+	}
+
+	identity, err := idp.Exchange(ctx, req.Code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSSOFailed, err)
+	}
+	user, err := s.ssoUser(identity)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.issueTokens(user, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+	resp.ReauthToken, err = s.createActionToken(user, user.Email, PurposeReauth, ssoReauthTTL)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ssoUser finds the account of an identity by subject, then by email, links
+// it to the subject and takes over a verified email from the IdP.
+func (s *authService) ssoUser(identity *ExternalIdentity) (*User, error) {
+	emailTrusted := identity.Email != "" && (identity.EmailVerified || s.settings.SSO.TrustUnverifiedEmail)
+
+	user, err := s.userRepo.GetBySubject(identity.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if user == nil {
+		if !emailTrusted {
+			return nil, ErrSSOEmailRequired
+		}
+		user, err = s.userRepo.GetByEmail(normalizeEmail(identity.Email))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	if user == nil {
+		if !s.settings.SSO.AutoProvision {
+			return nil, ErrSSONoAccount
+		}
+		user, err = s.provisionSSOUser(identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	subject := identity.Subject
+	user.OIDCSubject = &subject
+	if user.EmailVerifiedAt == nil && emailTrusted && strings.EqualFold(user.Email, identity.Email) {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	s.applyAdminEmails(user)
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// provisionSSOUser creates an account that can only sign in through the IdP
+// until its owner sets a password with a reset link.
+func (s *authService) provisionSSOUser(identity *ExternalIdentity) (*User, error) {
+	password, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	firstName, lastName := identity.FirstName, identity.LastName
+	if firstName == "" {
+		firstName, _, _ = strings.Cut(identity.Email, "@")
+	}
+	user := &User{
+		Email:     normalizeEmail(identity.Email),
+		Password:  string(hashedPassword),
+		FirstName: firstName,
+		LastName:  lastName,
+		Role:      RoleStudent,
+	}
+	if role := s.groupRole(identity.Groups); role != "" {
+		user.Role = role
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	log.Printf("auth: provisioned user %d from single sign-on", user.ID)
+	return user, nil
+}
+
+var roleRank = map[string]int{RoleStudent: 1, RoleTeacher: 2, RoleAdmin: 3}
+
+func (s *authService) groupRole(groups []string) string {
+	best := ""
+	for _, group := range groups {
+		role := s.settings.SSO.GroupRoles[group]
+		if roleRank[role] > roleRank[best] {
+			best = role
+		}
+	}
+	return best
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// TokenTTL sets how long each kind of token stays valid.
+type TokenTTL struct {
+	Access            time.Duration
+	Refresh           time.Duration
+	EmailVerification time.Duration
+	PasswordReset     time.Duration
+}
+
+// RefreshToken is one link of a session's rotation chain. Only the SHA-256
+// of the token is stored; every refresh marks the token used and issues the
+// next one under the same SessionID.
+type RefreshToken struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint      `gorm:"not null;index"`
+	SessionID string    `gorm:"type:varchar(36);not null;index"`
+	TokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// RevokedToken keeps a revoked access token or session ID until every
+// access token that could carry it has expired.
+type RevokedToken struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+type TokenRepository interface {
+	CreateRefreshToken(token *RefreshToken) error
+	GetRefreshToken(hash string) (*RefreshToken, error)
+	// UseRefreshToken marks an unused, unrevoked token used and reports
+	// whether it was; a false result means the token was replayed.
+	UseRefreshToken(id uint, at time.Time) (bool, error)
+	// RevokeSessions revokes the refresh tokens of the given sessions of a
+	// user, or of all their sessions when none are given, and returns the
+	// IDs of the sessions it revoked.
+	RevokeSessions(userID uint, sessionIDs []string, at time.Time) ([]string, error)
+	Revoke(ids []string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, ids ...string) (bool, error)
+
+	CreateActionToken(token *ActionToken) error
+	// UseActionToken marks the unused, unexpired token with the hash and
+	// purpose used and returns it, or gorm.ErrRecordNotFound.
+	UseActionToken(hash, purpose string, at time.Time) (*ActionToken, error)
+	// UseActionTokens marks every unused token of the user and purpose used.
+	UseActionTokens(userID uint, purpose string, at time.Time) error
+
+	CreateSSOState(state *SSOState) error
+	// TakeSSOState deletes the unexpired state with the hash and returns
+	// it, or gorm.ErrRecordNotFound.
+	TakeSSOState(hash string, at time.Time) (*SSOState, error)
+}
+
+// Refresh trades a refresh token for a new access and refresh token pair.
+// Presenting a token that was already used revokes its whole session, since
+// either the client or an attacker holds a stolen copy.
+func (s *authService) Refresh(req *RefreshRequest) (*LoginResponse, error) {
+	now := time.Now()
+	stored, err := s.tokens.GetRefreshToken(hashToken(req.RefreshToken))
+	if err != nil || stored.RevokedAt != nil || !now.Before(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	fresh, err := s.tokens.UseRefreshToken(stored.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		if err := s.revokeSessions(stored.UserID, []string{stored.SessionID}); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	return s.issueTokens(user, stored.SessionID)
+}
+
+// Logout ends the session the refresh token belongs to, including access
+// tokens already issued for it.
+func (s *authService) Logout(req *RefreshRequest) error {
+	stored, err := s.tokens.GetRefreshToken(hashToken(req.RefreshToken))
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+	return s.revokeSessions(stored.UserID, []string{stored.SessionID})
+}
+
+// LogoutAll ends every session of the user.
+func (s *authService) LogoutAll(userID uint) error {
+	return s.revokeSessions(userID, nil)
+}
+
+func (s *authService) revokeSessions(userID uint, sessionIDs []string) error {
+	return revokeSessions(s.tokens, userID, sessionIDs, s.settings.TTL.Access)
+}
+
+// revokeSessions ends the sessions and puts them on the revocation list until
+// the access tokens issued for them expire.
+func revokeSessions(tokens TokenRepository, userID uint, sessionIDs []string, accessTTL time.Duration) error {
+	now := time.Now()
+	revoked, err := tokens.RevokeSessions(userID, sessionIDs, now)
+	if err != nil {
+		return err
+	}
+	if len(revoked) == 0 {
+		return nil
+	}
+	return tokens.Revoke(revoked, now.Add(accessTTL))
+}
+
+// issueTokens signs an access token for the session and stores the next
+// refresh token of its chain.
+func (s *authService) issueTokens(user *User, sessionID string) (*LoginResponse, error) {
+	now := time.Now()
+	access, expiresAt, err := s.generateJWT(user, sessionID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokens.CreateRefreshToken(&RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: now.Add(s.settings.TTL.Refresh),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		Token:        access,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refresh,
+		User:         toUserResponse(user),
+	}, nil
+}
+
+func (s *authService) generateJWT(user *User, sessionID string, now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(s.settings.TTL.Access)
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"role":    user.Role,
+		"jti":     uuid.New().String(),
+		"sid":     sessionID,
+		"exp":     expiresAt.Unix(),
+		"iat":     now.Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(s.settings.JWTSecret))
+	return signed, expiresAt, err
+}
+
+func newOpaqueToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"time"
+
+	testdto "edu-system/internal/test/dto"
+)
+
+type QuestionRequest struct {
+	testdto.Question
+	Tags       []string `json:"tags"`
+	Difficulty string   `json:"difficulty,omitempty"` // easy | medium | hard
+}
+
+type QuestionView struct {
+	testdto.QuestionResponse
+	Tags       []string  `json:"tags"`
+	Difficulty string    `json:"difficulty,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,142 @@
+package bank
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"edu-system/internal/bank/dto"
+	"edu-system/internal/delivery"
+	"github.com/gin-gonic/gin"
+)
+
+type Handlers struct {
+	svc *Service
+}
+
+func NewHandlers(svc *Service) *Handlers {
+	return &Handlers{svc: svc}
+}
+
+// POST /v1/bank/questions
+func (h *Handlers) Create(c *gin.Context) {
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+	var req dto.QuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{Error: "validation error", Message: err.Error()})
+		return
+	}
+	q, err := h.svc.Create(c, uint(ownerID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{Error: "question creation failed", Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, toView(*q))
+}
+
+// GET /v1/bank/questions?tag=&difficulty=
+func (h *Handlers) List(c *gin.Context) {
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+	qs, err := h.svc.List(c, uint(ownerID), Filter{Tag: c.Query("tag"), Difficulty: c.Query("difficulty")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{Error: "failed to retrieve questions", Message: err.Error()})
+		return
+	}
+	out := make([]dto.QuestionView, 0, len(qs))
+	for _, q := range qs {
+		out = append(out, toView(q))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// GET /v1/bank/questions/:id
+func (h *Handlers) Get(c *gin.Context) {
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+	q, err := h.svc.Get(c, uint(ownerID), c.Param("id"))
+	if err != nil {
+		writeErr(c, "question retrieval failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, toView(*q))
+}
+
+// PUT /v1/bank/questions/:id
+func (h *Handlers) Update(c *gin.Context) {
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+	var req dto.QuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.ErrorResponse{Error: "validation error", Message: err.Error()})
+		return
+	}
+	q, err := h.svc.Update(c, uint(ownerID), c.Param("id"), req)
+	if err != nil {
+		writeErr(c, "question update failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, toView(*q))
+}
+
+// DELETE /v1/bank/questions/:id
+func (h *Handlers) Delete(c *gin.Context) {
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{Error: "unauthorized"})
+		return
+	}
+	if err := h.svc.Delete(c, uint(ownerID), c.Param("id")); err != nil {
+		writeErr(c, "question deletion failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessResponse{Message: "question deleted successfully"})
+}
+
+func writeErr(c *gin.Context, title string, err error) {
+	status := http.StatusBadRequest
+	msg := err.Error()
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrForbidden):
+		status = http.StatusForbidden
+		msg = "not allowed"
+	}
+	c.JSON(status, response.ErrorResponse{Error: title, Message: msg})
+}
+
+func userIDFromCtx(c *gin.Context) (uint64, bool) {
+	val, ok := c.Get("user_id")
+	if !ok {
+		return 0, false
+	}
+	switch v := val.(type) {
+	case uint64:
+		return v, true
+	case uint:
+		return uint64(v), true
+	case int:
+		return uint64(v), true
+	case float64:
+		return uint64(v), true
+	case string:
+		if parsed, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return parsed, true
+		}
+	}
+	return 0, false
+}
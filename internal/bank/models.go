@@ -0,0 +1,69 @@
+package bank
+
+import (
+	"errors"
+	"time"
+
+	"edu-system/internal/test"
+)
+
+var (
+	ErrNotFound  = errors.New("question not found")
+	ErrForbidden = errors.New("forbidden")
+)
+
+var difficulties = map[string]struct{}{"": {}, "easy": {}, "medium": {}, "hard": {}}
+
+// Question is a reusable question owned by a teacher and tagged so tests can
+// draw from the bank instead of embedding fixed questions.
+type Question struct {
+	ID            string
+	OwnerID       uint
+	QuestionText  string
+	Type          string
+	CorrectOption int
+	CorrectJSON   []byte
+	Weight        float64
+	ImageURL      string
+	Options       []Option
+	Tags          []string
+	Difficulty    string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type Option struct {
+	ID         string
+	OptionText string
+	ImageURL   string
+}
+
+// Filter narrows bank listings; empty fields match everything.
+type Filter struct {
+	Tag        string
+	Difficulty string
+}
+
+// ToTestQuestion converts a bank question so it can be frozen into an
+// assignment snapshot alongside regular test questions.
+func (q Question) ToTestQuestion() test.Question {
+	out := test.Question{
+		ID:            q.ID,
+		QuestionText:  q.QuestionText,
+		Type:          q.Type,
+		CorrectOption: q.CorrectOption,
+		CorrectJSON:   q.CorrectJSON,
+		Weight:        q.Weight,
+		ImageURL:      q.ImageURL,
+		Options:       make([]test.Option, 0, len(q.Options)),
+	}
+	for _, o := range q.Options {
+		out.Options = append(out.Options, test.Option{
+			ID:         o.ID,
+			QuestionID: q.ID,
+			OptionText: o.OptionText,
+			ImageURL:   o.ImageURL,
+		})
+	}
+	return out
+}
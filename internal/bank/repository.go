@@ -0,0 +1,11 @@
+package bank
+
+import "context"
+
+type Repository interface {
+	Create(ctx context.Context, q *Question) error
+	GetByID(ctx context.Context, id string) (*Question, error)
+	ListByOwner(ctx context.Context, ownerID uint, filter Filter) ([]Question, error)
+	Update(ctx context.Context, q *Question) error
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,15 @@
+package bank
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(v1 gin.IRouter, h *Handlers, auth, requireAuthor gin.HandlerFunc) {
+	secured := v1.Group("/bank/questions")
+	secured.Use(auth, requireAuthor)
+	{
+		secured.GET("", h.List)
+		secured.POST("", h.Create)
+		secured.GET("/:id", h.Get)
+		secured.PUT("/:id", h.Update)
+		secured.DELETE("/:id", h.Delete)
+	}
+}
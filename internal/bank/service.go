@@ -0,0 +1,140 @@
+package bank
+
+import (
+	"context"
+	"fmt"
+
+	"edu-system/internal/bank/dto"
+	"edu-system/internal/test"
+)
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Create(ctx context.Context, ownerID uint, req dto.QuestionRequest) (*Question, error) {
+	q, err := buildQuestion(req)
+	if err != nil {
+		return nil, err
+	}
+	q.OwnerID = ownerID
+	if err := s.repo.Create(ctx, q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (s *Service) Get(ctx context.Context, ownerID uint, id string) (*Question, error) {
+	q, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if q.OwnerID != ownerID {
+		return nil, ErrForbidden
+	}
+	return q, nil
+}
+
+// List returns the owner's questions matching the filter. It is also used to
+// resolve test draw rules when an assignment freezes its question pool.
+func (s *Service) List(ctx context.Context, ownerID uint, filter Filter) ([]Question, error) {
+	filter.Tag = test.NormalizeTag(filter.Tag)
+	filter.Difficulty = test.NormalizeTag(filter.Difficulty)
+	return s.repo.ListByOwner(ctx, ownerID, filter)
+}
+
+// PoolQuestions returns the owner's questions for a test draw rule, converted
+// for the assignment snapshot.
+func (s *Service) PoolQuestions(ctx context.Context, ownerID uint, tag, difficulty string) ([]test.Question, error) {
+	qs, err := s.List(ctx, ownerID, Filter{Tag: tag, Difficulty: difficulty})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]test.Question, 0, len(qs))
+	for _, q := range qs {
+		out = append(out, q.ToTestQuestion())
+	}
+	return out, nil
+}
+
+func (s *Service) Update(ctx context.Context, ownerID uint, id string, req dto.QuestionRequest) (*Question, error) {
+	existing, err := s.Get(ctx, ownerID, id)
+	if err != nil {
+		return nil, err
+	}
+	q, err := buildQuestion(req)
+	if err != nil {
+		return nil, err
+	}
+	q.ID = existing.ID
+	q.OwnerID = existing.OwnerID
+	q.CreatedAt = existing.CreatedAt
+	if err := s.repo.Update(ctx, q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (s *Service) Delete(ctx context.Context, ownerID uint, id string) error {
+	if _, err := s.Get(ctx, ownerID, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func buildQuestion(req dto.QuestionRequest) (*Question, error) {
+	difficulty := test.NormalizeTag(req.Difficulty)
+	if _, ok := difficulties[difficulty]; !ok {
+		return nil, fmt.Errorf("invalid difficulty %q (use easy, medium or hard)", req.Difficulty)
+	}
+	tq, err := test.BuildQuestion(req.Question)
+	if err != nil {
+		return nil, err
+	}
+	q := &Question{
+		QuestionText:  tq.QuestionText,
+		Type:          tq.Type,
+		CorrectOption: tq.CorrectOption,
+		CorrectJSON:   tq.CorrectJSON,
+		Weight:        tq.Weight,
+		ImageURL:      tq.ImageURL,
+		Options:       make([]Option, 0, len(tq.Options)),
+		Tags:          normalizeTags(req.Tags),
+		Difficulty:    difficulty,
+	}
+	for _, o := range tq.Options {
+		q.Options = append(q.Options, Option{OptionText: o.OptionText, ImageURL: o.ImageURL})
+	}
+	return q, nil
+}
+
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		tag := test.NormalizeTag(t)
+		if tag == "" {
+			continue
+		}
+		if _, dup := seen[tag]; dup {
+			continue
+		}
+		seen[tag] = struct{}{}
+		out = append(out, tag)
+	}
+	return out
+}
+
+func toView(q Question) dto.QuestionView {
+	return dto.QuestionView{
+		QuestionResponse: test.QuestionToDTO(q.ToTestQuestion()),
+		Tags:             q.Tags,
+		Difficulty:       q.Difficulty,
+		CreatedAt:        q.CreatedAt,
+		UpdatedAt:        q.UpdatedAt,
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"log"
 	"net/http"
+	"slices"
 	"strings"
 
 	"edu-system/internal/delivery"
@@ -10,7 +11,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func JWTAuth(jwtSecret string) gin.HandlerFunc {
+func JWTAuth(jwtSecret string, revoked RevocationList) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Printf("JWT Auth middleware: %s %s", c.Request.Method, c.Request.URL.Path)
 
@@ -111,6 +112,10 @@ func JWTAuth(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
+		if rejectRevoked(c, revoked, claims) {
+			return
+		}
+
 		log.Printf("JWT Auth: Successfully authenticated user ID: %v, email: %v, role: %v", userID, email, role)
 
 		c.Set("user_id", userID)
@@ -121,7 +126,9 @@ func JWTAuth(jwtSecret string) gin.HandlerFunc {
 	}
 }
 
-func RequireRole(requiredRole string) gin.HandlerFunc {
+// RequireRole lets the request through when the role claim set by JWTAuth
+// is one of roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
 		if !exists {
@@ -132,7 +139,8 @@ func RequireRole(requiredRole string) gin.HandlerFunc {
 			return
 		}
 
-		if role.(string) != requiredRole {
+		name, _ := role.(string)
+		if !slices.Contains(roles, name) {
 			c.JSON(http.StatusForbidden, response.ErrorResponse{
 				Error: "insufficient permissions",
 			})
@@ -10,7 +10,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func OptionalJWTAuth(jwtSecret string) gin.HandlerFunc {
+func OptionalJWTAuth(jwtSecret string, revoked RevocationList) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method == http.MethodOptions {
 			c.Next()
@@ -68,6 +68,10 @@ func OptionalJWTAuth(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
+		if rejectRevoked(c, revoked, claims) {
+			return
+		}
+
 		c.Set("user_id", userID)
 		c.Set("email", email)
 		c.Set("role", role)
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"edu-system/internal/delivery"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RevocationList tells whether any of the given token or session IDs has
+// been revoked before the token expired.
+type RevocationList interface {
+	IsRevoked(ctx context.Context, ids ...string) (bool, error)
+}
+
+// rejectRevoked checks the jti and sid claims against the list and aborts
+// the request when one is revoked. Tokens without either claim predate
+// revocation and are only limited by their expiry.
+func rejectRevoked(c *gin.Context, revoked RevocationList, claims jwt.MapClaims) bool {
+	if revoked == nil {
+		return false
+	}
+	var ids []string
+	for _, claim := range []string{"jti", "sid"} {
+		if id, ok := claims[claim].(string); ok && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return false
+	}
+
+	isRevoked, err := revoked.IsRevoked(c.Request.Context(), ids...)
+	if err != nil {
+		log.Printf("JWT Auth: revocation check failed: %v", err)
+		c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: "could not verify token",
+		})
+		c.Abort()
+		return true
+	}
+	if isRevoked {
+		c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Error: "token revoked",
+		})
+		c.Abort()
+		return true
+	}
+
+	c.Set("token_id", claims["jti"])
+	c.Set("session_id", claims["sid"])
+	return false
+}
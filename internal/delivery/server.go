@@ -1,12 +1,20 @@
 package response
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout bounds how long Run waits for requests in flight once its
+// context is canceled.
+const shutdownTimeout = 10 * time.Second
+
 type Server struct {
 	engine *gin.Engine
 }
@@ -63,9 +71,29 @@ func (s *Server) SetupRoutes(mounts ...func(gin.IRouter)) {
 	}
 }
 
-func (s *Server) Run(port string) error {
+// Run serves until ctx is canceled, then stops accepting connections and
+// waits for the requests in flight.
+func (s *Server) Run(ctx context.Context, port string) error {
+	srv := &http.Server{Addr: ":" + port, Handler: s.engine}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
 	log.Printf("Server starting on port %s", port)
-	return s.engine.Run(":" + port)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	log.Printf("Server shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 func (s *Server) GetEngine() *gin.Engine {
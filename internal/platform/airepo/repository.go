@@ -0,0 +1,270 @@
+package airepo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"edu-system/internal/ai/dto"
+	"edu-system/internal/ai/runs"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&runRow{}, &traceRow{}, &usageRow{})
+}
+
+// runRow keeps the input and every layer output of a run as JSON documents.
+type runRow struct {
+	ID        string `gorm:"primaryKey;type:varchar(36)"`
+	OwnerID   uint   `gorm:"not null;index"`
+	ParentID  string `gorm:"type:varchar(36);index"`
+	CreatedAt time.Time
+	Title     string `gorm:"type:varchar(255)"`
+	// ReadyForUse and Variants copy the final output for run listings.
+	ReadyForUse bool       `gorm:"not null;default:false"`
+	Variants    int        `gorm:"not null;default:0"`
+	Material    []byte     `gorm:"type:json"`
+	Config      []byte     `gorm:"type:json"`
+	Provider    []byte     `gorm:"type:json"`
+	Ingestion   []byte     `gorm:"type:json"`
+	Plan        []byte     `gorm:"type:json"`
+	Draft       []byte     `gorm:"type:json"`
+	Validation  []byte     `gorm:"type:json"`
+	Final       []byte     `gorm:"type:json"`
+	Traces      []traceRow `gorm:"foreignKey:RunID;references:ID;constraint:OnDelete:CASCADE;"`
+}
+
+func (runRow) TableName() string { return "ai_pipeline_runs" }
+
+type traceRow struct {
+	ID               uint   `gorm:"primaryKey"`
+	RunID            string `gorm:"not null;type:varchar(36);index"`
+	Position         int    `gorm:"not null"`
+	Layer            string `gorm:"type:varchar(16);not null"`
+	Provider         string `gorm:"type:varchar(64)"`
+	Model            string `gorm:"type:varchar(128)"`
+	Attempts         int    `gorm:"not null"`
+	Repairs          int    `gorm:"not null;default:0"`
+	FallbackUsed     bool   `gorm:"not null"`
+	Errors           []byte `gorm:"type:json"`
+	StartedAt        time.Time
+	DurationMs       int64   `gorm:"not null"`
+	PromptTokens     int     `gorm:"not null;default:0"`
+	CompletionTokens int     `gorm:"not null;default:0"`
+	CostUSD          float64 `gorm:"not null;default:0"`
+	PromptVersion    string  `gorm:"type:varchar(128)"`
+}
+
+func (traceRow) TableName() string { return "ai_pipeline_traces" }
+
+// usageRow is one provider call, kept for budget accounting.
+type usageRow struct {
+	ID               uint      `gorm:"primaryKey"`
+	OwnerID          uint      `gorm:"not null;index:idx_ai_usage_owner_created"`
+	CreatedAt        time.Time `gorm:"index:idx_ai_usage_owner_created"`
+	Layer            string    `gorm:"type:varchar(16)"`
+	Provider         string    `gorm:"type:varchar(64)"`
+	Model            string    `gorm:"type:varchar(128)"`
+	PromptTokens     int       `gorm:"not null"`
+	CompletionTokens int       `gorm:"not null"`
+	CostUSD          float64   `gorm:"not null"`
+}
+
+func (usageRow) TableName() string { return "ai_usage" }
+
+func (r *Repository) SaveRun(ctx context.Context, run *runs.Run) error {
+	row, err := fromDomain(run)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(&row).Error
+}
+
+func (r *Repository) GetRun(ctx context.Context, ownerID uint, id string) (*runs.Run, error) {
+	var row runRow
+	err := r.db.WithContext(ctx).
+		Preload("Traces", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		First(&row, "id = ? AND owner_id = ?", id, ownerID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, runs.ErrNotFound
+		}
+		return nil, err
+	}
+	return toDomain(&row)
+}
+
+func (r *Repository) ListRuns(ctx context.Context, ownerID uint, limit, offset int) ([]runs.Summary, error) {
+	var rows []runRow
+	err := r.db.WithContext(ctx).
+		Select("id", "parent_id", "title", "created_at", "ready_for_use", "variants").
+		Where("owner_id = ?", ownerID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]runs.Summary, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, runs.Summary{
+			ID:          row.ID,
+			ParentID:    row.ParentID,
+			Title:       row.Title,
+			CreatedAt:   row.CreatedAt,
+			ReadyForUse: row.ReadyForUse,
+			Variants:    row.Variants,
+		})
+	}
+	return out, nil
+}
+
+func (r *Repository) RecordUsage(ctx context.Context, usage *runs.Usage) error {
+	row := usageRow{
+		OwnerID:          usage.OwnerID,
+		CreatedAt:        usage.CreatedAt,
+		Layer:            usage.Layer,
+		Provider:         usage.Provider,
+		Model:            usage.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          usage.CostUSD,
+	}
+	return r.db.WithContext(ctx).Create(&row).Error
+}
+
+func (r *Repository) SpentSince(ctx context.Context, ownerID uint, since time.Time) (float64, error) {
+	var total float64
+	err := r.db.WithContext(ctx).Model(&usageRow{}).
+		Where("owner_id = ? AND created_at >= ?", ownerID, since).
+		Select("COALESCE(SUM(cost_usd), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+func fromDomain(run *runs.Run) (runRow, error) {
+	row := runRow{
+		ID:        run.ID,
+		OwnerID:   run.OwnerID,
+		ParentID:  run.ParentID,
+		CreatedAt: run.CreatedAt,
+		Title:     run.Material.Title,
+
+		ReadyForUse: run.Result.Final.ReadyForUse,
+		Variants:    len(run.Result.Final.TestVariants),
+		Traces:      make([]traceRow, 0, len(run.Result.ProviderTrace)),
+	}
+	fields := []struct {
+		dst *[]byte
+		src any
+	}{
+		{&row.Material, run.Material},
+		{&row.Config, run.Config},
+		{&row.Provider, run.Provider},
+		{&row.Ingestion, run.Ingestion},
+		{&row.Plan, run.Result.Plan},
+		{&row.Draft, run.Result.Draft},
+		{&row.Validation, run.Result.Validation},
+		{&row.Final, run.Result.Final},
+	}
+	for _, f := range fields {
+		data, err := json.Marshal(f.src)
+		if err != nil {
+			return runRow{}, err
+		}
+		*f.dst = data
+	}
+	for i, t := range run.Result.ProviderTrace {
+		errs, err := json.Marshal(t.Errors)
+		if err != nil {
+			return runRow{}, err
+		}
+		row.Traces = append(row.Traces, traceRow{
+			RunID:            run.ID,
+			Position:         i,
+			Layer:            t.Layer,
+			Provider:         t.Provider,
+			Model:            t.Model,
+			Attempts:         t.Attempts,
+			Repairs:          t.Repairs,
+			FallbackUsed:     t.FallbackUsed,
+			Errors:           errs,
+			StartedAt:        t.StartedAt,
+			DurationMs:       t.DurationMs,
+			PromptTokens:     t.Usage.PromptTokens,
+			CompletionTokens: t.Usage.CompletionTokens,
+			CostUSD:          t.Usage.CostUSD,
+			PromptVersion:    t.PromptVersion,
+		})
+	}
+	return row, nil
+}
+
+func toDomain(row *runRow) (*runs.Run, error) {
+	run := &runs.Run{
+		ID:        row.ID,
+		OwnerID:   row.OwnerID,
+		ParentID:  row.ParentID,
+		CreatedAt: row.CreatedAt,
+	}
+	run.Result.RunID = row.ID
+	fields := []struct {
+		src []byte
+		dst any
+	}{
+		{row.Material, &run.Material},
+		{row.Config, &run.Config},
+		{row.Provider, &run.Provider},
+		{row.Ingestion, &run.Ingestion},
+		{row.Plan, &run.Result.Plan},
+		{row.Draft, &run.Result.Draft},
+		{row.Validation, &run.Result.Validation},
+		{row.Final, &run.Result.Final},
+	}
+	for _, f := range fields {
+		if len(f.src) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(f.src, f.dst); err != nil {
+			return nil, err
+		}
+	}
+	run.Result.ProviderTrace = make([]dto.LayerProviderTrace, 0, len(row.Traces))
+	for _, t := range row.Traces {
+		trace := dto.LayerProviderTrace{
+			Layer:        t.Layer,
+			Provider:     t.Provider,
+			Model:        t.Model,
+			Attempts:     t.Attempts,
+			Repairs:      t.Repairs,
+			FallbackUsed: t.FallbackUsed,
+			StartedAt:    t.StartedAt,
+			DurationMs:   t.DurationMs,
+			Usage: dto.LayerUsage{
+				PromptTokens:     t.PromptTokens,
+				CompletionTokens: t.CompletionTokens,
+				CostUSD:          t.CostUSD,
+			},
+			PromptVersion: t.PromptVersion,
+		}
+		if len(t.Errors) > 0 {
+			if err := json.Unmarshal(t.Errors, &trace.Errors); err != nil {
+				return nil, err
+			}
+		}
+		run.Result.ProviderTrace = append(run.Result.ProviderTrace, trace)
+	}
+	return run, nil
+}
@@ -54,6 +54,51 @@ func (r *Repository) ListByOwner(ctx context.Context, ownerID uint) ([]assignmen
 	return out, nil
 }
 
+func (r *Repository) Update(ctx context.Context, a *assignment.Assignment) error {
+	row := fromDomain(a)
+	res := r.db.WithContext(ctx).Model(&assignmentRow{}).
+		Where("id = ?", row.ID).
+		Updates(map[string]any{
+			"title":           row.Title,
+			"comment":         row.Comment,
+			"available_from":  row.AvailableFrom,
+			"available_until": row.AvailableUntil,
+			"closed_at":       row.ClosedAt,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return assignment.ErrNotFound
+	}
+	return nil
+}
+
+// GetByIDIncludingDeleted also returns soft-deleted assignments, so attempts
+// made on them can still be finished and reviewed.
+func (r *Repository) GetByIDIncludingDeleted(ctx context.Context, id string) (*assignment.Assignment, error) {
+	var row assignmentRow
+	if err := r.db.WithContext(ctx).Unscoped().First(&row, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, assignment.ErrNotFound
+		}
+		return nil, err
+	}
+	return toDomain(&row), nil
+}
+
+// Delete soft-deletes the assignment; its attempts and grades are kept.
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	res := r.db.WithContext(ctx).Delete(&assignmentRow{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return assignment.ErrNotFound
+	}
+	return nil
+}
+
 func fromDomain(a *assignment.Assignment) assignmentRow {
 	return assignmentRow{
 		ID:               a.ID,
@@ -63,31 +108,52 @@ func fromDomain(a *assignment.Assignment) assignmentRow {
 		Comment:          a.Comment,
 		CreatedAt:        a.CreatedAt,
 		TemplateSnapshot: []byte(a.Template),
+		AvailableFrom:    a.AvailableFrom,
+		AvailableUntil:   a.AvailableUntil,
+		ClosedAt:         a.ClosedAt,
 	}
 }
 
 func toDomain(row *assignmentRow) *assignment.Assignment {
 	return &assignment.Assignment{
-		ID:        row.ID,
-		TestID:    row.TestID,
-		OwnerID:   row.OwnerID,
-		Title:     row.Title,
-		Comment:   row.Comment,
-		CreatedAt: row.CreatedAt,
-		Template:  row.TemplateSnapshot,
+		ID:             row.ID,
+		TestID:         row.TestID,
+		OwnerID:        row.OwnerID,
+		Title:          row.Title,
+		Comment:        row.Comment,
+		CreatedAt:      row.CreatedAt,
+		UpdatedAt:      row.UpdatedAt,
+		Template:       row.TemplateSnapshot,
+		AvailableFrom:  row.AvailableFrom,
+		AvailableUntil: row.AvailableUntil,
+		ClosedAt:       row.ClosedAt,
+		DeletedAt:      deletedAt(row.DeletedAt),
+	}
+}
+
+func deletedAt(d gorm.DeletedAt) *time.Time {
+	if !d.Valid {
+		return nil
 	}
+	t := d.Time
+	return &t
 }
 
 type assignmentRow struct {
 	ID        string `gorm:"primaryKey;type:varchar(36)"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 
 	TestID           string `gorm:"not null;type:varchar(36);index"`
 	OwnerID          uint   `gorm:"not null;index"`
 	Title            string `gorm:"type:varchar(255)"`
 	Comment          string `gorm:"type:varchar(500)"`
 	TemplateSnapshot []byte `gorm:"type:json"`
+
+	AvailableFrom  *time.Time
+	AvailableUntil *time.Time
+	ClosedAt       *time.Time
 }
 
 func (assignmentRow) TableName() string { return "test_assignments" }
@@ -0,0 +1,71 @@
+package authrepo
+
+import (
+	"edu-system/internal/auth"
+	"gorm.io/gorm"
+)
+
+type organisationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganisationRepository(db *gorm.DB) auth.OrganisationRepository {
+	return &organisationRepository{db: db}
+}
+
+func (r *organisationRepository) CreateOrganisation(org *auth.Organisation) error {
+	var count int64
+	if err := r.db.Model(&auth.Organisation{}).Where("name = ?", org.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return auth.ErrDuplicateName
+	}
+	return r.db.Create(org).Error
+}
+
+func (r *organisationRepository) GetOrganisation(id uint) (*auth.Organisation, error) {
+	var org auth.Organisation
+	if err := r.db.First(&org, id).Error; err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *organisationRepository) ListOrganisations() ([]auth.Organisation, error) {
+	var orgs []auth.Organisation
+	if err := r.db.Order("name").Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (r *organisationRepository) CreateSchool(school *auth.School) error {
+	var count int64
+	err := r.db.Model(&auth.School{}).
+		Where("organisation_id = ? AND name = ?", school.OrganisationID, school.Name).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return auth.ErrDuplicateName
+	}
+	return r.db.Create(school).Error
+}
+
+func (r *organisationRepository) GetSchool(id uint) (*auth.School, error) {
+	var school auth.School
+	if err := r.db.First(&school, id).Error; err != nil {
+		return nil, err
+	}
+	return &school, nil
+}
+
+func (r *organisationRepository) ListSchools(organisationID uint) ([]auth.School, error) {
+	var schools []auth.School
+	if err := r.db.Where("organisation_id = ?", organisationID).Order("name").Find(&schools).Error; err != nil {
+		return nil, err
+	}
+	return schools, nil
+}
@@ -1,7 +1,13 @@
 package authrepo
 
 import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
 	"edu-system/internal/auth"
+	"edu-system/internal/platform/gormtx"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +19,60 @@ func NewUserRepository(db *gorm.DB) auth.UserRepository {
 	return &userRepository{db: db}
 }
 
+// Migrate creates the account tables. Accounts from before roles existed
+// hold "user" and could author tests, so they become teachers; accounts from
+// before email verification count as verified. Emails are stored lower-case
+// and trimmed; rows that would collide with another account once normalised
+// are left alone and logged for an administrator to merge.
+func Migrate(db *gorm.DB) error {
+	verifiedColumn := db.Migrator().HasTable(&auth.User{}) && !db.Migrator().HasColumn(&auth.User{}, "EmailVerifiedAt")
+	if err := db.AutoMigrate(
+		&auth.User{},
+		&auth.Organisation{},
+		&auth.School{},
+		&auth.RefreshToken{},
+		&auth.RevokedToken{},
+		&auth.ActionToken{},
+		&auth.SSOState{},
+	); err != nil {
+		return err
+	}
+	if verifiedColumn {
+		if err := db.Model(&auth.User{}).Where("email_verified_at IS NULL").
+			Update("email_verified_at", gorm.Expr("created_at")).Error; err != nil {
+			return err
+		}
+	}
+	if err := db.Model(&auth.User{}).
+		Where("role = ? OR role = '' OR role IS NULL", "user").
+		Update("role", auth.RoleTeacher).Error; err != nil {
+		return err
+	}
+	return normalizeEmails(db)
+}
+
+func normalizeEmails(db *gorm.DB) error {
+	var users []auth.User
+	if err := db.Unscoped().Select("id", "email").Where("email <> LOWER(TRIM(email))").Find(&users).Error; err != nil {
+		return err
+	}
+	for _, user := range users {
+		email := strings.ToLower(strings.TrimSpace(user.Email))
+		var taken int64
+		if err := db.Unscoped().Model(&auth.User{}).Where("email = ?", email).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			log.Printf("authrepo: email of user %d differs only in case from another account; merge them by hand", user.ID)
+			continue
+		}
+		if err := db.Unscoped().Model(&auth.User{}).Where("id = ?", user.ID).Update("email", email).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *userRepository) Create(user *auth.User) error {
 	return r.db.Create(user).Error
 }
@@ -35,10 +95,60 @@ func (r *userRepository) GetByID(id uint) (*auth.User, error) {
 	return &user, nil
 }
 
+func (r *userRepository) GetBySubject(subject string) (*auth.User, error) {
+	var user auth.User
+	err := r.db.Where("oidc_subject = ?", subject).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) List(filter auth.UserFilter) ([]auth.User, error) {
+	query := r.db.Order("id")
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.OrganisationID != 0 {
+		query = query.Where("organisation_id = ?", filter.OrganisationID)
+	}
+	if filter.SchoolID != 0 {
+		query = query.Where("school_id = ?", filter.SchoolID)
+	}
+	var users []auth.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) CountByRole(role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&auth.User{}).Where("role = ?", role).Count(&count).Error
+	return count, err
+}
+
 func (r *userRepository) Update(user *auth.User) error {
 	return r.db.Save(user).Error
 }
 
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&auth.User{}, id).Error
+func (r *userRepository) DeleteAccount(ctx context.Context, user *auth.User, at time.Time) ([]string, error) {
+	var revoked []string
+	err := gormtx.DB(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&auth.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, at).
+			Distinct().Pluck("session_id", &revoked).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&auth.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", at).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&auth.User{}, user.ID).Error
+	})
+	return revoked, err
 }
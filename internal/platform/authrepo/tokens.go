@@ -0,0 +1,147 @@
+package authrepo
+
+import (
+	"context"
+	"time"
+
+	"edu-system/internal/auth"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) auth.TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+func (r *tokenRepository) CreateRefreshToken(token *auth.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *tokenRepository) GetRefreshToken(hash string) (*auth.RefreshToken, error) {
+	var token auth.RefreshToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *tokenRepository) UseRefreshToken(id uint, at time.Time) (bool, error) {
+	res := r.db.Model(&auth.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *tokenRepository) RevokeSessions(userID uint, sessionIDs []string, at time.Time) ([]string, error) {
+	var revoked []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&auth.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, at)
+		if len(sessionIDs) > 0 {
+			query = query.Where("session_id IN ?", sessionIDs)
+		}
+		if err := query.Distinct().Pluck("session_id", &revoked).Error; err != nil {
+			return err
+		}
+		if len(revoked) == 0 {
+			return nil
+		}
+		return tx.Model(&auth.RefreshToken{}).
+			Where("user_id = ? AND session_id IN ? AND revoked_at IS NULL", userID, revoked).
+			Update("revoked_at", at).Error
+	})
+	return revoked, err
+}
+
+// Revoke adds IDs to the revocation list and drops entries that expired.
+func (r *tokenRepository) Revoke(ids []string, expiresAt time.Time) error {
+	rows := make([]auth.RevokedToken, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, auth.RevokedToken{ID: id, ExpiresAt: expiresAt})
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&auth.RevokedToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+		}).Create(&rows).Error
+	})
+}
+
+func (r *tokenRepository) IsRevoked(ctx context.Context, ids ...string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&auth.RevokedToken{}).
+		Where("id IN ? AND expires_at > ?", ids, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *tokenRepository) CreateActionToken(token *auth.ActionToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *tokenRepository) UseActionTokens(userID uint, purpose string, at time.Time) error {
+	return r.db.Model(&auth.ActionToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error
+}
+
+func (r *tokenRepository) UseActionToken(hash, purpose string, at time.Time) (*auth.ActionToken, error) {
+	var token auth.ActionToken
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, at).
+			First(&token).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&auth.ActionToken{}).Where("id = ? AND used_at IS NULL", token.ID).Update("used_at", at)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		token.UsedAt = &at
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// CreateSSOState stores a pending sign-on and drops abandoned ones.
+func (r *tokenRepository) CreateSSOState(state *auth.SSOState) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&auth.SSOState{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(state).Error
+	})
+}
+
+func (r *tokenRepository) TakeSSOState(hash string, at time.Time) (*auth.SSOState, error) {
+	var state auth.SSOState
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state_hash = ? AND expires_at > ?", hash, at).First(&state).Error; err != nil {
+			return err
+		}
+		res := tx.Where("state_hash = ?", hash).Delete(&auth.SSOState{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
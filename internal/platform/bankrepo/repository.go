@@ -0,0 +1,196 @@
+package bankrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"edu-system/internal/bank"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&questionRow{}, &optionRow{}, &tagRow{})
+}
+
+type questionRow struct {
+	ID            string `gorm:"primaryKey;type:varchar(36)"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	OwnerID       uint        `gorm:"not null;index"`
+	QuestionText  string      `gorm:"not null"`
+	Type          string      `gorm:"type:varchar(16);not null;default:'single'"`
+	CorrectOption int         `gorm:"not null"`
+	CorrectJSON   []byte      `gorm:"type:json"`
+	Weight        float64     `gorm:"not null;default:1"`
+	ImageURL      string      `gorm:"type:varchar(255)"`
+	Difficulty    string      `gorm:"type:varchar(16);index"`
+	Options       []optionRow `gorm:"foreignKey:QuestionID;references:ID;constraint:OnDelete:CASCADE;"`
+	Tags          []tagRow    `gorm:"foreignKey:QuestionID;references:ID;constraint:OnDelete:CASCADE;"`
+}
+
+func (questionRow) TableName() string { return "bank_questions" }
+
+type optionRow struct {
+	ID         string `gorm:"primaryKey;type:varchar(36)"`
+	QuestionID string `gorm:"not null;type:varchar(36);index"`
+	Position   int    `gorm:"not null"`
+	OptionText string `gorm:"not null"`
+	ImageURL   string `gorm:"type:varchar(255)"`
+}
+
+func (optionRow) TableName() string { return "bank_question_options" }
+
+type tagRow struct {
+	QuestionID string `gorm:"primaryKey;type:varchar(36)"`
+	Tag        string `gorm:"primaryKey;type:varchar(64);index"`
+}
+
+func (tagRow) TableName() string { return "bank_question_tags" }
+
+func (r *Repository) Create(ctx context.Context, q *bank.Question) error {
+	if q.ID == "" {
+		q.ID = uuid.NewString()
+	}
+	row := fromDomain(q)
+	if err := r.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return err
+	}
+	q.CreatedAt, q.UpdatedAt = row.CreatedAt, row.UpdatedAt
+	return nil
+}
+
+func (r *Repository) GetByID(ctx context.Context, id string) (*bank.Question, error) {
+	var row questionRow
+	err := r.db.WithContext(ctx).
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Tags").
+		First(&row, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, bank.ErrNotFound
+		}
+		return nil, err
+	}
+	return toDomain(&row), nil
+}
+
+func (r *Repository) ListByOwner(ctx context.Context, ownerID uint, filter bank.Filter) ([]bank.Question, error) {
+	tx := r.db.WithContext(ctx).
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Tags").
+		Where("owner_id = ?", ownerID)
+	if filter.Tag != "" {
+		tx = tx.Where("id IN (?)", r.db.Model(&tagRow{}).Select("question_id").Where("tag = ?", filter.Tag))
+	}
+	if filter.Difficulty != "" {
+		tx = tx.Where("difficulty = ?", filter.Difficulty)
+	}
+	var rows []questionRow
+	if err := tx.Order("created_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]bank.Question, 0, len(rows))
+	for i := range rows {
+		out = append(out, *toDomain(&rows[i]))
+	}
+	return out, nil
+}
+
+func (r *Repository) Update(ctx context.Context, q *bank.Question) error {
+	row := fromDomain(q)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("question_id = ?", row.ID).Delete(&optionRow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("question_id = ?", row.ID).Delete(&tagRow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(&row).Error; err != nil {
+			return err
+		}
+		q.UpdatedAt = row.UpdatedAt
+		return nil
+	})
+}
+
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("question_id = ?", id).Delete(&optionRow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("question_id = ?", id).Delete(&tagRow{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&questionRow{}, "id = ?", id).Error
+	})
+}
+
+func fromDomain(q *bank.Question) questionRow {
+	row := questionRow{
+		ID:            q.ID,
+		CreatedAt:     q.CreatedAt,
+		OwnerID:       q.OwnerID,
+		QuestionText:  q.QuestionText,
+		Type:          q.Type,
+		CorrectOption: q.CorrectOption,
+		CorrectJSON:   q.CorrectJSON,
+		Weight:        q.Weight,
+		ImageURL:      q.ImageURL,
+		Difficulty:    q.Difficulty,
+		Options:       make([]optionRow, 0, len(q.Options)),
+		Tags:          make([]tagRow, 0, len(q.Tags)),
+	}
+	for i, o := range q.Options {
+		id := o.ID
+		if id == "" {
+			id = uuid.NewString()
+		}
+		row.Options = append(row.Options, optionRow{
+			ID:         id,
+			QuestionID: q.ID,
+			Position:   i,
+			OptionText: o.OptionText,
+			ImageURL:   o.ImageURL,
+		})
+	}
+	for _, t := range q.Tags {
+		row.Tags = append(row.Tags, tagRow{QuestionID: q.ID, Tag: t})
+	}
+	return row
+}
+
+func toDomain(row *questionRow) *bank.Question {
+	q := &bank.Question{
+		ID:            row.ID,
+		OwnerID:       row.OwnerID,
+		QuestionText:  row.QuestionText,
+		Type:          row.Type,
+		CorrectOption: row.CorrectOption,
+		CorrectJSON:   row.CorrectJSON,
+		Weight:        row.Weight,
+		ImageURL:      row.ImageURL,
+		Difficulty:    row.Difficulty,
+		CreatedAt:     row.CreatedAt,
+		UpdatedAt:     row.UpdatedAt,
+		Options:       make([]bank.Option, 0, len(row.Options)),
+		Tags:          make([]string, 0, len(row.Tags)),
+	}
+	for _, o := range row.Options {
+		q.Options = append(q.Options, bank.Option{ID: o.ID, OptionText: o.OptionText, ImageURL: o.ImageURL})
+	}
+	for _, t := range row.Tags {
+		q.Tags = append(q.Tags, t.Tag)
+	}
+	return q
+}
@@ -0,0 +1,391 @@
+// Package coderunner executes participant programs for code questions.
+package coderunner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	ta "edu-system/internal/testAttempt"
+)
+
+type Config struct {
+	TimeLimit        time.Duration // per test case
+	CompileTimeLimit time.Duration
+	MemoryLimitMB    int
+	MaxOutputBytes   int
+	GoBin            string
+	PythonBin        string
+	WorkDir          string // parent of per-run temp dirs, defaults to os.TempDir()
+	GoCacheDir       string // shared build cache so the standard library is compiled once
+
+	// MaxConcurrent caps how many submissions run at once; the default is
+	// the number of CPUs.
+	MaxConcurrent int
+	// MaxProcesses limits the processes and threads of one program.
+	MaxProcesses  int
+	MaxFileSizeMB int
+	TmpSizeMB     int // size of the program's private /tmp
+	// SandboxUID and SandboxGID own sandboxed processes when the backend
+	// runs as root. An unprivileged backend runs them under its own IDs,
+	// relying on the private root file system alone.
+	SandboxUID int
+	SandboxGID int
+}
+
+// tool is a resolved compiler or interpreter. Its directories are mounted
+// read-only at the same paths inside the sandbox.
+type tool struct {
+	bin    string
+	mounts []string
+	env    []string
+}
+
+type language struct {
+	file    string
+	resolve func(cfg Config) (*tool, error)
+	build   func(t *tool) []string // nil for interpreted languages
+	run     func(t *tool) []string
+}
+
+var languages = map[string]language{
+	"go": {
+		file:    "main.go",
+		resolve: resolveGo,
+		build: func(t *tool) []string {
+			return []string{t.bin, "build", "-o", workDir + "/prog", workDir + "/main.go"}
+		},
+		run: func(t *tool) []string { return []string{workDir + "/prog"} },
+	},
+	"python": {
+		file:    "main.py",
+		resolve: resolvePython,
+		run: func(t *tool) []string {
+			return []string{t.bin, "-I", "-B", workDir + "/main.py"}
+		},
+	},
+}
+
+var aliases = map[string]string{
+	"golang":  "go",
+	"py":      "python",
+	"python3": "python",
+}
+
+// LocalRunner runs programs as local subprocesses in a Linux sandbox: a
+// private read-only root holding only the toolchain, no network, its own
+// user and PID namespaces, no capabilities and rlimits on CPU, memory,
+// processes, open files and file size.
+type LocalRunner struct {
+	cfg   Config
+	slots chan struct{}
+
+	mu    sync.Mutex
+	tools map[string]*tool
+
+	cacheOnce sync.Once
+	cacheErr  error
+}
+
+func NewLocalRunner(cfg Config) *LocalRunner {
+	if cfg.TimeLimit <= 0 {
+		cfg.TimeLimit = 2 * time.Second
+	}
+	if cfg.CompileTimeLimit <= 0 {
+		cfg.CompileTimeLimit = 30 * time.Second
+	}
+	if cfg.MaxOutputBytes <= 0 {
+		cfg.MaxOutputBytes = 64 << 10
+	}
+	if cfg.GoBin == "" {
+		cfg.GoBin = "go"
+	}
+	if cfg.PythonBin == "" {
+		cfg.PythonBin = "python3"
+	}
+	if cfg.GoCacheDir == "" {
+		cfg.GoCacheDir = filepath.Join(os.TempDir(), "edu-system-gocache")
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = runtime.NumCPU()
+	}
+	if cfg.MaxProcesses <= 0 {
+		cfg.MaxProcesses = 64
+	}
+	if cfg.MaxFileSizeMB <= 0 {
+		cfg.MaxFileSizeMB = 16
+	}
+	if cfg.TmpSizeMB <= 0 {
+		cfg.TmpSizeMB = 64
+	}
+	if cfg.SandboxUID <= 0 {
+		cfg.SandboxUID = 65534
+	}
+	if cfg.SandboxGID <= 0 {
+		cfg.SandboxGID = 65534
+	}
+	return &LocalRunner{
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.MaxConcurrent),
+		tools: make(map[string]*tool),
+	}
+}
+
+func (r *LocalRunner) Run(ctx context.Context, lang, source string, stdins []string) ([]ta.CodeRunResult, error) {
+	name := strings.ToLower(strings.TrimSpace(lang))
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	l, ok := languages[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ta.ErrUnsupportedLanguage, lang)
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	t, err := r.tool(name, l)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(r.cfg.WorkDir, "edu-run-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, l.file)
+	if err := os.WriteFile(src, []byte(source), 0o600); err != nil {
+		return nil, err
+	}
+	if err := r.chown(dir, src); err != nil {
+		return nil, err
+	}
+
+	if l.build != nil {
+		if err := r.prepareCache(); err != nil {
+			return nil, err
+		}
+		run := newSandboxRun(l.build(t), r.buildEnv(t), workDir, 256, limits{
+			CPUSeconds: uint64(r.cfg.CompileTimeLimit.Seconds()) + 1,
+			FileSizeMB: 256,
+			OpenFiles:  1024,
+			Processes:  1024,
+		}, r.mounts(t, dir, true)...)
+		res, err := r.exec(ctx, run, "", r.cfg.CompileTimeLimit)
+		if err != nil {
+			return nil, err
+		}
+		if res.TimedOut {
+			return nil, fmt.Errorf("%w: compilation timed out", ta.ErrCodeCompile)
+		}
+		if res.ExitCode != 0 {
+			return nil, fmt.Errorf("%w: %s", ta.ErrCodeCompile, strings.TrimSpace(res.Stderr+res.Stdout))
+		}
+	}
+
+	out := make([]ta.CodeRunResult, 0, len(stdins))
+	for _, stdin := range stdins {
+		run := newSandboxRun(l.run(t), r.runEnv(), "/tmp", r.cfg.TmpSizeMB, limits{
+			CPUSeconds: uint64(r.cfg.TimeLimit.Seconds()) + 1,
+			DataMB:     uint64(r.cfg.MemoryLimitMB),
+			FileSizeMB: uint64(r.cfg.MaxFileSizeMB),
+			OpenFiles:  64,
+			Processes:  uint64(r.cfg.MaxProcesses),
+		}, r.mounts(t, dir, false)...)
+		res, err := r.exec(ctx, run, stdin, r.cfg.TimeLimit)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// mounts lists what a run sees besides the system directories: the tool,
+// the run directory (writable only while compiling) and the build cache.
+func (r *LocalRunner) mounts(t *tool, dir string, compile bool) []mount {
+	var ms []mount
+	for _, path := range t.mounts {
+		ms = append(ms, mount{Source: path, Target: path})
+	}
+	ms = append(ms, mount{Source: dir, Target: workDir, Writable: compile})
+	if compile {
+		ms = append(ms, mount{Source: r.cfg.GoCacheDir, Target: r.cfg.GoCacheDir, Writable: true})
+	}
+	return ms
+}
+
+func (r *LocalRunner) buildEnv(t *tool) []string {
+	return append([]string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=/tmp",
+		"TMPDIR=/tmp",
+		"GOCACHE=" + r.cfg.GoCacheDir,
+		"GOPATH=/tmp/gopath",
+		"GOENV=off",
+		"GOTOOLCHAIN=local",
+		"GOPROXY=off",
+		"GOFLAGS=",
+		"CGO_ENABLED=0",
+	}, t.env...)
+}
+
+func (r *LocalRunner) runEnv() []string {
+	return []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=/tmp",
+		"TMPDIR=/tmp",
+		// Keeps Go programs within the process limit on many-core hosts.
+		"GOMAXPROCS=2",
+	}
+}
+
+// tool resolves and caches the compiler or interpreter of a language.
+func (r *LocalRunner) tool(name string, l language) (*tool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tools[name]; ok {
+		return t, nil
+	}
+	t, err := l.resolve(r.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s toolchain: %v", errSandbox, name, err)
+	}
+	r.tools[name] = t
+	return t, nil
+}
+
+func resolveGo(cfg Config) (*tool, error) {
+	cmd := exec.Command(cfg.GoBin, "env", "GOROOT")
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	goroot, err := filepath.EvalSymlinks(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, err
+	}
+	return &tool{
+		bin:    filepath.Join(goroot, "bin", "go"),
+		mounts: []string{goroot},
+		env:    []string{"GOROOT=" + goroot},
+	}, nil
+}
+
+// resolvePython finds the real interpreter behind PythonBin, which may be
+// a shim, and the prefix holding its standard library.
+func resolvePython(cfg Config) (*tool, error) {
+	out, err := exec.Command(cfg.PythonBin, "-I", "-c", "import sys; print(sys.executable); print(sys.base_prefix)").Output()
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		return nil, fmt.Errorf("unexpected output %q", out)
+	}
+	bin, err := filepath.EvalSymlinks(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSpace(lines[1])
+	return &tool{bin: bin, mounts: []string{prefix, filepath.Dir(bin)}}, nil
+}
+
+// chown hands the run directory to the sandbox user when the backend runs
+// as root; otherwise the sandbox already runs under the owner's uid.
+func (r *LocalRunner) chown(paths ...string) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	for _, path := range paths {
+		if err := os.Lchown(path, r.cfg.SandboxUID, r.cfg.SandboxGID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareCache creates the shared build cache, owned by the sandbox user.
+func (r *LocalRunner) prepareCache() error {
+	r.cacheOnce.Do(func() {
+		if r.cacheErr = os.MkdirAll(r.cfg.GoCacheDir, 0o700); r.cacheErr != nil {
+			return
+		}
+		if os.Geteuid() != 0 {
+			return
+		}
+		r.cacheErr = filepath.WalkDir(r.cfg.GoCacheDir, func(path string, _ fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Lchown(path, r.cfg.SandboxUID, r.cfg.SandboxGID)
+		})
+	})
+	return r.cacheErr
+}
+
+func (r *LocalRunner) exec(ctx context.Context, run *sandboxRun, stdin string, limit time.Duration) (ta.CodeRunResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	stdout := &limitedBuffer{max: r.cfg.MaxOutputBytes}
+	stderr := &limitedBuffer{max: r.cfg.MaxOutputBytes}
+	started := time.Now()
+	err := runSandboxed(ctx, run, r.cfg.SandboxUID, r.cfg.SandboxGID, strings.NewReader(stdin), stdout, stderr)
+	res := ta.CodeRunResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(started),
+	}
+	if errors.Is(err, errSandbox) {
+		return ta.CodeRunResult{}, err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		res.TimedOut = true
+		res.ExitCode = -1
+		return res, nil
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+	default:
+		return ta.CodeRunResult{}, err
+	}
+	return res, nil
+}
+
+// limitedBuffer keeps the first max bytes and silently drops the rest so a
+// chatty program does not fail on a closed pipe.
+type limitedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string { return b.buf.String() }
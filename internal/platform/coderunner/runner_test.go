@@ -0,0 +1,118 @@
+package coderunner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	ta "edu-system/internal/testAttempt"
+)
+
+func newTestRunner(t *testing.T, bin string) *LocalRunner {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox requires linux")
+	}
+	if _, err := exec.LookPath(bin); err != nil {
+		t.Skipf("%s not installed", bin)
+	}
+	if err := exec.Command("unshare", "-rmn", "true").Run(); err != nil {
+		t.Skip("user namespaces are not available")
+	}
+	return NewLocalRunner(Config{TimeLimit: 2 * time.Second, MemoryLimitMB: 256, GoCacheDir: t.TempDir()})
+}
+
+func TestLocalRunnerPython(t *testing.T) {
+	r := newTestRunner(t, "python3")
+
+	src := "import sys\nprint(sum(int(x) for x in sys.stdin.read().split()))\n"
+	res, err := r.Run(context.Background(), "python", src, []string{"1 2", "40 2"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res) != 2 || strings.TrimSpace(res[0].Stdout) != "3" || strings.TrimSpace(res[1].Stdout) != "42" {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+}
+
+func TestLocalRunnerLimits(t *testing.T) {
+	r := newTestRunner(t, "python3")
+
+	res, err := r.Run(context.Background(), "python", "while True:\n    pass\n", []string{""})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !res[0].TimedOut {
+		t.Fatalf("expected timeout, got %+v", res[0])
+	}
+
+	netProbe := "import socket\nsocket.create_connection(('1.1.1.1', 53), timeout=1)\n"
+	res, err = r.Run(context.Background(), "python", netProbe, []string{""})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res[0].ExitCode == 0 {
+		t.Fatal("expected network access to fail")
+	}
+
+	if _, err := r.Run(context.Background(), "cobol", "", nil); !errors.Is(err, ta.ErrUnsupportedLanguage) {
+		t.Fatalf("expected unsupported language, got %v", err)
+	}
+}
+
+func TestLocalRunnerIsolation(t *testing.T) {
+	r := newTestRunner(t, "python3")
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secret, []byte("s3cret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	probes := map[string]string{
+		"read host file": "print(open(" + strconv.Quote(secret) + ").read())\n",
+		"write root":     "open('/pwned', 'w').write('x')\n",
+		"write source":   "open('/work/main.py', 'a').write('x')\n",
+		"file size":      "open('/tmp/big', 'wb').write(b'x' * (64 << 20))\n",
+	}
+	for name, src := range probes {
+		res, err := r.Run(context.Background(), "python", src, []string{""})
+		if err != nil {
+			t.Fatalf("%s: Run: %v", name, err)
+		}
+		if res[0].ExitCode == 0 || strings.Contains(res[0].Stdout, "s3cret") {
+			t.Fatalf("%s: expected the sandbox to refuse, got %+v", name, res[0])
+		}
+	}
+
+	// The private /tmp is writable.
+	res, err := r.Run(context.Background(), "python", "open('/tmp/x', 'w').write('ok')\nprint(open('/tmp/x').read())\n", []string{""})
+	if err != nil || strings.TrimSpace(res[0].Stdout) != "ok" {
+		t.Fatalf("expected /tmp to be writable, got %+v, %v", res, err)
+	}
+}
+
+func TestLocalRunnerGo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a Go program")
+	}
+	r := newTestRunner(t, "go")
+
+	src := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tvar a, b int\n\tfmt.Scan(&a, &b)\n\tfmt.Println(a * b)\n}\n"
+	res, err := r.Run(context.Background(), "go", src, []string{"6 7"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if strings.TrimSpace(res[0].Stdout) != "42" {
+		t.Fatalf("unexpected result: %+v", res[0])
+	}
+
+	if _, err := r.Run(context.Background(), "go", "package main\nfunc main() { undefined() }\n", []string{""}); !errors.Is(err, ta.ErrCodeCompile) {
+		t.Fatalf("expected compile error, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package coderunner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// errSandbox marks failures to set up the sandbox, as opposed to the
+// program failing inside it.
+var errSandbox = errors.New("code sandbox")
+
+// workDir is where the run directory appears inside the sandbox.
+const workDir = "/work"
+
+// mount makes a host path visible inside the sandbox, read-only unless
+// Writable is set.
+type mount struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Writable bool   `json:"writable"`
+}
+
+// limits are applied as rlimits to the sandboxed process; zero means the
+// limit is left as it is.
+type limits struct {
+	CPUSeconds uint64 `json:"cpu_seconds"`
+	DataMB     uint64 `json:"data_mb"`
+	FileSizeMB uint64 `json:"file_size_mb"`
+	OpenFiles  uint64 `json:"open_files"`
+	Processes  uint64 `json:"processes"`
+}
+
+// sandboxRun is one command to run in the sandbox. The command sees only
+// the mounts and links, a private tmpfs at /tmp and, where the kernel
+// allows it, its own /proc; the root itself is read-only.
+type sandboxRun struct {
+	Args      []string          `json:"args"`
+	Env       []string          `json:"env"`
+	Dir       string            `json:"dir"`
+	Mounts    []mount           `json:"mounts"`
+	Links     map[string]string `json:"links"`
+	TmpSizeMB int               `json:"tmp_size_mb"`
+	Limits    limits            `json:"limits"`
+	// MountFDs tells the sandbox init that the mounts arrive as detached
+	// mount trees on inherited descriptors instead of as paths to open.
+	MountFDs bool `json:"mount_fds"`
+}
+
+// systemPaths are shared read-only with every run when they exist. On
+// merged-/usr systems the top-level directories are symlinks and are
+// recreated as such.
+var systemPaths = []string{
+	"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32",
+	"/etc/ld.so.cache", "/etc/localtime", "/etc/alternatives",
+	"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom",
+}
+
+func newSandboxRun(args, env []string, dir string, tmpSizeMB int, l limits, extra ...mount) *sandboxRun {
+	run := &sandboxRun{
+		Args:      args,
+		Env:       env,
+		Dir:       dir,
+		Links:     map[string]string{},
+		TmpSizeMB: tmpSizeMB,
+		Limits:    l,
+	}
+	var mounts []mount
+	for _, path := range systemPaths {
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Readlink(path); err == nil {
+				run.Links[path] = target
+			}
+			continue
+		}
+		mounts = append(mounts, mount{Source: path, Target: path})
+	}
+	run.Mounts = normalizeMounts(append(mounts, extra...))
+	return run
+}
+
+// normalizeMounts orders mounts parents first and drops read-only mounts
+// already visible through a parent mount of the same host directory, since
+// mount points cannot be created inside a read-only bind.
+func normalizeMounts(mounts []mount) []mount {
+	sort.SliceStable(mounts, func(i, j int) bool { return mounts[i].Target < mounts[j].Target })
+	out := mounts[:0]
+	for _, m := range mounts {
+		covered := false
+		for _, p := range out {
+			if p.Source == p.Target && !m.Writable && m.Source == m.Target && within(m.Target, p.Target) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
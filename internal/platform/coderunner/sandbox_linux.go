@@ -0,0 +1,319 @@
+//go:build linux
+
+package coderunner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// The sandbox re-executes the current binary with sandboxSpecEnv set. The
+// copy starts in fresh user, mount, PID, network, IPC and UTS namespaces,
+// builds the run's root file system, applies the limits, drops every
+// capability and finally executes the command.
+const sandboxSpecEnv = "EDU_CODE_SANDBOX_SPEC"
+
+// Descriptors passed to the sandbox init.
+const (
+	selfFD       = 3 // the executable, run through /proc/self/fd
+	errorFD      = 4 // receives setup errors; closed on a successful exec
+	firstMountFD = 5 // detached mount trees, in the order of run.Mounts
+)
+
+// securebits that keep a uid-0 process from regaining capabilities on exec.
+const (
+	secbitNoroot                  = 1 << 0
+	secbitNorootLocked            = 1 << 1
+	secbitNoSetuidFixup           = 1 << 2
+	secbitNoSetuidFixupLocked     = 1 << 3
+	secbitKeepCapsLocked          = 1 << 5
+	secbitNoCapAmbientRaise       = 1 << 6
+	secbitNoCapAmbientRaiseLocked = 1 << 7
+)
+
+func init() {
+	if spec, ok := os.LookupEnv(sandboxSpecEnv); ok {
+		sandboxInit(spec)
+	}
+}
+
+// runSandboxed runs the command as uid and gid, which the sandbox maps to
+// its root; they only matter when the backend runs as root, because an
+// unprivileged user namespace can only map the caller's own IDs.
+func runSandboxed(ctx context.Context, run *sandboxRun, uid, gid int, stdin io.Reader, stdout, stderr io.Writer) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSandbox, err)
+	}
+	self, err := os.Open(exe)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSandbox, err)
+	}
+	defer self.Close()
+
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSandbox, err)
+	}
+	defer errR.Close()
+	defer errW.Close()
+	files := []*os.File{self, errW}
+
+	if os.Geteuid() == 0 {
+		// The sandbox cannot see paths its uid may not traverse, so root
+		// clones the mounts here and hands them over.
+		run.MountFDs = true
+		for _, m := range run.Mounts {
+			fd, err := cloneMount(m)
+			if err != nil {
+				return fmt.Errorf("%w: mount %s: %v", errSandbox, m.Source, err)
+			}
+			f := os.NewFile(uintptr(fd), m.Source)
+			defer f.Close()
+			files = append(files, f)
+		}
+	} else {
+		uid, gid = os.Getuid(), os.Getgid()
+	}
+
+	spec, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, fmt.Sprintf("/proc/self/fd/%d", selfFD))
+	cmd.Args = []string{"edu-code-sandbox"}
+	cmd.Env = []string{sandboxSpecEnv + "=" + string(spec)}
+	cmd.ExtraFiles = files
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: uid, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: gid, Size: 1}},
+	}
+	if run.MountFDs {
+		// Root switches to the mapped IDs and sheds its supplementary groups;
+		// otherwise the sandbox would still run as host root.
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: 0, Gid: 0, Groups: []uint32{}}
+		cmd.SysProcAttr.GidMappingsEnableSetgroups = true
+	}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%w: %v", errSandbox, err)
+	}
+	errW.Close()
+	setupErr, _ := io.ReadAll(errR)
+	err = cmd.Wait()
+	if len(setupErr) > 0 {
+		return fmt.Errorf("%w: %s", errSandbox, setupErr)
+	}
+	return err
+}
+
+// cloneMount returns a detached, read-only unless writable, copy of the
+// mount tree at m.Source.
+func cloneMount(m mount) (int, error) {
+	fd, err := unix.OpenTree(unix.AT_FDCWD, m.Source, unix.OPEN_TREE_CLONE|unix.OPEN_TREE_CLOEXEC|unix.AT_RECURSIVE)
+	if err != nil {
+		return -1, err
+	}
+	if err := restrictMount(fd, m.Writable); err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+func restrictMount(fd int, writable bool) error {
+	attr := &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_NOSUID}
+	if !writable {
+		attr.Attr_set |= unix.MOUNT_ATTR_RDONLY
+	}
+	return unix.MountSetattr(fd, "", unix.AT_EMPTY_PATH|unix.AT_RECURSIVE, attr)
+}
+
+// sandboxInit runs in the re-executed binary and never returns.
+func sandboxInit(spec string) {
+	// Capabilities and securebits are per thread; exec from the same one.
+	runtime.LockOSThread()
+	errPipe := os.NewFile(errorFD, "sandbox-errors")
+
+	var run sandboxRun
+	err := json.Unmarshal([]byte(spec), &run)
+	if err == nil {
+		err = enterSandbox(&run)
+	}
+	if err == nil {
+		err = unix.Exec(run.Args[0], run.Args, run.Env)
+	}
+	fmt.Fprint(errPipe, err)
+	os.Exit(127)
+}
+
+func enterSandbox(run *sandboxRun) error {
+	// Nothing below may propagate to the host's mounts.
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+
+	trees := make([]int, len(run.Mounts))
+	for i, m := range run.Mounts {
+		if run.MountFDs {
+			trees[i] = firstMountFD + i
+			continue
+		}
+		fd, err := cloneMount(m)
+		if err != nil {
+			return fmt.Errorf("mount %s: %w", m.Source, err)
+		}
+		trees[i] = fd
+	}
+
+	// The new root is built on a tmpfs over /tmp; it stays private to this
+	// mount namespace.
+	root := "/tmp"
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=1m,mode=0755"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+	tmpOpts := fmt.Sprintf("size=%dm,mode=1777", run.TmpSizeMB)
+	if err := os.Mkdir(root+"/tmp", 0o755); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", root+"/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, tmpOpts); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+	for i, m := range run.Mounts {
+		if err := attachMount(trees[i], root+m.Target); err != nil {
+			return fmt.Errorf("mount %s: %w", m.Target, err)
+		}
+	}
+	for path, target := range run.Links {
+		if err := os.MkdirAll(filepath.Dir(root+path), 0o755); err != nil {
+			return err
+		}
+		if err := os.Symlink(target, root+path); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(root+"/proc", 0o555); err != nil {
+		return err
+	}
+	// A fresh /proc is refused inside containers that mask parts of theirs;
+	// the languages run without one.
+	_ = unix.Mount("proc", root+"/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+
+	if err := unix.Chdir(root); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot root: %w", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach old root: %w", err)
+	}
+	if err := unix.Mount("", "/", "", unix.MS_REMOUNT|unix.MS_BIND|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("make root read-only: %w", err)
+	}
+	if err := unix.Chdir(run.Dir); err != nil {
+		return err
+	}
+	for fd := selfFD; fd < firstMountFD+len(run.Mounts); fd++ {
+		unix.CloseOnExec(fd)
+	}
+	for _, fd := range trees {
+		unix.CloseOnExec(fd)
+	}
+
+	if err := setLimits(run.Limits); err != nil {
+		return fmt.Errorf("set limits: %w", err)
+	}
+	return dropPrivileges()
+}
+
+func attachMount(tree int, target string) error {
+	var st unix.Stat_t
+	if err := unix.Fstat(tree, &st); err != nil {
+		return err
+	}
+	if st.Mode&unix.S_IFMT == unix.S_IFDIR {
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		f.Close()
+	}
+	return unix.MoveMount(tree, "", unix.AT_FDCWD, target, unix.MOVE_MOUNT_F_EMPTY_PATH)
+}
+
+func setLimits(l limits) error {
+	set := func(resource int, value uint64, extra uint64) error {
+		if value == 0 {
+			return nil
+		}
+		return syscall.Setrlimit(resource, &syscall.Rlimit{Cur: value, Max: value + extra})
+	}
+	// The CPU hard limit is a second above the soft one, so the program gets
+	// SIGXCPU before SIGKILL.
+	if err := set(unix.RLIMIT_CPU, l.CPUSeconds, 1); err != nil {
+		return err
+	}
+	if err := set(unix.RLIMIT_DATA, l.DataMB<<20, 0); err != nil {
+		return err
+	}
+	if err := set(unix.RLIMIT_FSIZE, l.FileSizeMB<<20, 0); err != nil {
+		return err
+	}
+	if err := set(unix.RLIMIT_NOFILE, l.OpenFiles, 0); err != nil {
+		return err
+	}
+	return set(unix.RLIMIT_NPROC, l.Processes, 0)
+}
+
+// dropPrivileges clears every capability the sandbox root holds in its user
+// namespace and makes sure the exec cannot give any back.
+func dropPrivileges() error {
+	bits := secbitNoroot | secbitNorootLocked | secbitNoSetuidFixup | secbitNoSetuidFixupLocked |
+		secbitKeepCapsLocked | secbitNoCapAmbientRaise | secbitNoCapAmbientRaiseLocked
+	if err := unix.Prctl(unix.PR_SET_SECUREBITS, uintptr(bits), 0, 0, 0); err != nil {
+		return fmt.Errorf("securebits: %w", err)
+	}
+	for c := 0; c <= unix.CAP_LAST_CAP; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && err != unix.EINVAL {
+			return fmt.Errorf("drop bounding set: %w", err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil && err != unix.EINVAL {
+		return fmt.Errorf("clear ambient capabilities: %w", err)
+	}
+	var data [2]unix.CapUserData
+	if err := unix.Capset(&unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}, &data[0]); err != nil {
+		return fmt.Errorf("capset: %w", err)
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("no_new_privs: %w", err)
+	}
+	return nil
+}
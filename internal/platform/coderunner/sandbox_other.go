@@ -0,0 +1,15 @@
+//go:build !linux
+
+package coderunner
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// The sandbox relies on Linux namespaces; refuse to run elsewhere so answers
+// stay pending instead of running unsandboxed.
+func runSandboxed(context.Context, *sandboxRun, int, int, io.Reader, io.Writer, io.Writer) error {
+	return fmt.Errorf("%w: only supported on linux", errSandbox)
+}
@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"encoding/json"
 	"log"
 	"os"
 	"strconv"
@@ -9,6 +10,27 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// AIPrice is the price of a provider in USD per million tokens.
+type AIPrice struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+// AIProviderConfig registers an extra named provider through
+// AI_PROVIDERS_JSON. Kind is "openai" (the default) for OpenAI-compatible
+// endpoints, "anthropic" or "ollama". APIKeyEnv names an environment
+// variable to read the key from, so that keys stay out of the JSON.
+type AIProviderConfig struct {
+	Name         string            `json:"name"`
+	Kind         string            `json:"kind"`
+	BaseURL      string            `json:"base_url"`
+	Model        string            `json:"model"`
+	APIKey       string            `json:"api_key"`
+	APIKeyEnv    string            `json:"api_key_env"`
+	AuthOptional bool              `json:"auth_optional"`
+	Headers      map[string]string `json:"headers"`
+}
+
 type Config struct {
 	DBPath    string
 	DBDriver  string
@@ -17,9 +39,75 @@ type Config struct {
 	GinMode   string
 	Port      string
 
+	// AdminEmails get the admin role when they register or log in.
+	AdminEmails []string
+
+	AccessTokenTTLMin    int
+	RefreshTokenTTLHours int
+
+	// AppBaseURL is the frontend address used in links sent by email.
+	AppBaseURL               string
+	RequireEmailVerification bool
+	EmailVerifyTTLHours      int
+	PasswordResetTTLMin      int
+
+	// MailDriver is "smtp" to send mail; anything else writes it to MailDir,
+	// or to the log when MailDir is empty.
+	MailDriver   string
+	MailDir      string
+	MailFrom     string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// Single sign-on is enabled when OIDCIssuerURL and OIDCClientID are set.
+	// OIDCGroupRoles maps identity provider groups to roles.
+	OIDCIssuerURL            string
+	OIDCClientID             string
+	OIDCClientSecret         string
+	OIDCRedirectURL          string
+	OIDCScopes               []string
+	OIDCGroupsClaim          string
+	OIDCGroupRoles           map[string]string
+	OIDCAutoProvision        bool
+	OIDCTrustUnverifiedEmail bool
+
+	AttemptSweepIntervalSec int
+
+	CodeRunner            string // "local" enables execution of code answers
+	CodeRunTimeoutMs      int
+	CodeCompileTimeoutSec int
+	CodeRunMemoryMB       int
+	CodeRunnerGoBin       string
+	CodeRunnerPythonBin   string
+	CodeRunMaxConcurrent  int
+	CodeRunMaxProcesses   int
+	CodeRunnerUID         int
+	CodeRunnerGID         int
+
 	AIProviderOrder  []string
 	AIHTTPTimeoutSec int
 
+	// Material longer than AIMaterialMaxChars is condensed chunk by chunk
+	// before the plan layer.
+	AIMaterialMaxChars         int
+	AIMaterialChunkChars       int
+	AIMaterialAllowPrivateURLs bool
+
+	// AIPrices maps a provider name to its price in USD per million tokens.
+	// Providers without a price are treated as free.
+	AIPrices map[string]AIPrice
+	// AIMonthlyBudgetUSD caps what one user may spend on paid providers in a
+	// calendar month; zero means no cap.
+	AIMonthlyBudgetUSD float64
+
+	// AIPromptsDir overrides the embedded pipeline prompt templates with
+	// <dir>/<language>/<layer>.tmpl files; AIPromptLanguage is used when a
+	// run asks for no language the templates cover.
+	AIPromptsDir     string
+	AIPromptLanguage string
+
 	OpenAIAPIKey  string
 	OpenAIBaseURL string
 	OpenAIModel   string
@@ -39,6 +127,16 @@ type Config struct {
 	LocalAIAPIKey  string
 	LocalAIBaseURL string
 	LocalAIModel   string
+
+	AnthropicAPIKey    string
+	AnthropicBaseURL   string
+	AnthropicModel     string
+	AnthropicMaxTokens int
+
+	OllamaBaseURL string
+	OllamaModel   string
+
+	AIExtraProviders []AIProviderConfig
 }
 
 func Load() *Config {
@@ -54,9 +152,60 @@ func Load() *Config {
 		GinMode:   getEnv("GIN_MODE", "debug"),
 		Port:      getEnv("PORT", "8080"),
 
-		AIProviderOrder:  splitAndTrim(getEnv("AI_PROVIDER_ORDER", "openai,gemini,deepseek,openrouter,local")),
+		AdminEmails: splitAndTrim(getEnv("ADMIN_EMAILS", "")),
+
+		AccessTokenTTLMin:    getEnvInt("ACCESS_TOKEN_TTL_MIN", 15),
+		RefreshTokenTTLHours: getEnvInt("REFRESH_TOKEN_TTL_HOURS", 720),
+
+		AppBaseURL:               getEnv("APP_BASE_URL", "http://localhost:3000"),
+		RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+		EmailVerifyTTLHours:      getEnvInt("EMAIL_VERIFY_TTL_HOURS", 48),
+		PasswordResetTTLMin:      getEnvInt("PASSWORD_RESET_TTL_MIN", 60),
+
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailDir:      getEnv("MAIL_DIR", ""),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@localhost"),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
+		OIDCIssuerURL:            getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:             getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:         getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:          getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/sso/callback"),
+		OIDCScopes:               strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+		OIDCGroupsClaim:          getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCGroupRoles:           parseGroupRoles(getEnv("OIDC_GROUP_ROLES", "")),
+		OIDCAutoProvision:        getEnvBool("OIDC_AUTO_PROVISION", true),
+		OIDCTrustUnverifiedEmail: getEnvBool("OIDC_TRUST_UNVERIFIED_EMAIL", false),
+
+		AttemptSweepIntervalSec: getEnvInt("ATTEMPT_SWEEP_INTERVAL_SEC", 60),
+
+		CodeRunner:            getEnv("CODE_RUNNER", ""),
+		CodeRunTimeoutMs:      getEnvInt("CODE_RUN_TIMEOUT_MS", 2000),
+		CodeCompileTimeoutSec: getEnvInt("CODE_COMPILE_TIMEOUT_SEC", 30),
+		CodeRunMemoryMB:       getEnvInt("CODE_RUN_MEMORY_MB", 256),
+		CodeRunnerGoBin:       getEnv("CODE_RUNNER_GO_BIN", "go"),
+		CodeRunnerPythonBin:   getEnv("CODE_RUNNER_PYTHON_BIN", "python3"),
+		CodeRunMaxConcurrent:  getEnvInt("CODE_RUN_MAX_CONCURRENT", 0),
+		CodeRunMaxProcesses:   getEnvInt("CODE_RUN_MAX_PROCESSES", 64),
+		CodeRunnerUID:         getEnvInt("CODE_RUNNER_UID", 65534),
+		CodeRunnerGID:         getEnvInt("CODE_RUNNER_GID", 65534),
+
+		AIProviderOrder:  splitAndTrim(getEnv("AI_PROVIDER_ORDER", "openai,gemini,anthropic,deepseek,openrouter,ollama,local")),
 		AIHTTPTimeoutSec: getEnvInt("AI_HTTP_TIMEOUT_SEC", 90),
 
+		AIMaterialMaxChars:         getEnvInt("AI_MATERIAL_MAX_CHARS", 30000),
+		AIMaterialChunkChars:       getEnvInt("AI_MATERIAL_CHUNK_CHARS", 12000),
+		AIMaterialAllowPrivateURLs: getEnvBool("AI_MATERIAL_ALLOW_PRIVATE_URLS", false),
+
+		AIPrices:           parseAIPrices(getEnv("AI_PRICES", "openai:0.15:0.60,gemini:0.10:0.40,anthropic:0.80:4.00,deepseek:0.27:1.10,openrouter:0.15:0.60")),
+		AIMonthlyBudgetUSD: getEnvFloat("AI_MONTHLY_BUDGET_USD", 0),
+
+		AIPromptsDir:     getEnv("AI_PROMPTS_DIR", ""),
+		AIPromptLanguage: getEnv("AI_PROMPT_LANGUAGE", "ru"),
+
 		OpenAIAPIKey:  getEnv("OPENAI_API_KEY", ""),
 		OpenAIBaseURL: getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
 		OpenAIModel:   getEnv("OPENAI_MODEL", "gpt-4o-mini"),
@@ -76,6 +225,16 @@ func Load() *Config {
 		LocalAIAPIKey:  getEnv("LOCAL_AI_API_KEY", ""),
 		LocalAIBaseURL: getEnv("LOCAL_AI_BASE_URL", "http://localhost:11434/v1"),
 		LocalAIModel:   getEnv("LOCAL_AI_MODEL", "llama3.1:8b-instruct"),
+
+		AnthropicAPIKey:    getEnv("ANTHROPIC_API_KEY", ""),
+		AnthropicBaseURL:   getEnv("ANTHROPIC_BASE_URL", "https://api.anthropic.com"),
+		AnthropicModel:     getEnv("ANTHROPIC_MODEL", "claude-3-5-haiku-latest"),
+		AnthropicMaxTokens: getEnvInt("ANTHROPIC_MAX_TOKENS", 8192),
+
+		OllamaBaseURL: getEnv("OLLAMA_BASE_URL", "http://localhost:11434"),
+		OllamaModel:   getEnv("OLLAMA_MODEL", ""),
+
+		AIExtraProviders: parseAIProviders(getEnv("AI_PROVIDERS_JSON", "")),
 	}
 }
 
@@ -98,6 +257,86 @@ func getEnvInt(key string, defaultValue int) int {
 	return value
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// parseAIPrices reads "provider:input:output" entries separated by commas.
+// Malformed entries are skipped.
+func parseAIPrices(input string) map[string]AIPrice {
+	prices := make(map[string]AIPrice)
+	for _, entry := range splitAndTrim(input) {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			log.Printf("Ignoring AI price entry %q", entry)
+			continue
+		}
+		in, errIn := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		out, errOut := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+		if errIn != nil || errOut != nil || in < 0 || out < 0 {
+			log.Printf("Ignoring AI price entry %q", entry)
+			continue
+		}
+		prices[strings.ToLower(strings.TrimSpace(parts[0]))] = AIPrice{InputPerMTok: in, OutputPerMTok: out}
+	}
+	return prices
+}
+
+// parseGroupRoles reads "group=role" entries separated by commas. Malformed
+// entries are skipped.
+func parseGroupRoles(input string) map[string]string {
+	roles := make(map[string]string)
+	for _, entry := range splitAndTrim(input) {
+		group, role, ok := strings.Cut(entry, "=")
+		group, role = strings.TrimSpace(group), strings.ToLower(strings.TrimSpace(role))
+		if !ok || group == "" || role == "" {
+			log.Printf("Ignoring OIDC group role entry %q", entry)
+			continue
+		}
+		roles[group] = role
+	}
+	return roles
+}
+
+// parseAIProviders reads AI_PROVIDERS_JSON, a JSON array of provider
+// entries. Invalid JSON is logged and ignored.
+func parseAIProviders(input string) []AIProviderConfig {
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+	var providers []AIProviderConfig
+	if err := json.Unmarshal([]byte(input), &providers); err != nil {
+		log.Printf("Ignoring AI_PROVIDERS_JSON: %v", err)
+		return nil
+	}
+	for i := range providers {
+		if providers[i].APIKey == "" && providers[i].APIKeyEnv != "" {
+			providers[i].APIKey = os.Getenv(providers[i].APIKeyEnv)
+		}
+	}
+	return providers
+}
+
 func splitAndTrim(input string) []string {
 	if strings.TrimSpace(input) == "" {
 		return nil
@@ -2,8 +2,9 @@ package platform
 
 const (
 	// User roles
-	RoleUser  = "user"
-	RoleAdmin = "admin"
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+	RoleStudent = "student"
 
 	// JWT claims
 	ClaimUserID = "user_id"
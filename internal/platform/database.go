@@ -12,8 +12,10 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
-	"edu-system/internal/auth"
+	"edu-system/internal/platform/airepo"
 	"edu-system/internal/platform/assignmentrepo"
+	"edu-system/internal/platform/authrepo"
+	"edu-system/internal/platform/bankrepo"
 	"edu-system/internal/platform/testattemptrepo"
 	"edu-system/internal/test"
 )
@@ -44,8 +46,11 @@ func InitDB(cfg *Config) *gorm.DB {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	if err := authrepo.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate user tables: %v", err)
+	}
+
 	if err := db.AutoMigrate(
-		&auth.User{},
 		&test.Test{},
 		&test.Question{},
 		&test.Option{},
@@ -61,6 +66,14 @@ func InitDB(cfg *Config) *gorm.DB {
 		log.Fatalf("Failed to migrate assignment tables: %v", err)
 	}
 
+	if err := bankrepo.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate question bank tables: %v", err)
+	}
+
+	if err := airepo.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate ai pipeline tables: %v", err)
+	}
+
 	log.Println("Database initialized successfully")
 	return db
 }
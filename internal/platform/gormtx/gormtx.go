@@ -0,0 +1,24 @@
+// Package gormtx carries a GORM transaction through a context, so
+// repositories of different features can write in one transaction.
+package gormtx
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// With returns ctx carrying tx.
+func With(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// DB returns the transaction ctx carries, or db when it carries none.
+func DB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -2,6 +2,7 @@ package platform
 
 import (
 	"context"
+	"edu-system/internal/platform/gormtx"
 	"edu-system/internal/testAttempt"
 	"fmt"
 	"strings"
@@ -14,10 +15,12 @@ type SystemClock struct{}
 
 func (SystemClock) Now() time.Time { return time.Now().UTC() }
 
+// GormTransactor runs fn in a transaction that repositories reading the
+// context through gormtx.DB take part in.
 type GormTransactor struct{ DB *gorm.DB }
 
 func (t GormTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
-	return t.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error { return fn(ctx) })
+	return t.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error { return fn(gormtx.With(ctx, tx)) })
 }
 
 // AllowGuestsAndOwnerPolicy defines access rules for test attempts.
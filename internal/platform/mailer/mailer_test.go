@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"edu-system/internal/auth"
+)
+
+func TestFileMailerWritesMessages(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{Dir: dir, From: "noreply@example.com"}
+
+	if err := m.Send(auth.MailMessage{To: "a@example.com", Subject: "Подтвердите адрес", Body: "line one\nline two\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(auth.MailMessage{To: "a@example.com\r\nBcc: b@example.com", Subject: "x"}); err == nil {
+		t.Fatal("expected a header injection to be rejected")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one message file, got %v", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(data)
+	if !strings.Contains(msg, "To: a@example.com\r\n") || !strings.Contains(msg, "Subject: =?utf-8?q?") || !strings.HasSuffix(msg, "\r\n\r\nline one\r\nline two\r\n") {
+		t.Fatalf("unexpected message:\n%s", msg)
+	}
+}
+
+func TestLoggedMailRedactsTokens(t *testing.T) {
+	got := string(redactTokens([]byte("Open http://app/reset-password?token=abc%2Bdef\r\nor http://app/x?a=1&token=xyz&b=2\r\n")))
+	if strings.Contains(got, "abc") || strings.Contains(got, "xyz") || !strings.Contains(got, "?token=REDACTED\r\n") || !strings.Contains(got, "&token=REDACTED&b=2") {
+		t.Fatalf("unexpected redaction: %q", got)
+	}
+}
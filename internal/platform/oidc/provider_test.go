@@ -0,0 +1,229 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"edu-system/internal/auth"
+	"edu-system/internal/platform/authrepo"
+	"edu-system/internal/platform/mailer"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// mockIdP is a minimal OpenID provider. authorize stands in for the user
+// signing in at the IdP and returns the code it would redirect back with.
+type mockIdP struct {
+	t      *testing.T
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]any
+
+	mu    sync.Mutex
+	codes map[string]url.Values
+}
+
+func newMockIdP(t *testing.T, claims map[string]any) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{t: t, key: key, claims: claims, codes: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.srv.URL,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func (m *mockIdP) authorize(authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "edu" {
+		m.t.Fatalf("unexpected authorization request %s", authURL)
+	}
+	code := rand.Text()
+	m.mu.Lock()
+	m.codes[code] = q
+	m.mu.Unlock()
+	return code
+}
+
+func (m *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	authReq, ok := m.codes[r.PostFormValue("code")]
+	delete(m.codes, r.PostFormValue("code"))
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != authReq.Get("code_challenge") ||
+		r.PostFormValue("redirect_uri") != authReq.Get("redirect_uri") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   m.srv.URL,
+		"aud":   "edu",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": authReq.Get("nonce"),
+	}
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": signed})
+}
+
+func TestSingleSignOnWithMockIdP(t *testing.T) {
+	idp := newMockIdP(t, map[string]any{
+		"sub":            "idp-42",
+		"email":          "teacher@school.example",
+		"email_verified": true,
+		"name":           "Ada Lovelace",
+		"groups":         []string{"staff", "teachers"},
+	})
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "auth.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := authrepo.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	users := authrepo.NewUserRepository(db)
+	svc := auth.NewAuthService(users, authrepo.NewTokenRepository(db), &mailer.FileMailer{Dir: t.TempDir()}, auth.Settings{
+		JWTSecret: "secret",
+		TTL:       auth.TokenTTL{Access: time.Minute, Refresh: time.Hour},
+		SSO: auth.SSOSettings{
+			Provider: NewProvider(Config{
+				IssuerURL:   idp.srv.URL,
+				ClientID:    "edu",
+				RedirectURL: "http://app/sso/callback",
+			}),
+			GroupRoles:    map[string]string{"teachers": auth.RoleTeacher},
+			AutoProvision: true,
+		},
+	})
+	ctx := context.Background()
+
+	// A state started in another browser is refused.
+	start, err := svc.StartSSO(ctx)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if _, err := svc.CompleteSSO(ctx, &auth.SSOCallbackRequest{Code: idp.authorize(start.AuthorizationURL), State: start.State}); !errors.Is(err, auth.ErrInvalidSSOState) {
+		t.Fatalf("expected a state without its cookie to be rejected, got %v", err)
+	}
+
+	start, err = svc.StartSSO(ctx)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	code := idp.authorize(start.AuthorizationURL)
+	login, err := svc.CompleteSSO(ctx, &auth.SSOCallbackRequest{Code: code, State: start.State, Binding: start.Binding})
+	if err != nil {
+		t.Fatalf("callback: %v", err)
+	}
+	if login.User.Email != "teacher@school.example" || login.User.Role != auth.RoleTeacher || login.User.FirstName != "Ada" || !login.User.EmailVerified {
+		t.Fatalf("unexpected user %+v", login.User)
+	}
+
+	// The state is single-use.
+	if _, err := svc.CompleteSSO(ctx, &auth.SSOCallbackRequest{Code: code, State: start.State, Binding: start.Binding}); !errors.Is(err, auth.ErrInvalidSSOState) {
+		t.Fatalf("expected a replayed state to be rejected, got %v", err)
+	}
+
+	// A second sign-in finds the same account by subject and keeps the role
+	// an administrator changed meanwhile.
+	if err := db.Model(&auth.User{}).Where("id = ?", login.User.ID).Update("role", auth.RoleStudent).Error; err != nil {
+		t.Fatal(err)
+	}
+	start, err = svc.StartSSO(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := svc.CompleteSSO(ctx, &auth.SSOCallbackRequest{Code: idp.authorize(start.AuthorizationURL), State: start.State, Binding: start.Binding})
+	if err != nil {
+		t.Fatalf("second sign-in: %v", err)
+	}
+	if again.User.ID != login.User.ID || again.User.Role != auth.RoleStudent {
+		t.Fatalf("expected user %d to stay a student, got %+v", login.User.ID, again.User)
+	}
+
+	// A provisioned account has no password it knows; a fresh sign-on
+	// confirms the change instead, once.
+	if _, err := svc.ChangePassword(again.User.ID, &auth.ChangePasswordRequest{CurrentPassword: "guess1", NewPassword: "chosen1"}); !errors.Is(err, auth.ErrWrongPassword) {
+		t.Fatalf("expected a guessed password to be rejected, got %v", err)
+	}
+	if _, err := svc.ChangePassword(again.User.ID, &auth.ChangePasswordRequest{ReauthToken: again.ReauthToken, NewPassword: "chosen1"}); err != nil {
+		t.Fatalf("change password after sign-on: %v", err)
+	}
+	if err := svc.DeleteAccount(ctx, again.User.ID, &auth.DeleteAccountRequest{ReauthToken: again.ReauthToken}); !errors.Is(err, auth.ErrReauthRequired) {
+		t.Fatalf("expected a used reauth token to be rejected, got %v", err)
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	idp := newMockIdP(t, map[string]any{"sub": "idp-42"})
+	p := NewProvider(Config{IssuerURL: idp.srv.URL, ClientID: "edu", RedirectURL: "http://app/sso/callback"})
+	ctx := context.Background()
+
+	verifier := "verifier"
+	sum := sha256.Sum256([]byte(verifier))
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce", base64.RawURLEncoding.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Exchange(ctx, idp.authorize(authURL), verifier, "other-nonce"); err == nil {
+		t.Fatal("expected a nonce mismatch")
+	}
+
+	authURL, _ = p.AuthCodeURL(ctx, "state", "nonce", base64.RawURLEncoding.EncodeToString(sum[:]))
+	if _, err := p.Exchange(ctx, idp.authorize(authURL), "wrong-verifier", "nonce"); err == nil {
+		t.Fatal("expected the token endpoint to reject a wrong PKCE verifier")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"time"
 
+	"edu-system/internal/platform/gormtx"
 	domain "edu-system/internal/testAttempt"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -22,6 +23,17 @@ func Migrate(db *gorm.DB) error {
 	return db.AutoMigrate(&attemptRow{}, &answerRow{})
 }
 
+// AnonymiseUser joins the transaction ctx carries, so it can run alongside
+// the deletion of the account.
+func (r *Repo) AnonymiseUser(ctx context.Context, user domain.UserID) error {
+	return gormtx.DB(ctx, r.db).Model(&attemptRow{}).Where("user_id = ?", uint64(user)).Updates(map[string]any{
+		"guest_name":              nil,
+		"participant_fields_json": nil,
+		"client_ip":               "",
+		"client_fingerprint":      "",
+	}).Error
+}
+
 func (r *Repo) Create(ctx context.Context, a *domain.Attempt) (domain.AttemptID, error) {
 	row, err := toRow(a)
 	if err != nil {
@@ -53,6 +65,51 @@ func (r *Repo) GetActiveByUserAndAssignment(ctx context.Context, user domain.Use
 	return toDomain(&row)
 }
 
+func (r *Repo) ListActiveTimed(ctx context.Context) ([]*domain.Attempt, error) {
+	var rows []attemptRow
+	err := r.db.WithContext(ctx).
+		Preload("Answers").
+		Where("status = ?", string(domain.StatusActive)).
+		Where("duration_sec > 0 OR question_opened_at IS NOT NULL").
+		Order("started_at").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.Attempt, 0, len(rows))
+	for i := range rows {
+		a, err := toDomain(&rows[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (r *Repo) ListClosedUngraded(ctx context.Context) ([]*domain.Attempt, error) {
+	var rows []attemptRow
+	err := r.db.WithContext(ctx).
+		Preload("Answers").
+		Where("status IN ?", []string{string(domain.StatusSubmitted), string(domain.StatusExpired)}).
+		Where("pending_score > 0").
+		Where("EXISTS (SELECT 1 FROM selected_answers sa WHERE sa.attempt_id = test_attempts.id AND sa.score IS NULL AND sa.code_results IS NULL)").
+		Order("started_at").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.Attempt, 0, len(rows))
+	for i := range rows {
+		a, err := toDomain(&rows[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
 func (r *Repo) SaveAnswer(ctx context.Context, a *domain.Attempt, answered domain.QuestionID) error {
 	row, err := toRow(a)
 	if err != nil {
@@ -78,16 +135,27 @@ func (r *Repo) SaveAnswer(ctx context.Context, a *domain.Attempt, answered domai
 				"status":             row.Status,
 				"expired_at":         row.ExpiredAt,
 				"question_opened_at": row.QuestionOpenedAt,
+				"section_started_at": row.SectionStartedAt,
 			}).Error; err != nil {
 			return err
 		}
 		return tx.Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "attempt_id"}, {Name: "question_id"}},
-			DoUpdates: clause.AssignmentColumns([]string{"payload", "is_correct", "score", "updated_at"}),
+			DoUpdates: clause.AssignmentColumns([]string{"payload", "is_correct", "score", "code_results", "suggestion", "graded_by", "graded_at", "grade_decision", "updated_at"}),
 		}).Create(&ar).Error
 	})
 }
 
+func (r *Repo) SaveSuggestion(ctx context.Context, id domain.AttemptID, question domain.QuestionID, suggestion *domain.GradeSuggestion) error {
+	raw, err := json.Marshal(suggestion)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Model(&answerRow{}).
+		Where("attempt_id = ? AND question_id = ?", string(id), string(question)).
+		Update("suggestion", json.RawMessage(raw)).Error
+}
+
 func (r *Repo) SaveProgress(ctx context.Context, a *domain.Attempt) error {
 	row, err := toRow(a)
 	if err != nil {
@@ -101,6 +169,7 @@ func (r *Repo) SaveProgress(ctx context.Context, a *domain.Attempt) error {
 			"status":             row.Status,
 			"expired_at":         row.ExpiredAt,
 			"question_opened_at": row.QuestionOpenedAt,
+			"section_started_at": row.SectionStartedAt,
 		}).Error
 }
 
@@ -109,16 +178,40 @@ func (r *Repo) Submit(ctx context.Context, a *domain.Attempt) error {
 	if err != nil {
 		return err
 	}
-	return r.db.WithContext(ctx).Model(&attemptRow{}).
-		Where("id = ?", row.ID).
-		Updates(map[string]any{
-			"status":        row.Status,
-			"submitted_at":  row.SubmittedAt,
-			"score":         row.Score,
-			"max_score":     row.MaxScore,
-			"pending_score": row.PendingScore,
-			"version":       row.Version,
-		}).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&attemptRow{}).
+			Where("id = ?", row.ID).
+			Updates(map[string]any{
+				"status":        row.Status,
+				"submitted_at":  row.SubmittedAt,
+				"score":         row.Score,
+				"max_score":     row.MaxScore,
+				"pending_score": row.PendingScore,
+				"version":       row.Version,
+			}).Error; err != nil {
+			return err
+		}
+		return saveCodeResults(tx, row)
+	})
+}
+
+func (r *Repo) SaveCodeResults(ctx context.Context, a *domain.Attempt) error {
+	row, err := toRow(a)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&attemptRow{}).
+			Where("id = ?", row.ID).
+			Updates(map[string]any{
+				"score":         row.Score,
+				"max_score":     row.MaxScore,
+				"pending_score": row.PendingScore,
+			}).Error; err != nil {
+			return err
+		}
+		return saveCodeResults(tx, row)
+	})
 }
 
 func (r *Repo) Cancel(ctx context.Context, a *domain.Attempt) error {
@@ -134,6 +227,51 @@ func (r *Repo) Cancel(ctx context.Context, a *domain.Attempt) error {
 		}).Error
 }
 
+func (r *Repo) Expire(ctx context.Context, a *domain.Attempt) (bool, error) {
+	row, err := toRow(a)
+	if err != nil {
+		return false, err
+	}
+	var expired bool
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Expire bumped the version once; a different stored version means
+		// an answer was saved after the attempt was loaded and is missing
+		// from the score.
+		res := tx.Model(&attemptRow{}).
+			Where("id = ? AND status = ? AND version = ?", row.ID, string(domain.StatusActive), row.Version-1).
+			Updates(map[string]any{
+				"status":             row.Status,
+				"expired_at":         row.ExpiredAt,
+				"score":              row.Score,
+				"max_score":          row.MaxScore,
+				"pending_score":      row.PendingScore,
+				"version":            row.Version,
+				"question_opened_at": row.QuestionOpenedAt,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		expired = true
+		return saveCodeResults(tx, row)
+	})
+	return expired, err
+}
+
+// saveCodeResults stores test-case results produced while closing the attempt.
+func saveCodeResults(tx *gorm.DB, row *attemptRow) error {
+	for _, ar := range row.Answers {
+		if len(ar.CodeResults) == 0 {
+			continue
+		}
+		if err := tx.Model(&answerRow{}).
+			Where("attempt_id = ? AND question_id = ?", ar.AttemptID, ar.QuestionID).
+			Update("code_results", ar.CodeResults).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *Repo) ListSummariesByAssignments(ctx context.Context, assignments []domain.AssignmentID) ([]domain.AttemptSummary, error) {
 	if len(assignments) == 0 {
 		return []domain.AttemptSummary{}, nil
@@ -258,6 +396,8 @@ type attemptRow struct {
 	PolicyJSON        json.RawMessage `gorm:"type:json;not null;default:'{}'"`
 	OrderJSON         json.RawMessage `gorm:"type:json;not null"`
 	Cursor            int             `gorm:"not null;default:0"`
+	SectionsJSON      json.RawMessage `gorm:"type:json"`
+	SectionStartedAt  *time.Time
 
 	Answers []answerRow `gorm:"foreignKey:AttemptID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
@@ -273,6 +413,13 @@ type answerRow struct {
 	Payload    json.RawMessage `gorm:"type:json;not null"`
 	IsCorrect  *bool           `gorm:"index"`
 	Score      *float64
+	// CodeResults keeps per-test-case results of executed code answers.
+	CodeResults json.RawMessage `gorm:"type:json"`
+	// Suggestion holds the AI grading proposal, kept apart from Score.
+	Suggestion    json.RawMessage `gorm:"type:json"`
+	GradedBy      *uint64
+	GradedAt      *time.Time
+	GradeDecision string `gorm:"type:varchar(16)"`
 }
 
 func (answerRow) TableName() string { return "selected_answers" }
@@ -292,18 +439,47 @@ func toRow(a *domain.Attempt) (*attemptRow, error) {
 		return nil, err
 	}
 
+	var sectionsJSON json.RawMessage
+	if sections := a.Sections(); len(sections) > 0 {
+		if sectionsJSON, err = json.Marshal(sections); err != nil {
+			return nil, err
+		}
+	}
+
 	arows := make([]answerRow, 0, len(a.Answers()))
 	for qid, ans := range a.Answers() {
 		payload, err := payloadToJSON(ans.Payload)
 		if err != nil {
 			return nil, err
 		}
+		var codeResults json.RawMessage
+		if len(ans.CodeResults) > 0 {
+			if codeResults, err = json.Marshal(ans.CodeResults); err != nil {
+				return nil, err
+			}
+		}
+		var suggestion json.RawMessage
+		if ans.Suggestion != nil {
+			if suggestion, err = json.Marshal(ans.Suggestion); err != nil {
+				return nil, err
+			}
+		}
+		var gradedBy *uint64
+		if ans.GradedBy != nil {
+			id := uint64(*ans.GradedBy)
+			gradedBy = &id
+		}
 		arows = append(arows, answerRow{
-			AttemptID:  string(a.ID()),
-			QuestionID: string(qid),
-			Payload:    payload,
-			IsCorrect:  ans.IsCorrect,
-			Score:      ans.Score,
+			AttemptID:     string(a.ID()),
+			QuestionID:    string(qid),
+			Payload:       payload,
+			IsCorrect:     ans.IsCorrect,
+			Score:         ans.Score,
+			CodeResults:   codeResults,
+			Suggestion:    suggestion,
+			GradedBy:      gradedBy,
+			GradedAt:      ans.GradedAt,
+			GradeDecision: ans.Decision,
 		})
 	}
 
@@ -332,6 +508,11 @@ func toRow(a *domain.Attempt) (*attemptRow, error) {
 		t := ts.UTC()
 		openedAt = &t
 	}
+	var sectionStartedAt *time.Time
+	if ts := a.SectionStartedAt(); ts != nil {
+		t := ts.UTC()
+		sectionStartedAt = &t
+	}
 
 	return &attemptRow{
 		ID:                    string(a.ID()),
@@ -354,10 +535,12 @@ func toRow(a *domain.Attempt) (*attemptRow, error) {
 		ClientFingerprint:     a.ClientFingerprint(),
 		QuestionOpenedAt:      openedAt,
 
-		PolicyJSON: policyJSON,
-		OrderJSON:  orderJSON,
-		Cursor:     a.Cursor(),
-		Answers:    arows,
+		PolicyJSON:       policyJSON,
+		OrderJSON:        orderJSON,
+		Cursor:           a.Cursor(),
+		SectionsJSON:     sectionsJSON,
+		SectionStartedAt: sectionStartedAt,
+		Answers:          arows,
 	}, nil
 }
 
@@ -385,18 +568,48 @@ func toDomain(r *attemptRow) (*domain.Attempt, error) {
 		plan[i] = domain.QuestionID(s)
 	}
 
+	var sections []domain.Section
+	if len(r.SectionsJSON) > 0 {
+		if err := json.Unmarshal(r.SectionsJSON, &sections); err != nil {
+			return nil, err
+		}
+	}
+
 	answers := make(map[domain.QuestionID]domain.Answer, len(r.Answers))
 	for _, row := range r.Answers {
 		payload, err := jsonToPayload([]byte(row.Payload))
 		if err != nil {
 			return nil, err
 		}
+		var codeResults []domain.CodeCaseResult
+		if len(row.CodeResults) > 0 {
+			if err := json.Unmarshal(row.CodeResults, &codeResults); err != nil {
+				return nil, err
+			}
+		}
+		var suggestion *domain.GradeSuggestion
+		if len(row.Suggestion) > 0 && string(row.Suggestion) != "null" {
+			suggestion = &domain.GradeSuggestion{}
+			if err := json.Unmarshal(row.Suggestion, suggestion); err != nil {
+				return nil, err
+			}
+		}
+		var gradedBy *domain.UserID
+		if row.GradedBy != nil {
+			id := domain.UserID(*row.GradedBy)
+			gradedBy = &id
+		}
 		qid := domain.QuestionID(row.QuestionID)
 		answers[qid] = domain.Answer{
-			QuestionID: qid,
-			Payload:    payload,
-			IsCorrect:  row.IsCorrect,
-			Score:      row.Score,
+			QuestionID:  qid,
+			Payload:     payload,
+			IsCorrect:   row.IsCorrect,
+			Score:       row.Score,
+			CodeResults: codeResults,
+			Suggestion:  suggestion,
+			GradedBy:    gradedBy,
+			GradedAt:    row.GradedAt,
+			Decision:    row.GradeDecision,
 		}
 	}
 
@@ -428,6 +641,8 @@ func toDomain(r *attemptRow) (*domain.Attempt, error) {
 		r.ClientIP,
 		r.ClientFingerprint,
 		r.QuestionOpenedAt,
+		sections,
+		r.SectionStartedAt,
 	)
 	if err != nil {
 		return nil, err
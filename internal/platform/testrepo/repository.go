@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -52,6 +53,17 @@ func (r *testRepository) Delete(id string) error {
 	return r.db.Delete(&test.Test{}, "id = ?", id).Error
 }
 
+func (r *testRepository) GetQuestion(id string) (*test.Question, error) {
+	var q test.Question
+	if err := r.db.Preload("Options").First(&q, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, test.ErrQuestionNotFound
+		}
+		return nil, err
+	}
+	return &q, nil
+}
+
 func (r *testRepository) GetTestSettings(ctx context.Context, testID string) (durationSec int, availableFrom, availableUntil *time.Time, allowGuests bool, policy ta.AttemptPolicy, err error) {
 	var t test.Test
 	if err = r.db.WithContext(ctx).First(&t, "id = ?", testID).Error; err != nil {
@@ -82,6 +94,13 @@ func (r *testRepository) ListVisibleQuestions(ctx context.Context, testID string
 				ImageURL:   o.ImageURL,
 			})
 		}
+		var examples []ta.CodeExample
+		if normalizeQuestionType(q.Type) == "code" && len(q.CorrectJSON) > 0 {
+			var key ta.CodeAnswerKey
+			if err := json.Unmarshal(q.CorrectJSON, &key); err == nil {
+				examples = key.CodeExamples()
+			}
+		}
 		out = append(out, ta.VisibleQuestion{
 			ID:           q.ID,
 			Type:         normalizeQuestionType(q.Type),
@@ -89,6 +108,7 @@ func (r *testRepository) ListVisibleQuestions(ctx context.Context, testID string
 			ImageURL:     q.ImageURL,
 			Weight:       normalizeWeight(q.Weight),
 			Options:      opts,
+			Examples:     examples,
 		})
 	}
 	return out, nil
@@ -97,6 +117,7 @@ func (r *testRepository) ListVisibleQuestions(ctx context.Context, testID string
 func (r *testRepository) ListQuestionsForScoring(ctx context.Context, testID string) ([]ta.QuestionForScoring, error) {
 	var qs []test.Question
 	if err := r.db.WithContext(ctx).
+		Preload("Options").
 		Where("test_id = ?", testID).
 		Find(&qs).Error; err != nil {
 		return nil, err
@@ -112,7 +133,7 @@ func (r *testRepository) ListQuestionsForScoring(ctx context.Context, testID str
 		} else {
 			correct, _ = json.Marshal(map[string]any{"selected": []int{q.CorrectOption}})
 		}
-		if qType == "text" || qType == "code" {
+		if (qType == "text" || qType == "code") && len(q.CorrectJSON) == 0 {
 			correct = nil
 		}
 		out = append(out, ta.QuestionForScoring{
@@ -120,6 +141,7 @@ func (r *testRepository) ListQuestionsForScoring(ctx context.Context, testID str
 			Type:        qType,
 			Weight:      weight,
 			CorrectJSON: correct,
+			Solution:    q.Solution,
 		})
 	}
 	return out, nil
@@ -37,8 +37,9 @@ func (d GormUserDirectory) Lookup(ctx context.Context, ids []testAttempt.UserID)
 		uintIDs[i] = uint(id)
 	}
 
+	// Deleted accounts are kept anonymised, so their attempts still resolve.
 	var users []auth.User
-	if err := d.DB.WithContext(ctx).Where("id IN ?", uintIDs).Find(&users).Error; err != nil {
+	if err := d.DB.WithContext(ctx).Unscoped().Where("id IN ?", uintIDs).Find(&users).Error; err != nil {
 		return nil, err
 	}
 
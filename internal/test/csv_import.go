@@ -10,17 +10,20 @@ import (
 	"strings"
 
 	"edu-system/internal/test/dto"
+	ta "edu-system/internal/testAttempt"
 )
 
 func csvTemplateContent() string {
 	return strings.TrimSpace(`
-title,description,question_text,question_type,options,correct_answers,weight
-Sample test,Quick diagnostic quiz,What is 2+2?,single,"4|3|5|2","1",1
-,,Select prime numbers,multi,"2|3|4|5","1|2|4",1
-,,Explain the purpose of polymorphism (open answer),text,"","",1
-,,Write a function that reverses a string,code,"","",1
-,,What is the derivative of $x^2$?,single,"2x|x^2|2|x","1",1
-,,"Evaluate $\\int x^2 dx$",single,"\\frac{x^3}{3}+C|2x+C|x^3+C|\\frac{2}{3}x^3+C","1",1
+title,description,question_text,question_type,options,correct_answers,weight,scoring,match_mode,tolerance,manual_review,section
+Sample test,Quick diagnostic quiz,What is 2+2?,single,"4|3|5|2","1",1,,,,,
+,,Select prime numbers,multi,"2|3|4|5","1|2|4",1,proportional,,,,
+,,Explain the purpose of polymorphism (open answer),text,"","",1,,,,,
+,,Name the capital of France,text,"","Paris",1,,normalized,,,
+,,What is the value of pi to two decimals?,text,"","3.14",1,,numeric,0.005,true,
+,,Write a function that reverses a string,code,"","",1,,,,,
+,,What is the derivative of $x^2$?,single,"2x|x^2|2|x","1",1,,,,,
+,,"Evaluate $\\int x^2 dx$",single,"\\frac{x^3}{3}+C|2x+C|x^3+C|\\frac{2}{3}x^3+C","1",1,,,,,
 `) + "\n"
 }
 
@@ -53,6 +56,8 @@ func parseCSVTemplate(reader io.Reader) (*dto.CreateTestRequest, error) {
 	var title string
 	var description string
 	questions := make([]dto.Question, 0)
+	var sections []dto.Section
+	seenSections := make(map[string]struct{})
 
 	for rowIdx, row := range rows[1:] {
 		rowNumber := rowIdx + 2 // account for header line
@@ -94,9 +99,12 @@ func parseCSVTemplate(reader io.Reader) (*dto.CreateTestRequest, error) {
 			})
 		}
 
-		correctValues, err := parseCorrectIndexes(valueAt(row, header, "correct_answers"), len(answers))
-		if err != nil {
-			return nil, fmt.Errorf("row %d: %w", rowNumber, err)
+		var correctValues []int
+		if qType == "single" || qType == "multi" {
+			correctValues, err = parseCorrectIndexes(valueAt(row, header, "correct_answers"), len(answers))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", rowNumber, err)
+			}
 		}
 
 		question := dto.Question{
@@ -111,6 +119,13 @@ func parseCSVTemplate(reader io.Reader) (*dto.CreateTestRequest, error) {
 			CorrectOptions: correctValues,
 			Type:           qType,
 			Weight:         weight,
+			Section:        strings.TrimSpace(valueAt(row, header, "section")),
+		}
+		if name := question.Section; name != "" {
+			if _, ok := seenSections[name]; !ok {
+				seenSections[name] = struct{}{}
+				sections = append(sections, dto.Section{Name: name})
+			}
 		}
 
 		switch qType {
@@ -129,7 +144,17 @@ func parseCSVTemplate(reader io.Reader) (*dto.CreateTestRequest, error) {
 			if len(correctValues) == 0 {
 				return nil, fmt.Errorf("row %d: at least one correct answer is required for multi choice questions", rowNumber)
 			}
-		default: // text or code
+			scoring, err := ta.ParseMultiScoringRule(valueAt(row, header, "scoring"))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", rowNumber, err)
+			}
+			question.Scoring = string(scoring)
+		case "text":
+			question.Options = []dto.Answer{}
+			if err := applyCSVTextAnswers(&question, row, header); err != nil {
+				return nil, fmt.Errorf("row %d: %w", rowNumber, err)
+			}
+		default: // code
 			question.Options = []dto.Answer{}
 			question.CorrectOption = 0
 			question.CorrectOptions = nil
@@ -151,9 +176,46 @@ func parseCSVTemplate(reader io.Reader) (*dto.CreateTestRequest, error) {
 		Title:       title,
 		Description: description,
 		Questions:   questions,
+		Sections:    sections,
 	}, nil
 }
 
+// applyCSVTextAnswers reads accepted answers of a text question from
+// correct_answers plus the optional match_mode, tolerance and manual_review
+// columns. Regex patterns are kept whole since they may contain '|'.
+func applyCSVTextAnswers(q *dto.Question, row []string, header map[string]int) error {
+	mode, err := ta.ParseTextMatchMode(valueAt(row, header, "match_mode"))
+	if err != nil {
+		return err
+	}
+	raw := strings.TrimSpace(valueAt(row, header, "correct_answers"))
+	if raw == "" {
+		return nil
+	}
+	if mode == ta.TextMatchRegex {
+		q.AcceptedAnswers = []string{raw}
+	} else {
+		q.AcceptedAnswers = splitList(raw)
+	}
+	q.MatchMode = string(mode)
+
+	if tol := strings.TrimSpace(valueAt(row, header, "tolerance")); tol != "" {
+		val, err := strconv.ParseFloat(tol, 64)
+		if err != nil || val < 0 {
+			return fmt.Errorf("tolerance %q must be a non-negative number", tol)
+		}
+		q.Tolerance = val
+	}
+	if review := strings.TrimSpace(valueAt(row, header, "manual_review")); review != "" {
+		val, err := strconv.ParseBool(review)
+		if err != nil {
+			return fmt.Errorf("manual_review %q must be true or false", review)
+		}
+		q.ManualReview = val
+	}
+	return nil
+}
+
 func valueAt(row []string, header map[string]int, key string) string {
 	idx, ok := header[key]
 	if !ok || idx >= len(row) {
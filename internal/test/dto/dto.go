@@ -6,7 +6,26 @@ type CreateTestRequest struct {
 	Author      string     `json:"author"`
 	Title       string     `json:"title" binding:"required"`
 	Description string     `json:"description" binding:"required"`
-	Questions   []Question `json:"questions" binding:"required,min=1"`
+	Questions   []Question `json:"questions"`
+	DrawRules   []DrawRule `json:"draw_rules,omitempty"`
+	Sections    []Section  `json:"sections,omitempty"`
+}
+
+// Section splits a test into parts with their own draw count, shuffle flag and
+// time limit. Questions and draw rules join a section by name.
+type Section struct {
+	Name         string `json:"name" binding:"required"`
+	DrawCount    int    `json:"draw_count,omitempty" binding:"min=0"`
+	Shuffle      bool   `json:"shuffle,omitempty"`
+	TimeLimitSec int    `json:"time_limit_sec,omitempty" binding:"min=0"`
+}
+
+// DrawRule draws Count random questions with the tag from the author's question bank.
+type DrawRule struct {
+	Tag        string `json:"tag" binding:"required"`
+	Difficulty string `json:"difficulty,omitempty"`
+	Count      int    `json:"count" binding:"required,min=1"`
+	Section    string `json:"section,omitempty"`
 }
 
 type ImportTestResponse struct {
@@ -26,6 +45,8 @@ type GetTestResponse struct {
 	AvailableUntil *time.Time         `json:"available_until,omitempty"`
 	AttemptPolicy  AttemptPolicyView  `json:"attempt_policy"`
 	Questions      []QuestionResponse `json:"questions"`
+	DrawRules      []DrawRule         `json:"draw_rules,omitempty"`
+	Sections       []Section          `json:"sections,omitempty"`
 }
 
 type UpdateTestRequest struct {
@@ -33,6 +54,8 @@ type UpdateTestRequest struct {
 	Title       string              `json:"title,omitempty"`
 	Description string              `json:"description,omitempty"`
 	Questions   []Question          `json:"questions,omitempty"`
+	DrawRules   []DrawRule          `json:"draw_rules,omitempty"` // an empty list removes all rules
+	Sections    []Section           `json:"sections,omitempty"`   // an empty list removes all sections
 	Settings    *UpdateTestSettings `json:"settings,omitempty"`
 }
 
@@ -92,10 +115,29 @@ type Question struct {
 	Type           string   `json:"type,omitempty"`   // single | multi | text | code
 	Weight         float64  `json:"weight,omitempty"` // default 1
 	ImageURL       string   `json:"image_url,omitempty"`
+	Scoring        string   `json:"scoring,omitempty"`  // multi only: all_or_nothing | proportional | proportional_penalty
+	Section        string   `json:"section,omitempty"`  // name of a test section, required when the test has sections
+	Solution       string   `json:"solution,omitempty"` // worked explanation of the correct answer
+
+	// Auto-grading of text questions.
+	AcceptedAnswers []string `json:"accepted_answers,omitempty"`
+	MatchMode       string   `json:"match_mode,omitempty"` // exact | normalized | regex | numeric
+	Tolerance       float64  `json:"tolerance,omitempty"`  // numeric mode only
+	ManualReview    bool     `json:"manual_review,omitempty"`
+
+	// Test cases of code questions.
+	TestCases []CodeTestCase `json:"test_cases,omitempty"`
+}
+
+type CodeTestCase struct {
+	Stdin          string `json:"stdin"`
+	ExpectedStdout string `json:"expected_stdout"`
+	Hidden         bool   `json:"hidden,omitempty"`
 }
 
 type QuestionResponse struct {
 	ID             string           `json:"id"`
+	TestID         string           `json:"test_id,omitempty"` // set when the question is fetched on its own
 	QuestionText   string           `json:"question_text"`
 	Options        []OptionResponse `json:"options"`
 	CorrectOption  int              `json:"correct_option"`
@@ -103,6 +145,16 @@ type QuestionResponse struct {
 	Type           string           `json:"type"`
 	Weight         float64          `json:"weight"`
 	ImageURL       string           `json:"image_url,omitempty"`
+	Scoring        string           `json:"scoring,omitempty"`
+	Section        string           `json:"section,omitempty"`
+	Solution       string           `json:"solution,omitempty"`
+
+	AcceptedAnswers []string `json:"accepted_answers,omitempty"`
+	MatchMode       string   `json:"match_mode,omitempty"`
+	Tolerance       float64  `json:"tolerance,omitempty"`
+	ManualReview    bool     `json:"manual_review,omitempty"`
+
+	TestCases []CodeTestCase `json:"test_cases,omitempty"`
 }
 
 type Answer struct {
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +21,8 @@ type Question struct {
 	CorrectJSON   []byte         `json:"correct_json" gorm:"type:json"`
 	Weight        float64        `json:"weight" gorm:"not null;default:1"`
 	ImageURL      string         `json:"image_url,omitempty" gorm:"type:varchar(255)"`
+	Section       string         `json:"section,omitempty" gorm:"type:varchar(64)"`
+	Solution      string         `json:"solution,omitempty" gorm:"type:text"`
 }
 
 func (q *Question) BeforeCreate(tx *gorm.DB) error {
@@ -62,6 +65,49 @@ type Test struct {
 	AvailableFrom  *time.Time `json:"available_from" gorm:"index"`
 	AvailableUntil *time.Time `json:"available_until" gorm:"index"`
 	AttemptPolicy  []byte     `json:"attempt_policy" gorm:"type:json;not null;default:'{}'"`
+	DrawRules      []byte     `json:"draw_rules" gorm:"type:json"` // []DrawRule resolved against the author's question bank
+	Sections       []byte     `json:"sections" gorm:"type:json"`   // []Section, in the order they are taken
+}
+
+// Section groups questions that are drawn and timed together. A DrawCount of
+// zero asks every question of the section; TimeLimitSec of zero leaves the
+// section bounded only by the attempt deadline.
+type Section struct {
+	Name         string `json:"name"`
+	DrawCount    int    `json:"draw_count,omitempty"`
+	Shuffle      bool   `json:"shuffle,omitempty"`
+	TimeLimitSec int    `json:"time_limit_sec,omitempty"`
+}
+
+func (t *Test) DecodeSections() ([]Section, error) {
+	if len(t.Sections) == 0 {
+		return nil, nil
+	}
+	var sections []Section
+	if err := json.Unmarshal(t.Sections, &sections); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// DrawRule asks for Count random bank questions tagged Tag (and of the given
+// difficulty, when set) in every attempt.
+type DrawRule struct {
+	Tag        string `json:"tag"`
+	Difficulty string `json:"difficulty,omitempty"`
+	Count      int    `json:"count"`
+	Section    string `json:"section,omitempty"`
+}
+
+func (t *Test) DecodeDrawRules() ([]DrawRule, error) {
+	if len(t.DrawRules) == 0 {
+		return nil, nil
+	}
+	var rules []DrawRule
+	if err := json.Unmarshal(t.DrawRules, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
 }
 
 func (t *Test) BeforeCreate(tx *gorm.DB) error {
@@ -13,6 +13,8 @@ type TestRepository interface {
 	GetByOwner(ownerID uint) ([]*Test, error)
 	Update(test *Test) error
 	Delete(id string) error
+	// GetQuestion returns ErrQuestionNotFound for unknown ids.
+	GetQuestion(id string) (*Question, error)
 
 	//TODO: consider moving these methods to a separate interface
 	GetTestSettings(ctx context.Context, testID string) (durationSec int, availableFrom, availableUntil *time.Time, allowGuests bool, policy testAttempt.AttemptPolicy, err error)
@@ -4,10 +4,11 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// RegisterRoutes registers all test-related routes
-func RegisterRoutes(v1 gin.IRouter, h *TestHandler, jwtAuth gin.HandlerFunc) {
+// RegisterRoutes registers all test-related routes; requireAuthor limits
+// them to the roles that may author tests.
+func RegisterRoutes(v1 gin.IRouter, h *TestHandler, jwtAuth, requireAuthor gin.HandlerFunc) {
 	protected := v1.Group("/tests")
-	protected.Use(jwtAuth)
+	protected.Use(jwtAuth, requireAuthor)
 	{
 		protected.GET("", h.GetAllTests)
 		protected.GET("/template/csv", h.DownloadCSVTemplate)
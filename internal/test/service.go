@@ -6,13 +6,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"edu-system/internal/test/dto"
 	ta "edu-system/internal/testAttempt"
 )
 
-var ErrForbidden = errors.New("forbidden")
+var (
+	ErrForbidden        = errors.New("forbidden")
+	ErrQuestionNotFound = errors.New("question not found")
+)
 
 type TestService interface {
 	CreateTest(ownerID uint, req *dto.CreateTestRequest) (string, error)
@@ -21,6 +25,7 @@ type TestService interface {
 	ListTests(ownerID uint) ([]*dto.GetTestResponse, error)
 	UpdateTest(ownerID uint, testID string, req *dto.UpdateTestRequest) error
 	DeleteTest(ownerID uint, testID string) error
+	GetQuestion(ownerID uint, questionID string) (*dto.QuestionResponse, error)
 }
 
 type testService struct {
@@ -34,7 +39,26 @@ func NewTestService(testRepo TestRepository) TestService {
 }
 
 func (t testService) CreateTest(ownerID uint, req *dto.CreateTestRequest) (string, error) {
+	if len(req.Questions) == 0 && len(req.DrawRules) == 0 {
+		return "", errors.New("a test needs at least one question or draw rule")
+	}
+	if err := validateQuestions(req.Questions); err != nil {
+		return "", err
+	}
+	rules, err := encodeDrawRules(req.DrawRules)
+	if err != nil {
+		return "", err
+	}
+	sections, err := encodeSections(req.Sections)
+	if err != nil {
+		return "", err
+	}
 	test := buildTestModel(ownerID, req)
+	test.DrawRules = rules
+	test.Sections = sections
+	if err := checkSectionRefs(test); err != nil {
+		return "", err
+	}
 	if err := t.testRepo.Create(test); err != nil {
 		return "", err
 	}
@@ -76,6 +100,8 @@ func buildTestModel(ownerID uint, req *dto.CreateTestRequest) *Test {
 			Options:      make([]Option, 0),
 			Type:         normalizeQuestionType(q.Type),
 			Weight:       normalizeWeight(q.Weight),
+			Section:      strings.TrimSpace(q.Section),
+			Solution:     strings.TrimSpace(q.Solution),
 		}
 		setCorrectAnswers(&question, q)
 
@@ -117,6 +143,8 @@ func (t testService) GetTest(ownerID uint, testID string) (*dto.GetTestResponse,
 		AvailableUntil: cloneTimePtr(test.AvailableUntil),
 		AttemptPolicy:  attemptPolicyToDTO(policy),
 		Questions:      make([]dto.QuestionResponse, 0),
+		DrawRules:      drawRulesToDTO(test),
+		Sections:       sectionsToDTO(test),
 	}
 
 	for _, q := range test.Questions {
@@ -128,9 +156,13 @@ func (t testService) GetTest(ownerID uint, testID string) (*dto.GetTestResponse,
 			Type:           normalizeQuestionType(q.Type),
 			Weight:         normalizeWeight(q.Weight),
 			ImageURL:       q.ImageURL,
+			Section:        q.Section,
+			Solution:       q.Solution,
 			Options:        make([]dto.OptionResponse, 0),
 		}
 
+		setAnswerKeyResponse(&questionResponse, q)
+
 		for _, opt := range q.Options {
 			questionResponse.Options = append(questionResponse.Options, dto.OptionResponse{
 				ID:         opt.ID,
@@ -162,6 +194,9 @@ func (t testService) UpdateTest(ownerID uint, testID string, req *dto.UpdateTest
 	}
 
 	if req.Questions != nil {
+		if err := validateQuestions(req.Questions); err != nil {
+			return err
+		}
 		test.Questions = make([]Question, 0, len(req.Questions))
 
 		for _, q := range req.Questions {
@@ -170,6 +205,8 @@ func (t testService) UpdateTest(ownerID uint, testID string, req *dto.UpdateTest
 				Options:      make([]Option, 0, len(q.Options)),
 				Type:         normalizeQuestionType(q.Type),
 				Weight:       normalizeWeight(q.Weight),
+				Section:      strings.TrimSpace(q.Section),
+				Solution:     strings.TrimSpace(q.Solution),
 			}
 			setCorrectAnswers(&question, q)
 
@@ -184,6 +221,25 @@ func (t testService) UpdateTest(ownerID uint, testID string, req *dto.UpdateTest
 		}
 	}
 
+	if req.DrawRules != nil {
+		rules, err := encodeDrawRules(req.DrawRules)
+		if err != nil {
+			return err
+		}
+		test.DrawRules = rules
+	}
+
+	if req.Sections != nil {
+		sections, err := encodeSections(req.Sections)
+		if err != nil {
+			return err
+		}
+		test.Sections = sections
+	}
+	if err := checkSectionRefs(test); err != nil {
+		return err
+	}
+
 	if req.Settings != nil {
 		if req.Settings.DurationSec != nil {
 			if *req.Settings.DurationSec < 0 {
@@ -221,6 +277,24 @@ func (t testService) UpdateTest(ownerID uint, testID string, req *dto.UpdateTest
 	return t.testRepo.Update(test)
 }
 
+// GetQuestion returns one question of a test owned by ownerID.
+func (t testService) GetQuestion(ownerID uint, questionID string) (*dto.QuestionResponse, error) {
+	q, err := t.testRepo.GetQuestion(questionID)
+	if err != nil {
+		return nil, err
+	}
+	test, err := t.testRepo.GetByID(q.TestID)
+	if err != nil {
+		return nil, err
+	}
+	if test.AuthorID != ownerID {
+		return nil, ErrForbidden
+	}
+	resp := QuestionToDTO(*q)
+	resp.TestID = q.TestID
+	return &resp, nil
+}
+
 func (t testService) DeleteTest(ownerID uint, testID string) error {
 	test, err := t.testRepo.GetByID(testID)
 	if err != nil {
@@ -255,6 +329,8 @@ func (t testService) ListTests(ownerID uint) ([]*dto.GetTestResponse, error) {
 			AvailableUntil: cloneTimePtr(test.AvailableUntil),
 			AttemptPolicy:  attemptPolicyToDTO(policy),
 			Questions:      make([]dto.QuestionResponse, 0),
+			DrawRules:      drawRulesToDTO(test),
+			Sections:       sectionsToDTO(test),
 		}
 
 		for _, q := range test.Questions {
@@ -266,9 +342,13 @@ func (t testService) ListTests(ownerID uint) ([]*dto.GetTestResponse, error) {
 				Type:           normalizeQuestionType(q.Type),
 				Weight:         normalizeWeight(q.Weight),
 				ImageURL:       q.ImageURL,
+				Section:        q.Section,
+				Solution:       q.Solution,
 				Options:        make([]dto.OptionResponse, 0),
 			}
 
+			setAnswerKeyResponse(&questionResponse, q)
+
 			for _, opt := range q.Options {
 				questionResponse.Options = append(questionResponse.Options, dto.OptionResponse{
 					ID:         opt.ID,
@@ -311,7 +391,8 @@ func setCorrectAnswers(q *Question, req dto.Question) {
 		if len(opts) == 0 && req.CorrectOption >= 0 {
 			opts = []int{req.CorrectOption}
 		}
-		payload, _ := json.Marshal(map[string]any{"selected": opts})
+		scoring, _ := ta.ParseMultiScoringRule(req.Scoring)
+		payload, _ := json.Marshal(map[string]any{"selected": opts, "scoring": scoring})
 		q.CorrectJSON = payload
 		if len(opts) > 0 {
 			q.CorrectOption = opts[0]
@@ -319,9 +400,19 @@ func setCorrectAnswers(q *Question, req dto.Question) {
 	case "text":
 		q.CorrectOption = 0
 		q.CorrectJSON = nil
+		if key, ok := textAnswerKey(req); ok {
+			q.CorrectJSON, _ = json.Marshal(key)
+		}
 	case "code":
 		q.CorrectOption = 0
 		q.CorrectJSON = nil
+		if len(req.TestCases) > 0 {
+			key := ta.CodeAnswerKey{TestCases: make([]ta.CodeTestCase, 0, len(req.TestCases))}
+			for _, tc := range req.TestCases {
+				key.TestCases = append(key.TestCases, ta.CodeTestCase(tc))
+			}
+			q.CorrectJSON, _ = json.Marshal(key)
+		}
 	default: // single
 		q.CorrectOption = req.CorrectOption
 		payload, _ := json.Marshal(map[string]any{"selected": []int{req.CorrectOption}})
@@ -329,6 +420,254 @@ func setCorrectAnswers(q *Question, req dto.Question) {
 	}
 }
 
+// BuildQuestion validates a question payload and converts it to a model that
+// is not attached to any test yet, e.g. for the question bank.
+func BuildQuestion(req dto.Question) (Question, error) {
+	if err := validateQuestions([]dto.Question{req}); err != nil {
+		return Question{}, err
+	}
+	question := Question{
+		QuestionText: req.QuestionText,
+		ImageURL:     req.ImageURL,
+		Solution:     strings.TrimSpace(req.Solution),
+		Options:      make([]Option, 0, len(req.Options)),
+	}
+	setCorrectAnswers(&question, req)
+	for _, option := range req.Options {
+		question.Options = append(question.Options, Option{
+			OptionText: option.AnswerText,
+			ImageURL:   option.ImageURL,
+		})
+	}
+	return question, nil
+}
+
+// QuestionToDTO renders a stored question the same way GetTest does.
+func QuestionToDTO(q Question) dto.QuestionResponse {
+	resp := dto.QuestionResponse{
+		ID:             q.ID,
+		QuestionText:   q.QuestionText,
+		CorrectOption:  q.CorrectOption,
+		CorrectOptions: decodeCorrectOptions(q.CorrectJSON),
+		Type:           normalizeQuestionType(q.Type),
+		Weight:         normalizeWeight(q.Weight),
+		ImageURL:       q.ImageURL,
+		Section:        q.Section,
+		Solution:       q.Solution,
+		Options:        make([]dto.OptionResponse, 0, len(q.Options)),
+	}
+	setAnswerKeyResponse(&resp, q)
+	for _, opt := range q.Options {
+		resp.Options = append(resp.Options, dto.OptionResponse{
+			ID:         opt.ID,
+			OptionText: opt.OptionText,
+			ImageURL:   opt.ImageURL,
+		})
+	}
+	return resp
+}
+
+func encodeDrawRules(rules []dto.DrawRule) ([]byte, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	out := make([]DrawRule, 0, len(rules))
+	for i, r := range rules {
+		tag := NormalizeTag(r.Tag)
+		if tag == "" {
+			return nil, fmt.Errorf("draw rule %d: tag is required", i+1)
+		}
+		if r.Count <= 0 {
+			return nil, fmt.Errorf("draw rule %d: count must be > 0", i+1)
+		}
+		out = append(out, DrawRule{
+			Tag:        tag,
+			Difficulty: NormalizeTag(r.Difficulty),
+			Count:      r.Count,
+			Section:    strings.TrimSpace(r.Section),
+		})
+	}
+	return json.Marshal(out)
+}
+
+func drawRulesToDTO(t *Test) []dto.DrawRule {
+	rules, err := t.DecodeDrawRules()
+	if err != nil || len(rules) == 0 {
+		return nil
+	}
+	out := make([]dto.DrawRule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, dto.DrawRule(r))
+	}
+	return out
+}
+
+func encodeSections(sections []dto.Section) ([]byte, error) {
+	if len(sections) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]struct{}, len(sections))
+	out := make([]Section, 0, len(sections))
+	for i, sec := range sections {
+		name := strings.TrimSpace(sec.Name)
+		if name == "" {
+			return nil, fmt.Errorf("section %d: name is required", i+1)
+		}
+		if _, dup := seen[name]; dup {
+			return nil, fmt.Errorf("section %d: duplicate name %q", i+1, name)
+		}
+		seen[name] = struct{}{}
+		if sec.DrawCount < 0 || sec.TimeLimitSec < 0 {
+			return nil, fmt.Errorf("section %q: draw_count and time_limit_sec must be >= 0", name)
+		}
+		out = append(out, Section{
+			Name:         name,
+			DrawCount:    sec.DrawCount,
+			Shuffle:      sec.Shuffle,
+			TimeLimitSec: sec.TimeLimitSec,
+		})
+	}
+	return json.Marshal(out)
+}
+
+// checkSectionRefs makes sure every question and draw rule of a sectioned
+// test names one of its sections, and that unsectioned tests use none.
+func checkSectionRefs(t *Test) error {
+	sections, err := t.DecodeSections()
+	if err != nil {
+		return err
+	}
+	rules, err := t.DecodeDrawRules()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]struct{}, len(sections))
+	for _, sec := range sections {
+		known[sec.Name] = struct{}{}
+	}
+	check := func(what, section string) error {
+		if len(sections) == 0 {
+			if section != "" {
+				return fmt.Errorf("%s: section %q is set but the test has no sections", what, section)
+			}
+			return nil
+		}
+		if _, ok := known[section]; !ok {
+			return fmt.Errorf("%s: unknown section %q", what, section)
+		}
+		return nil
+	}
+	for i, q := range t.Questions {
+		if err := check(fmt.Sprintf("question %d", i+1), q.Section); err != nil {
+			return err
+		}
+	}
+	for i, r := range rules {
+		if err := check(fmt.Sprintf("draw rule %d", i+1), r.Section); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sectionsToDTO(t *Test) []dto.Section {
+	sections, err := t.DecodeSections()
+	if err != nil || len(sections) == 0 {
+		return nil
+	}
+	out := make([]dto.Section, 0, len(sections))
+	for _, sec := range sections {
+		out = append(out, dto.Section(sec))
+	}
+	return out
+}
+
+// NormalizeTag lower-cases and trims tags and difficulty levels so draw
+// rules match bank questions regardless of spelling.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// textAnswerKey builds the auto-grading key of a text question; ok is false
+// when no accepted answers were given and the question stays manually graded.
+func textAnswerKey(req dto.Question) (ta.TextAnswerKey, bool) {
+	accepted := make([]string, 0, len(req.AcceptedAnswers))
+	for _, a := range req.AcceptedAnswers {
+		if strings.TrimSpace(a) != "" {
+			accepted = append(accepted, a)
+		}
+	}
+	if len(accepted) == 0 {
+		return ta.TextAnswerKey{}, false
+	}
+	mode, _ := ta.ParseTextMatchMode(req.MatchMode)
+	return ta.TextAnswerKey{
+		AcceptedAnswers: accepted,
+		MatchMode:       mode,
+		Tolerance:       req.Tolerance,
+		ManualReview:    req.ManualReview,
+	}, true
+}
+
+func validateQuestions(questions []dto.Question) error {
+	for i, q := range questions {
+		if normalizeQuestionType(q.Type) == "multi" {
+			if _, err := ta.ParseMultiScoringRule(q.Scoring); err != nil {
+				return fmt.Errorf("question %d: %w", i+1, err)
+			}
+		}
+		if normalizeQuestionType(q.Type) != "text" {
+			continue
+		}
+		if _, err := ta.ParseTextMatchMode(q.MatchMode); err != nil {
+			return fmt.Errorf("question %d: %w", i+1, err)
+		}
+		if key, ok := textAnswerKey(q); ok {
+			if err := key.Validate(); err != nil {
+				return fmt.Errorf("question %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setAnswerKeyResponse exposes the grading settings stored in CorrectJSON.
+func setAnswerKeyResponse(resp *dto.QuestionResponse, q Question) {
+	if len(q.CorrectJSON) == 0 {
+		return
+	}
+	if normalizeQuestionType(q.Type) == "multi" {
+		var key struct {
+			Scoring string `json:"scoring"`
+		}
+		if err := json.Unmarshal(q.CorrectJSON, &key); err == nil {
+			resp.Scoring = key.Scoring
+		}
+		return
+	}
+	if normalizeQuestionType(q.Type) == "code" {
+		var key ta.CodeAnswerKey
+		if err := json.Unmarshal(q.CorrectJSON, &key); err != nil {
+			return
+		}
+		for _, tc := range key.TestCases {
+			resp.TestCases = append(resp.TestCases, dto.CodeTestCase(tc))
+		}
+		return
+	}
+	if normalizeQuestionType(q.Type) != "text" {
+		return
+	}
+	var key ta.TextAnswerKey
+	if err := json.Unmarshal(q.CorrectJSON, &key); err != nil {
+		return
+	}
+	resp.AcceptedAnswers = key.AcceptedAnswers
+	resp.MatchMode = string(key.MatchMode)
+	resp.Tolerance = key.Tolerance
+	resp.ManualReview = key.ManualReview
+}
+
 func decodeCorrectOptions(raw []byte) []int {
 	if len(raw) == 0 {
 		return nil
@@ -0,0 +1,210 @@
+package testAttempt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrGraderNotConfigured = errors.New("ai grading is not configured")
+	ErrGradingFailed       = errors.New("ai grading failed")
+	ErrNoSuggestion        = errors.New("answer has no grade suggestion")
+)
+
+// Grade decisions recorded with a teacher's final score.
+const (
+	DecisionManual     = "manual"
+	DecisionAccepted   = "accepted"
+	DecisionOverridden = "overridden"
+)
+
+// AnswerGrader suggests a score for a free-form answer; the ai service
+// satisfies it. Calls are billed to ownerID.
+type AnswerGrader interface {
+	SuggestGrade(ctx context.Context, ownerID UserID, req GradingRequest) (GradeSuggestion, error)
+}
+
+// GradingRequest carries everything the grader sees about one answer.
+type GradingRequest struct {
+	QuestionText string
+	Kind         string
+	// Reference is the teacher's worked solution or rubric, AcceptedAnswers
+	// the answers of a text key that needs manual review.
+	Reference       string
+	AcceptedAnswers []string
+	Answer          string
+	Lang            string
+	CodeResults     []CodeCaseResult
+	MaxScore        float64
+}
+
+// GradeSuggestion is a proposed score kept apart from Answer.Score until the
+// teacher accepts or overrides it.
+type GradeSuggestion struct {
+	Score     float64 `json:"score"`
+	Rationale string  `json:"rationale"`
+	Provider  string  `json:"provider,omitempty"`
+	Model     string  `json:"model,omitempty"`
+	// PromptVersion names the prompt template the suggestion came from.
+	PromptVersion string    `json:"prompt_version,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AIGradeSummary counts what a bulk grading run did.
+type AIGradeSummary struct {
+	Attempts  int
+	Suggested int
+}
+
+// AIGradeAttempt asks the grader for a suggestion on every pending text and
+// code answer of a closed attempt. Answers that already have one are skipped
+// unless regrade is set.
+func (s *Service) AIGradeAttempt(ctx context.Context, requester UserID, id AttemptID, regrade bool) (AttemptDetails, error) {
+	a, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return AttemptDetails{}, err
+	}
+	descriptor, err := s.getOwnedAssignmentDescriptor(ctx, requester, a.Assignment())
+	if err != nil {
+		return AttemptDetails{}, err
+	}
+	if a.Status() != StatusSubmitted && a.Status() != StatusExpired {
+		return AttemptDetails{}, fmt.Errorf("%w: can grade only submitted/expired attempts", ErrInvalidState)
+	}
+	if _, err := s.suggestGrades(ctx, requester, descriptor, a, regrade); err != nil {
+		return AttemptDetails{}, err
+	}
+	return s.AttemptDetails(ctx, requester, id)
+}
+
+// aiGradeAssignment runs AIGradeAttempt over the closed attempts of an
+// assignment, reporting the running totals after each attempt. It stops at
+// the first grader error; suggestions stored until then are kept.
+func (s *Service) aiGradeAssignment(ctx context.Context, requester UserID, assignmentID AssignmentID, regrade bool, progress func(AIGradeSummary)) (AIGradeSummary, error) {
+	descriptor, err := s.getOwnedAssignmentDescriptor(ctx, requester, assignmentID)
+	if err != nil {
+		return AIGradeSummary{}, err
+	}
+	summaries, err := s.repo.ListSummariesByAssignments(ctx, []AssignmentID{assignmentID})
+	if err != nil {
+		return AIGradeSummary{}, err
+	}
+	var summary AIGradeSummary
+	for _, item := range summaries {
+		if item.PendingScore <= 0 || (item.Status != StatusSubmitted && item.Status != StatusExpired) {
+			continue
+		}
+		a, err := s.repo.GetByID(ctx, item.AttemptID)
+		if err != nil {
+			return summary, err
+		}
+		n, err := s.suggestGrades(ctx, requester, descriptor, a, regrade)
+		summary.Suggested += n
+		if n > 0 {
+			summary.Attempts++
+			progress(summary)
+		}
+		if err != nil {
+			return summary, err
+		}
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// AcceptSuggestion makes the stored suggestion the final score.
+func (s *Service) AcceptSuggestion(ctx context.Context, grader UserID, id AttemptID, questionID QuestionID) (AttemptDetails, error) {
+	a, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return AttemptDetails{}, err
+	}
+	ans, ok := a.Answers()[questionID]
+	if !ok || ans.Suggestion == nil {
+		return AttemptDetails{}, ErrNoSuggestion
+	}
+	return s.GradeAnswer(ctx, grader, id, questionID, ans.Suggestion.Score, nil)
+}
+
+func (s *Service) suggestGrades(ctx context.Context, requester UserID, descriptor AssignmentDescriptor, a *Attempt, regrade bool) (int, error) {
+	if s.grader == nil {
+		return 0, ErrGraderNotConfigured
+	}
+	visibleQuestions, err := s.getVisibleQuestions(ctx, descriptor, a.Test())
+	if err != nil {
+		return 0, err
+	}
+	questionText := make(map[string]string, len(visibleQuestions))
+	for _, q := range visibleQuestions {
+		questionText[q.ID] = q.QuestionText
+	}
+	qs, err := s.getQuestionsForScoring(ctx, descriptor, a.Test())
+	if err != nil {
+		return 0, err
+	}
+
+	answers := a.Answers()
+	suggested := 0
+	for _, q := range qs {
+		qid := QuestionID(q.ID)
+		ans, answered := answers[qid]
+		if !answered || (ans.Suggestion != nil && !regrade) {
+			continue
+		}
+		if _, pending, err := scoreAnswer(q, ans, answered); err != nil || !pending {
+			continue
+		}
+		suggestion, err := s.grader.SuggestGrade(ctx, requester, gradingRequest(q, questionText[q.ID], ans))
+		if err != nil {
+			return suggested, fmt.Errorf("%w: question %s: %v", ErrGradingFailed, qid, err)
+		}
+		suggestion.Score = min(max(suggestion.Score, 0), q.Weight)
+		if suggestion.CreatedAt.IsZero() {
+			suggestion.CreatedAt = s.clock.Now().UTC()
+		}
+		if err := s.repo.SaveSuggestion(ctx, a.ID(), qid, &suggestion); err != nil {
+			return suggested, err
+		}
+		suggested++
+	}
+	return suggested, nil
+}
+
+func gradingRequest(q QuestionForScoring, text string, ans Answer) GradingRequest {
+	req := GradingRequest{
+		QuestionText: text,
+		Kind:         q.Type,
+		Reference:    strings.TrimSpace(q.Solution),
+		MaxScore:     q.Weight,
+	}
+	if key, ok := decodeTextAnswerKey(q.CorrectJSON); ok {
+		req.AcceptedAnswers = key.AcceptedAnswers
+	}
+	switch ans.Payload.Kind {
+	case AnswerText:
+		req.Answer = ans.Payload.Text
+	case AnswerCode:
+		if ans.Payload.Code != nil {
+			req.Answer = ans.Payload.Code.Body
+			req.Lang = ans.Payload.Code.Lang
+		}
+		req.CodeResults = ans.CodeResults
+	}
+	return req
+}
+
+// gradeDecision tells whether a final score took or replaced the suggestion.
+func gradeDecision(suggestion *GradeSuggestion, score float64) string {
+	switch {
+	case suggestion == nil:
+		return DecisionManual
+	case suggestion.Score == score:
+		return DecisionAccepted
+	default:
+		return DecisionOverridden
+	}
+}
@@ -0,0 +1,43 @@
+package testAttempt
+
+import "testing"
+
+func TestGradingRequestCarriesReference(t *testing.T) {
+	q := QuestionForScoring{
+		ID:          "q1",
+		Type:        "text",
+		Weight:      2,
+		CorrectJSON: []byte(`{"accepted_answers":["сокрытие данных"],"manual_review":true}`),
+		Solution:    "  Нужен пример.  ",
+	}
+	req := gradingRequest(q, "Что такое инкапсуляция?", Answer{Payload: AnswerPayload{Kind: AnswerText, Text: "ответ"}})
+	if req.Reference != "Нужен пример." || len(req.AcceptedAnswers) != 1 || req.Answer != "ответ" || req.MaxScore != 2 {
+		t.Fatalf("unexpected grading request: %+v", req)
+	}
+
+	code := gradingRequest(QuestionForScoring{ID: "q2", Type: "code", Weight: 1}, "Сумма", Answer{
+		Payload:     AnswerPayload{Kind: AnswerCode, Code: &CodePayload{Lang: "go", Body: "package main"}},
+		CodeResults: []CodeCaseResult{{Index: 0, Passed: true}},
+	})
+	if code.Lang != "go" || code.Answer != "package main" || len(code.CodeResults) != 1 {
+		t.Fatalf("unexpected code grading request: %+v", code)
+	}
+}
+
+func TestGradeDecision(t *testing.T) {
+	suggestion := &GradeSuggestion{Score: 1.5}
+	cases := []struct {
+		suggestion *GradeSuggestion
+		score      float64
+		want       string
+	}{
+		{nil, 1, DecisionManual},
+		{suggestion, 1.5, DecisionAccepted},
+		{suggestion, 2, DecisionOverridden},
+	}
+	for _, tc := range cases {
+		if got := gradeDecision(tc.suggestion, tc.score); got != tc.want {
+			t.Fatalf("gradeDecision(%v, %v) = %q, want %q", tc.suggestion, tc.score, got, tc.want)
+		}
+	}
+}
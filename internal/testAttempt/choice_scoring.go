@@ -0,0 +1,99 @@
+package testAttempt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MultiScoringRule decides how a partially correct "multi" answer is scored.
+type MultiScoringRule string
+
+const (
+	MultiScoringAllOrNothing MultiScoringRule = "all_or_nothing"
+	// MultiScoringProportional awards weight * correctPicked / totalCorrect.
+	MultiScoringProportional MultiScoringRule = "proportional"
+	// MultiScoringPenalty awards weight * (correctPicked - wrongPicked) / totalCorrect, never below 0.
+	MultiScoringPenalty MultiScoringRule = "proportional_penalty"
+)
+
+func ParseMultiScoringRule(raw string) (MultiScoringRule, error) {
+	switch rule := MultiScoringRule(strings.ToLower(strings.TrimSpace(raw))); rule {
+	case "":
+		return MultiScoringAllOrNothing, nil
+	case MultiScoringAllOrNothing, MultiScoringProportional, MultiScoringPenalty:
+		return rule, nil
+	default:
+		return "", fmt.Errorf("unsupported scoring %q (use all_or_nothing, proportional or proportional_penalty)", raw)
+	}
+}
+
+// choiceKey is the CorrectJSON payload of single and multi questions.
+type choiceKey struct {
+	Selected []int            `json:"selected"`
+	Scoring  MultiScoringRule `json:"scoring,omitempty"`
+}
+
+func decodeChoiceKey(raw []byte) (choiceKey, bool) {
+	if len(raw) == 0 {
+		return choiceKey{}, false
+	}
+	var key choiceKey
+	if err := json.Unmarshal(raw, &key); err != nil || key.Selected == nil {
+		return choiceKey{}, false
+	}
+	return key, true
+}
+
+func selectedSet(payload AnswerPayload) map[int]struct{} {
+	set := make(map[int]struct{})
+	switch payload.Kind {
+	case AnswerSingle:
+		set[payload.Single] = struct{}{}
+	case AnswerMulti:
+		for _, i := range payload.Multi {
+			set[i] = struct{}{}
+		}
+	}
+	return set
+}
+
+// choiceCredit returns the share of the question weight earned by a choice answer.
+func choiceCredit(qType string, key choiceKey, payload AnswerPayload) float64 {
+	picked := selectedSet(payload)
+	correct := make(map[int]struct{}, len(key.Selected))
+	for _, i := range key.Selected {
+		correct[i] = struct{}{}
+	}
+	if len(correct) == 0 {
+		return 0
+	}
+	var hits, misses int
+	for i := range picked {
+		if _, ok := correct[i]; ok {
+			hits++
+		} else {
+			misses++
+		}
+	}
+
+	rule := key.Scoring
+	if qType != "multi" {
+		rule = MultiScoringAllOrNothing
+	}
+	switch rule {
+	case MultiScoringProportional:
+		return float64(hits) / float64(len(correct))
+	case MultiScoringPenalty:
+		credit := float64(hits-misses) / float64(len(correct))
+		if credit < 0 {
+			return 0
+		}
+		return credit
+	default:
+		if hits == len(correct) && misses == 0 {
+			return 1
+		}
+		return 0
+	}
+}
@@ -0,0 +1,41 @@
+package testAttempt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMultiScoringRules(t *testing.T) {
+	multi := func(rule MultiScoringRule) QuestionForScoring {
+		raw, _ := json.Marshal(choiceKey{Selected: []int{0, 1, 2}, Scoring: rule})
+		return QuestionForScoring{ID: "q1", Type: "multi", Weight: 3, CorrectJSON: raw}
+	}
+	pick := func(idx ...int) Answer {
+		return Answer{Payload: AnswerPayload{Kind: AnswerMulti, Multi: idx}}
+	}
+
+	cases := []struct {
+		name string
+		rule MultiScoringRule
+		ans  Answer
+		want float64
+	}{
+		{"all or nothing exact", MultiScoringAllOrNothing, pick(2, 1, 0), 3},
+		{"all or nothing partial", MultiScoringAllOrNothing, pick(0, 1), 0},
+		{"proportional partial", MultiScoringProportional, pick(0, 1), 2},
+		{"proportional ignores wrong picks", MultiScoringProportional, pick(0, 1, 3), 2},
+		{"penalty subtracts wrong picks", MultiScoringPenalty, pick(0, 1, 3), 1},
+		{"penalty never negative", MultiScoringPenalty, pick(3), 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, pending, err := scoreAnswer(multi(tc.rule), tc.ans, true)
+			if err != nil || pending {
+				t.Fatalf("scoreAnswer: pending=%v err=%v", pending, err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package testAttempt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCodeCompile         = errors.New("code compilation failed")
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+)
+
+// CodeTestCase feeds Stdin to the participant program and expects ExpectedStdout.
+// Hidden cases are not shown to participants.
+type CodeTestCase struct {
+	Stdin          string `json:"stdin"`
+	ExpectedStdout string `json:"expected_stdout"`
+	Hidden         bool   `json:"hidden,omitempty"`
+}
+
+// CodeAnswerKey is the CorrectJSON payload of an auto-graded code question.
+type CodeAnswerKey struct {
+	TestCases []CodeTestCase `json:"test_cases"`
+}
+
+type CodeRunResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	TimedOut bool
+	Duration time.Duration
+}
+
+// CodeRunner executes a participant program once per stdin. Compilation
+// failures are reported as ErrCodeCompile, unknown languages as
+// ErrUnsupportedLanguage.
+type CodeRunner interface {
+	Run(ctx context.Context, lang, source string, stdins []string) ([]CodeRunResult, error)
+}
+
+// CodeCaseResult is stored with the answer for every executed test case.
+type CodeCaseResult struct {
+	Index      int    `json:"index"`
+	Hidden     bool   `json:"hidden,omitempty"`
+	Passed     bool   `json:"passed"`
+	Stdin      string `json:"stdin,omitempty"`
+	Expected   string `json:"expected,omitempty"`
+	Stdout     string `json:"stdout,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+	TimedOut   bool   `json:"timed_out,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+func decodeCodeAnswerKey(raw []byte) (CodeAnswerKey, bool) {
+	if len(raw) == 0 {
+		return CodeAnswerKey{}, false
+	}
+	var key CodeAnswerKey
+	if err := json.Unmarshal(raw, &key); err != nil || len(key.TestCases) == 0 {
+		return CodeAnswerKey{}, false
+	}
+	return key, true
+}
+
+// runCodeTests executes the answer against every test case. Compilation and
+// language errors fail all cases; other runner errors are returned so the
+// answer stays pending.
+func runCodeTests(ctx context.Context, runner CodeRunner, key CodeAnswerKey, code *CodePayload) ([]CodeCaseResult, error) {
+	results := make([]CodeCaseResult, len(key.TestCases))
+	stdins := make([]string, len(key.TestCases))
+	for i, tc := range key.TestCases {
+		stdins[i] = tc.Stdin
+		results[i] = CodeCaseResult{Index: i, Hidden: tc.Hidden, Stdin: tc.Stdin, Expected: tc.ExpectedStdout}
+	}
+	if code == nil || strings.TrimSpace(code.Body) == "" {
+		return failAll(results, "empty program"), nil
+	}
+
+	runs, err := runner.Run(ctx, code.Lang, code.Body, stdins)
+	if errors.Is(err, ErrCodeCompile) || errors.Is(err, ErrUnsupportedLanguage) {
+		return failAll(results, err.Error()), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		if i >= len(runs) {
+			results[i].Error = "not executed"
+			continue
+		}
+		run := runs[i]
+		results[i].Stdout = run.Stdout
+		results[i].Stderr = run.Stderr
+		results[i].ExitCode = run.ExitCode
+		results[i].TimedOut = run.TimedOut
+		results[i].DurationMs = run.Duration.Milliseconds()
+		results[i].Passed = !run.TimedOut && run.ExitCode == 0 &&
+			normalizeOutput(run.Stdout) == normalizeOutput(results[i].Expected)
+	}
+	return results, nil
+}
+
+func failAll(results []CodeCaseResult, msg string) []CodeCaseResult {
+	for i := range results {
+		results[i].Error = msg
+	}
+	return results
+}
+
+// passedFraction returns the share of passing cases, or ok=false when the
+// answer was never executed.
+func passedFraction(results []CodeCaseResult) (float64, bool) {
+	if len(results) == 0 {
+		return 0, false
+	}
+	passed := 0
+	for _, r := range results {
+		if r.Passed {
+			passed++
+		}
+	}
+	return float64(passed) / float64(len(results)), true
+}
+
+// normalizeOutput ignores trailing whitespace on each line and trailing blank lines.
+func normalizeOutput(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// publicCodeResults strips inputs and outputs of hidden cases for participant views.
+func publicCodeResults(results []CodeCaseResult) []CodeCaseResult {
+	if len(results) == 0 {
+		return nil
+	}
+	out := make([]CodeCaseResult, len(results))
+	for i, r := range results {
+		if r.Hidden {
+			r.Stdin, r.Expected, r.Stdout, r.Stderr = "", "", "", ""
+		}
+		out[i] = r
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package testAttempt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// echoRunner prints stdin back, upper-cased for "upper" programs.
+type echoRunner struct{}
+
+func (echoRunner) Run(_ context.Context, lang, source string, stdins []string) ([]CodeRunResult, error) {
+	if lang != "python" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, lang)
+	}
+	out := make([]CodeRunResult, len(stdins))
+	for i, in := range stdins {
+		if source == "upper" {
+			in = strings.ToUpper(in)
+		}
+		out[i] = CodeRunResult{Stdout: in + "\n"}
+	}
+	return out, nil
+}
+
+func TestCodeAnswerScoredByPassingCases(t *testing.T) {
+	key := CodeAnswerKey{TestCases: []CodeTestCase{
+		{Stdin: "abc", ExpectedStdout: "ABC"},
+		{Stdin: "123", ExpectedStdout: "123", Hidden: true},
+	}}
+	raw, _ := json.Marshal(key)
+	q := QuestionForScoring{ID: "q1", Type: "code", Weight: 4, CorrectJSON: raw}
+
+	results, err := runCodeTests(context.Background(), echoRunner{}, key, &CodePayload{Lang: "python", Body: "echo"})
+	if err != nil {
+		t.Fatalf("runCodeTests: %v", err)
+	}
+	score, pending, err := scoreAnswer(q, Answer{CodeResults: results}, true)
+	if err != nil || pending || score != 2 {
+		t.Fatalf("got score=%v pending=%v err=%v, want 2", score, pending, err)
+	}
+
+	results, _ = runCodeTests(context.Background(), echoRunner{}, key, &CodePayload{Lang: "python", Body: "upper"})
+	if score, _, _ := scoreAnswer(q, Answer{CodeResults: results}, true); score != 4 {
+		t.Fatalf("expected full score, got %v", score)
+	}
+	if public := publicCodeResults(results); public[1].Stdin != "" || public[0].Stdin != "abc" {
+		t.Fatalf("hidden case leaked: %+v", public)
+	}
+
+	results, _ = runCodeTests(context.Background(), echoRunner{}, key, &CodePayload{Lang: "cobol", Body: "x"})
+	if score, _, _ := scoreAnswer(q, Answer{CodeResults: results}, true); score != 0 || results[0].Error == "" {
+		t.Fatalf("expected failed cases for unsupported language, got %+v", results)
+	}
+
+	if _, pending, _ := scoreAnswer(q, Answer{}, true); !pending {
+		t.Fatal("answer without results should stay pending")
+	}
+}
@@ -0,0 +1,126 @@
+package testAttempt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// codeGradingTimeout bounds the background run of one attempt's code
+// answers; answers not run by then stay pending for manual grading.
+const codeGradingTimeout = 10 * time.Minute
+
+// codeJobs tracks the background runs of code answers of closed attempts.
+type codeJobs struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newCodeJobs() *codeJobs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &codeJobs{ctx: ctx, cancel: cancel}
+}
+
+func (j *codeJobs) stop() {
+	j.cancel()
+	j.wg.Wait()
+}
+
+// gradeCodeLater runs the code answers of an attempt that was just submitted
+// or expired in the background, so closing an attempt never waits for the
+// sandbox. The attempt keeps those answers pending until the run is stored.
+func (s *Service) gradeCodeLater(a *Attempt, qs []QuestionForScoring) {
+	if s.runner == nil || !hasUnrunCode(a, qs) {
+		return
+	}
+	id := a.ID()
+	s.codeJobs.wg.Add(1)
+	go func() {
+		defer s.codeJobs.wg.Done()
+		ctx, cancel := context.WithTimeout(s.codeJobs.ctx, codeGradingTimeout)
+		defer cancel()
+		if err := s.gradeCode(ctx, id, qs); err != nil {
+			log.Printf("attempt %s: code grading: %v", id, err)
+		}
+	}()
+}
+
+// ResumeCodeGrading queues the code answers of closed attempts whose
+// background run was lost, e.g. to a restart while it was going.
+func (s *Service) ResumeCodeGrading(ctx context.Context) error {
+	if s.runner == nil {
+		return nil
+	}
+	attempts, err := s.repo.ListClosedUngraded(ctx)
+	if err != nil {
+		return err
+	}
+	scoring := make(map[AssignmentID][]QuestionForScoring)
+	var errs []error
+	for _, a := range attempts {
+		qs, ok := scoring[a.Assignment()]
+		if !ok {
+			descriptor, err := s.assignments.GetAssignment(ctx, a.Assignment())
+			if err == nil {
+				qs, err = s.getQuestionsForScoring(ctx, descriptor, a.Test())
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("attempt %s: %w", a.ID(), err))
+				continue
+			}
+			scoring[a.Assignment()] = qs
+		}
+		s.gradeCodeLater(a, qs)
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) gradeCode(ctx context.Context, id AttemptID, qs []QuestionForScoring) error {
+	a, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	s.runCodeAnswers(ctx, a, qs)
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// A teacher may have graded the attempt while the code ran, so the
+		// results are applied to the latest answers.
+		fresh, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		for qid, ans := range a.answers {
+			cur, ok := fresh.answers[qid]
+			if len(ans.CodeResults) == 0 || !ok || cur.Score != nil || len(cur.CodeResults) > 0 {
+				continue
+			}
+			cur.CodeResults = ans.CodeResults
+			fresh.answers[qid] = cur
+		}
+		score, max, pending, err := scoreAttempt(fresh, qs)
+		if err != nil {
+			return err
+		}
+		fresh.score, fresh.maxScore, fresh.pending = score, max, pending
+		return s.repo.SaveCodeResults(ctx, fresh)
+	})
+}
+
+// hasUnrunCode reports whether runCodeAnswers would execute anything.
+func hasUnrunCode(a *Attempt, qs []QuestionForScoring) bool {
+	for _, q := range qs {
+		if q.Type != "code" {
+			continue
+		}
+		if _, ok := decodeCodeAnswerKey(q.CorrectJSON); !ok {
+			continue
+		}
+		if ans, ok := a.answers[QuestionID(q.ID)]; ok && ans.Score == nil && len(ans.CodeResults) == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -14,15 +14,32 @@ type StartAttemptResponse struct {
 }
 
 type AttemptView struct {
-	AttemptID    string            `json:"attempt_id"`
-	AssignmentID string            `json:"assignment_id"`
-	Status       string            `json:"status"`
-	Version      int               `json:"version"`
-	TimeLeftSec  int64             `json:"time_left_sec"`
-	Total        int               `json:"total"`
-	Cursor       int               `json:"cursor"`
-	GuestName    string            `json:"guest_name,omitempty"`
-	Policy       AttemptPolicyView `json:"policy"`
+	AttemptID    string                 `json:"attempt_id"`
+	AssignmentID string                 `json:"assignment_id"`
+	Status       string                 `json:"status"`
+	Version      int                    `json:"version"`
+	TimeLeftSec  int64                  `json:"time_left_sec"`
+	Total        int                    `json:"total"`
+	Cursor       int                    `json:"cursor"`
+	GuestName    string                 `json:"guest_name,omitempty"`
+	Policy       AttemptPolicyView      `json:"policy"`
+	Progress     []QuestionProgressView `json:"progress,omitempty"`
+	Section      *SectionView           `json:"section,omitempty"`
+}
+
+type SectionView struct {
+	Index       int    `json:"index"`
+	Name        string `json:"name"`
+	Total       int    `json:"total"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	TimeLeftSec *int64 `json:"time_left_sec,omitempty"`
+}
+
+type QuestionProgressView struct {
+	Index      int    `json:"index"`
+	QuestionID string `json:"question_id"`
+	Answered   bool   `json:"answered"`
 }
 
 type AttemptPolicyView struct {
@@ -45,13 +62,33 @@ type NextQuestionResponse struct {
 	Question QuestionView `json:"question"`
 }
 
+type QuestionAtResponse struct {
+	Attempt  AttemptView      `json:"attempt"`
+	Index    int              `json:"index"`
+	Question QuestionView     `json:"question"`
+	Answer   *SavedAnswerView `json:"answer,omitempty"`
+}
+
+type SavedAnswerView struct {
+	Kind     string          `json:"kind"`
+	Selected []int           `json:"selected,omitempty"`
+	Text     string          `json:"text,omitempty"`
+	Code     *CodeAnswerView `json:"code,omitempty"`
+}
+
 type QuestionView struct {
-	ID           string       `json:"id"`
-	Type         string       `json:"type,omitempty"`
-	QuestionText string       `json:"question_text"`
-	ImageURL     string       `json:"image_url,omitempty"`
-	Weight       float64      `json:"weight,omitempty"`
-	Options      []OptionView `json:"options"`
+	ID           string            `json:"id"`
+	Type         string            `json:"type,omitempty"`
+	QuestionText string            `json:"question_text"`
+	ImageURL     string            `json:"image_url,omitempty"`
+	Weight       float64           `json:"weight,omitempty"`
+	Options      []OptionView      `json:"options"`
+	Examples     []CodeExampleView `json:"examples,omitempty"`
+}
+
+type CodeExampleView struct {
+	Stdin          string `json:"stdin"`
+	ExpectedStdout string `json:"expected_stdout"`
 }
 
 type OptionView struct {
@@ -135,6 +172,34 @@ type AnsweredQuestionView struct {
 	CodeAnswer   *CodeAnswerView      `json:"code_answer,omitempty"`
 	IsCorrect    *bool                `json:"is_correct,omitempty"`
 	Score        *float64             `json:"score,omitempty"`
+	CodeResults  []CodeCaseResultView `json:"code_results,omitempty"`
+	// Grading review, shown to the assignment owner only.
+	AISuggestion  *GradeSuggestionView `json:"ai_suggestion,omitempty"`
+	GradedBy      *uint64              `json:"graded_by,omitempty"`
+	GradedAt      *time.Time           `json:"graded_at,omitempty"`
+	GradeDecision string               `json:"grade_decision,omitempty"`
+}
+
+type GradeSuggestionView struct {
+	Score     float64   `json:"score"`
+	Rationale string    `json:"rationale"`
+	Provider  string    `json:"provider,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CodeCaseResultView struct {
+	Index      int    `json:"index"`
+	Hidden     bool   `json:"hidden,omitempty"`
+	Passed     bool   `json:"passed"`
+	Stdin      string `json:"stdin,omitempty"`
+	Expected   string `json:"expected,omitempty"`
+	Stdout     string `json:"stdout,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+	TimedOut   bool   `json:"timed_out,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
 }
 
 type AnsweredOptionView struct {
@@ -142,6 +207,25 @@ type AnsweredOptionView struct {
 	OptionText string `json:"option_text"`
 	ImageURL   string `json:"image_url,omitempty"`
 	Selected   bool   `json:"selected"`
+	Correct    *bool  `json:"correct,omitempty"`
+}
+
+type AttemptResultResponse struct {
+	Attempt AttemptResultView      `json:"attempt"`
+	Answers []AnsweredQuestionView `json:"answers"`
+}
+
+type AttemptResultView struct {
+	AttemptID        string     `json:"attempt_id"`
+	AssignmentID     string     `json:"assignment_id"`
+	Status           string     `json:"status"`
+	SubmittedAt      *time.Time `json:"submitted_at,omitempty"`
+	ExpiredAt        *time.Time `json:"expired_at,omitempty"`
+	ScoreVisible     bool       `json:"score_visible"`
+	SolutionsVisible bool       `json:"solutions_visible"`
+	Score            *float64   `json:"score,omitempty"`
+	MaxScore         *float64   `json:"max_score,omitempty"`
+	PendingScore     *float64   `json:"pending_score,omitempty"`
 }
 
 type CodeAnswerView struct {
@@ -153,6 +237,19 @@ type GradeAnswerRequest struct {
 	QuestionID string  `json:"question_id" validate:"required,uuid4"`
 	Score      float64 `json:"score" validate:"gte=0"`
 	IsCorrect  *bool   `json:"is_correct,omitempty"`
+	// AcceptSuggestion takes the AI suggested score; Score is ignored.
+	AcceptSuggestion bool `json:"accept_suggestion,omitempty"`
+}
+
+type AIGradeJobView struct {
+	JobID        string     `json:"job_id"`
+	AssignmentID string     `json:"assignment_id"`
+	Status       string     `json:"status"`
+	Attempts     int        `json:"attempts"`
+	Suggested    int        `json:"suggested"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
 }
 
 type GradeAnswerResponse struct {
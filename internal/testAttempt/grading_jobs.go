@@ -0,0 +1,176 @@
+package testAttempt
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrGradingJobNotFound = errors.New("ai grading job not found")
+
+// Statuses of a background grading job.
+const (
+	GradingJobRunning   = "running"
+	GradingJobSucceeded = "succeeded"
+	GradingJobFailed    = "failed"
+	GradingJobCanceled  = "canceled"
+)
+
+// gradingJobRetention is how long finished grading jobs stay queryable.
+const gradingJobRetention = time.Hour
+
+// GradingJob is a bulk AI grading run of one assignment. Attempts and
+// Suggested grow while it runs.
+type GradingJob struct {
+	ID           string
+	AssignmentID AssignmentID
+	Status       string
+	Attempts     int
+	Suggested    int
+	Error        string
+	CreatedAt    time.Time
+	FinishedAt   *time.Time
+}
+
+// StartAIGradeAssignment asks for suggestions on the closed attempts of an
+// assignment in the background and returns the job to poll with AIGradeJob. A job already running for the assignment
+// is returned instead of starting a second one.
+func (s *Service) StartAIGradeAssignment(ctx context.Context, requester UserID, assignmentID AssignmentID, regrade bool) (GradingJob, error) {
+	if s.grader == nil {
+		return GradingJob{}, ErrGraderNotConfigured
+	}
+	if _, err := s.getOwnedAssignmentDescriptor(ctx, requester, assignmentID); err != nil {
+		return GradingJob{}, err
+	}
+	// The job outlives the request that started it; StopJobs ends it.
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	j, started := s.gradingJobs.add(requester, assignmentID, cancel)
+	if !started {
+		cancel()
+		return j.snapshot(), nil
+	}
+	go func() {
+		defer cancel()
+		summary, err := s.aiGradeAssignment(jobCtx, requester, assignmentID, regrade, j.progress)
+		j.finish(summary, err)
+	}()
+	return j.snapshot(), nil
+}
+
+// AIGradeJob returns a grading job started by requester.
+func (s *Service) AIGradeJob(requester UserID, jobID string) (GradingJob, error) {
+	j, err := s.gradingJobs.get(requester, jobID)
+	if err != nil {
+		return GradingJob{}, err
+	}
+	return j.snapshot(), nil
+}
+
+// StopJobs cancels the running grading jobs, e.g. on shutdown, and waits
+// for background code runs to stop.
+func (s *Service) StopJobs() {
+	s.gradingJobs.cancelAll()
+	s.codeJobs.stop()
+}
+
+type gradingJobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*gradingJob
+}
+
+func newGradingJobRegistry() *gradingJobRegistry {
+	return &gradingJobRegistry{jobs: make(map[string]*gradingJob)}
+}
+
+// add registers a job unless one is still running for the assignment, which
+// is returned with started false.
+func (r *gradingJobRegistry) add(ownerID UserID, assignmentID AssignmentID, cancel context.CancelFunc) (*gradingJob, bool) {
+	now := time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, old := range r.jobs {
+		old.mu.Lock()
+		running := old.view.FinishedAt == nil
+		expired := !running && now.Sub(*old.view.FinishedAt) > gradingJobRetention
+		sameAssignment := old.view.AssignmentID == assignmentID
+		old.mu.Unlock()
+		if running && sameAssignment {
+			return old, false
+		}
+		if expired {
+			delete(r.jobs, id)
+		}
+	}
+	j := &gradingJob{
+		ownerID: ownerID,
+		cancel:  cancel,
+		view: GradingJob{
+			ID:           uuid.New().String(),
+			AssignmentID: assignmentID,
+			Status:       GradingJobRunning,
+			CreatedAt:    now,
+		},
+	}
+	r.jobs[j.view.ID] = j
+	return j, true
+}
+
+func (r *gradingJobRegistry) get(ownerID UserID, id string) (*gradingJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[id]
+	if !ok || j.ownerID != ownerID {
+		return nil, ErrGradingJobNotFound
+	}
+	return j, nil
+}
+
+func (r *gradingJobRegistry) cancelAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, j := range r.jobs {
+		j.cancel()
+	}
+}
+
+type gradingJob struct {
+	mu      sync.Mutex
+	ownerID UserID
+	cancel  context.CancelFunc
+	view    GradingJob
+}
+
+func (j *gradingJob) progress(summary AIGradeSummary) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.view.Attempts = summary.Attempts
+	j.view.Suggested = summary.Suggested
+}
+
+func (j *gradingJob) finish(summary AIGradeSummary, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now().UTC()
+	j.view.Attempts = summary.Attempts
+	j.view.Suggested = summary.Suggested
+	j.view.FinishedAt = &now
+	switch {
+	case err == nil:
+		j.view.Status = GradingJobSucceeded
+	case errors.Is(err, context.Canceled):
+		j.view.Status = GradingJobCanceled
+		j.view.Error = err.Error()
+	default:
+		j.view.Status = GradingJobFailed
+		j.view.Error = err.Error()
+	}
+}
+
+func (j *gradingJob) snapshot() GradingJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.view
+}
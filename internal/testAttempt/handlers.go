@@ -82,17 +82,8 @@ func (h *Handlers) NextQuestion(c *gin.Context) {
 		return
 	}
 	resp := dto.NextQuestionResponse{
-		Attempt: toDTOAttemptView(av),
-		Question: dto.QuestionView{
-			ID:           qv.ID,
-			Type:         qv.Type,
-			QuestionText: qv.QuestionText,
-			ImageURL:     qv.ImageURL,
-			Options:      make([]dto.OptionView, len(qv.Options)),
-		},
-	}
-	for i, o := range qv.Options {
-		resp.Question.Options[i] = dto.OptionView{ID: o.ID, OptionText: o.OptionText, ImageURL: o.ImageURL}
+		Attempt:  toDTOAttemptView(av),
+		Question: toDTOQuestionView(qv),
 	}
 	c.JSON(http.StatusOK, resp)
 }
@@ -134,6 +125,75 @@ func (h *Handlers) Answer(c *gin.Context) {
 	})
 }
 
+// GET /v1/attempts/:id/questions/:index
+func (h *Handlers) QuestionAt(c *gin.Context) {
+	attemptID := c.Param("id")
+	if attemptID == "" {
+		c.JSON(http.StatusBadRequest, errJSON("invalid_id", "missing id"))
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		c.JSON(http.StatusBadRequest, errJSON("invalid_index", "index must be a non-negative integer"))
+		return
+	}
+	var userIDPtr *UserID
+	if uid, ok := userIDFromCtx(c); ok {
+		u := UserID(uid)
+		userIDPtr = &u
+	}
+	av, qv, saved, err := h.svc.QuestionAt(c, userIDPtr, AttemptID(attemptID), index)
+	if err != nil {
+		writeDomainErr(c, err)
+		return
+	}
+	resp := dto.QuestionAtResponse{
+		Attempt:  toDTOAttemptView(av),
+		Index:    index,
+		Question: toDTOQuestionView(qv),
+		Answer:   toDTOSavedAnswer(saved),
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// POST /v1/attempts/:id/answers/:question_id
+func (h *Handlers) AnswerQuestion(c *gin.Context) {
+	attemptID := c.Param("id")
+	questionID := c.Param("question_id")
+	if attemptID == "" || questionID == "" {
+		c.JSON(http.StatusBadRequest, errJSON("invalid_id", "missing id"))
+		return
+	}
+	var req dto.AnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errJSON("bad_json", err.Error()))
+		return
+	}
+	if err := h.v.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, errJSON("invalid", err.Error()))
+		return
+	}
+	var userIDPtr *UserID
+	if uid, ok := userIDFromCtx(c); ok {
+		u := UserID(uid)
+		userIDPtr = &u
+	}
+	payload, err := normalizePayload(req.Payload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errJSON("invalid_payload", err.Error()))
+		return
+	}
+	av, answered, err := h.svc.AnswerQuestion(c, userIDPtr, AttemptID(attemptID), QuestionID(questionID), req.Version, payload)
+	if err != nil {
+		writeDomainErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.AnswerResponse{
+		Attempt:    toDTOAttemptView(av),
+		QuestionID: answered.QuestionID,
+	})
+}
+
 // POST /v1/attempts/:id/submit
 func (h *Handlers) Submit(c *gin.Context) {
 	attemptID := c.Param("id")
@@ -473,56 +533,111 @@ func (h *Handlers) Details(c *gin.Context) {
 		writeDomainErr(c, err)
 		return
 	}
-	resp := dto.AttemptDetailsResponse{
-		Attempt: dto.AttemptDetailsView{
-			AttemptID:    details.Attempt.AttemptID,
-			AssignmentID: details.Attempt.AssignmentID,
-			TestID:       details.Attempt.TestID,
-			Status:       string(details.Attempt.Status),
-			StartedAt:    details.Attempt.StartedAt,
-			SubmittedAt:  details.Attempt.SubmittedAt,
-			ExpiredAt:    details.Attempt.ExpiredAt,
-			DurationSec:  int(details.Attempt.Duration / time.Second),
-			Score:        details.Attempt.Score,
-			MaxScore:     details.Attempt.MaxScore,
-			PendingScore: details.Attempt.PendingScore,
-			Participant: dto.ParticipantView{
-				Kind: details.Attempt.Participant.Kind,
-				Name: details.Attempt.Participant.Name,
-			},
-		},
-		Answers: make([]dto.AnsweredQuestionView, 0, len(details.Answers)),
+	c.JSON(http.StatusOK, toDTOAttemptDetails(details))
+}
+
+// POST /v1/attempts/:id/ai-grade
+func (h *Handlers) AIGrade(c *gin.Context) {
+	attemptID := c.Param("id")
+	if attemptID == "" {
+		c.JSON(http.StatusBadRequest, errJSON("invalid_id", "missing id"))
+		return
 	}
-	if details.Attempt.Participant.UserID != nil {
-		uid := uint64(*details.Attempt.Participant.UserID)
-		resp.Attempt.Participant.UserID = &uid
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errJSON("unauthorized", "authentication required"))
+		return
 	}
-	for _, answer := range details.Answers {
-		item := dto.AnsweredQuestionView{
-			QuestionID:   answer.QuestionID,
-			QuestionText: answer.QuestionText,
-			ImageURL:     answer.ImageURL,
-			Kind:         answer.Kind,
-			TextAnswer:   answer.TextAnswer,
-			IsCorrect:    answer.IsCorrect,
-			Score:        answer.Score,
-			Options:      make([]dto.AnsweredOptionView, 0, len(answer.Options)),
-		}
-		if answer.CodeAnswer != nil {
-			item.CodeAnswer = &dto.CodeAnswerView{Lang: answer.CodeAnswer.Lang, Body: answer.CodeAnswer.Body}
-		}
-		item.Weight = answer.Weight
-		for _, opt := range answer.Options {
-			item.Options = append(item.Options, dto.AnsweredOptionView{
-				ID:         opt.ID,
-				OptionText: opt.OptionText,
-				ImageURL:   opt.ImageURL,
-				Selected:   opt.Selected,
-			})
-		}
-		resp.Answers = append(resp.Answers, item)
+	regrade, _ := strconv.ParseBool(c.Query("regrade"))
+	details, err := h.svc.AIGradeAttempt(c, UserID(ownerID), AttemptID(attemptID), regrade)
+	if err != nil {
+		writeDomainErr(c, err)
+		return
 	}
-	c.JSON(http.StatusOK, resp)
+	c.JSON(http.StatusOK, toDTOAttemptDetails(details))
+}
+
+// POST /v1/attempts/ai-grade?assignment_id=...
+func (h *Handlers) AIGradeAssignment(c *gin.Context) {
+	assignmentID := c.Query("assignment_id")
+	if assignmentID == "" {
+		c.JSON(http.StatusBadRequest, errJSON("missing_assignment_id", "assignment_id query parameter is required"))
+		return
+	}
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errJSON("unauthorized", "authentication required"))
+		return
+	}
+	regrade, _ := strconv.ParseBool(c.Query("regrade"))
+	job, err := h.svc.StartAIGradeAssignment(c, UserID(ownerID), AssignmentID(assignmentID), regrade)
+	if err != nil {
+		writeDomainErr(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, toDTOGradingJob(job))
+}
+
+// GET /v1/attempts/ai-grade/:jobID
+func (h *Handlers) AIGradeJob(c *gin.Context) {
+	ownerID, ok := userIDFromCtx(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errJSON("unauthorized", "authentication required"))
+		return
+	}
+	job, err := h.svc.AIGradeJob(UserID(ownerID), c.Param("jobID"))
+	if err != nil {
+		writeDomainErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toDTOGradingJob(job))
+}
+
+func toDTOGradingJob(job GradingJob) dto.AIGradeJobView {
+	return dto.AIGradeJobView{
+		JobID:        job.ID,
+		AssignmentID: string(job.AssignmentID),
+		Status:       job.Status,
+		Attempts:     job.Attempts,
+		Suggested:    job.Suggested,
+		Error:        job.Error,
+		CreatedAt:    job.CreatedAt,
+		FinishedAt:   job.FinishedAt,
+	}
+}
+
+// GET /v1/attempts/:id/result
+func (h *Handlers) Result(c *gin.Context) {
+	attemptID := c.Param("id")
+	if attemptID == "" {
+		c.JSON(http.StatusBadRequest, errJSON("invalid_id", "missing id"))
+		return
+	}
+	var userIDPtr *UserID
+	if uid, ok := userIDFromCtx(c); ok {
+		u := UserID(uid)
+		userIDPtr = &u
+	}
+	result, err := h.svc.AttemptResult(c, userIDPtr, AttemptID(attemptID))
+	if err != nil {
+		writeDomainErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.AttemptResultResponse{
+		Attempt: dto.AttemptResultView{
+			AttemptID:        result.AttemptID,
+			AssignmentID:     result.AssignmentID,
+			Status:           string(result.Status),
+			SubmittedAt:      result.SubmittedAt,
+			ExpiredAt:        result.ExpiredAt,
+			ScoreVisible:     result.ScoreVisible,
+			SolutionsVisible: result.SolutionsVisible,
+			Score:            result.Score,
+			MaxScore:         result.MaxScore,
+			PendingScore:     result.PendingScore,
+		},
+		Answers: toDTOAnsweredQuestions(result.Answers),
+	})
 }
 
 // POST /v1/attempts/:id/grade
@@ -547,7 +662,15 @@ func (h *Handlers) Grade(c *gin.Context) {
 		return
 	}
 
-	details, err := h.svc.GradeAnswer(c, UserID(ownerID), AttemptID(attemptID), QuestionID(req.QuestionID), req.Score, req.IsCorrect)
+	var (
+		details AttemptDetails
+		err     error
+	)
+	if req.AcceptSuggestion {
+		details, err = h.svc.AcceptSuggestion(c, UserID(ownerID), AttemptID(attemptID), QuestionID(req.QuestionID))
+	} else {
+		details, err = h.svc.GradeAnswer(c, UserID(ownerID), AttemptID(attemptID), QuestionID(req.QuestionID), req.Score, req.IsCorrect)
+	}
 	if err != nil {
 		writeDomainErr(c, err)
 		return
@@ -581,6 +704,83 @@ func (h *Handlers) Grade(c *gin.Context) {
 
 // Helpers.
 
+func toDTOAttemptDetails(details AttemptDetails) dto.AttemptDetailsResponse {
+	resp := dto.AttemptDetailsResponse{
+		Attempt: dto.AttemptDetailsView{
+			AttemptID:    details.Attempt.AttemptID,
+			AssignmentID: details.Attempt.AssignmentID,
+			TestID:       details.Attempt.TestID,
+			Status:       string(details.Attempt.Status),
+			StartedAt:    details.Attempt.StartedAt,
+			SubmittedAt:  details.Attempt.SubmittedAt,
+			ExpiredAt:    details.Attempt.ExpiredAt,
+			DurationSec:  int(details.Attempt.Duration / time.Second),
+			Score:        details.Attempt.Score,
+			MaxScore:     details.Attempt.MaxScore,
+			PendingScore: details.Attempt.PendingScore,
+			Participant: dto.ParticipantView{
+				Kind: details.Attempt.Participant.Kind,
+				Name: details.Attempt.Participant.Name,
+			},
+		},
+	}
+	if details.Attempt.Participant.UserID != nil {
+		uid := uint64(*details.Attempt.Participant.UserID)
+		resp.Attempt.Participant.UserID = &uid
+	}
+	resp.Answers = toDTOAnsweredQuestions(details.Answers)
+	return resp
+}
+
+func toDTOAnsweredQuestions(answers []AnsweredQuestion) []dto.AnsweredQuestionView {
+	out := make([]dto.AnsweredQuestionView, 0, len(answers))
+	for _, answer := range answers {
+		item := dto.AnsweredQuestionView{
+			QuestionID:   answer.QuestionID,
+			QuestionText: answer.QuestionText,
+			ImageURL:     answer.ImageURL,
+			Kind:         answer.Kind,
+			TextAnswer:   answer.TextAnswer,
+			IsCorrect:    answer.IsCorrect,
+			Score:        answer.Score,
+			Options:      make([]dto.AnsweredOptionView, 0, len(answer.Options)),
+		}
+		if answer.CodeAnswer != nil {
+			item.CodeAnswer = &dto.CodeAnswerView{Lang: answer.CodeAnswer.Lang, Body: answer.CodeAnswer.Body}
+		}
+		item.Weight = answer.Weight
+		if answer.Suggestion != nil {
+			item.AISuggestion = &dto.GradeSuggestionView{
+				Score:     answer.Suggestion.Score,
+				Rationale: answer.Suggestion.Rationale,
+				Provider:  answer.Suggestion.Provider,
+				Model:     answer.Suggestion.Model,
+				CreatedAt: answer.Suggestion.CreatedAt,
+			}
+		}
+		if answer.GradedBy != nil {
+			uid := uint64(*answer.GradedBy)
+			item.GradedBy = &uid
+		}
+		item.GradedAt = answer.GradedAt
+		item.GradeDecision = answer.Decision
+		for _, r := range answer.CodeResults {
+			item.CodeResults = append(item.CodeResults, dto.CodeCaseResultView(r))
+		}
+		for _, opt := range answer.Options {
+			item.Options = append(item.Options, dto.AnsweredOptionView{
+				ID:         opt.ID,
+				OptionText: opt.OptionText,
+				ImageURL:   opt.ImageURL,
+				Selected:   opt.Selected,
+				Correct:    opt.Correct,
+			})
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
 func userIDFromCtx(c *gin.Context) (uint64, bool) {
 	idv, ok := c.Get("user_id")
 	if !ok {
@@ -605,10 +805,27 @@ func userIDFromCtx(c *gin.Context) (uint64, bool) {
 	}
 }
 
+func toDTOQuestionView(qv QuestionView) dto.QuestionView {
+	out := dto.QuestionView{
+		ID:           qv.ID,
+		Type:         qv.Type,
+		QuestionText: qv.QuestionText,
+		ImageURL:     qv.ImageURL,
+		Options:      make([]dto.OptionView, len(qv.Options)),
+	}
+	for i, o := range qv.Options {
+		out.Options[i] = dto.OptionView{ID: o.ID, OptionText: o.OptionText, ImageURL: o.ImageURL}
+	}
+	for _, ex := range qv.Examples {
+		out.Examples = append(out.Examples, dto.CodeExampleView(ex))
+	}
+	return out
+}
+
 func errJSON(code, msg string) gin.H { return gin.H{"error": gin.H{"code": code, "message": msg}} }
 
 func toDTOAttemptView(av AttemptView) dto.AttemptView {
-	return dto.AttemptView{
+	out := dto.AttemptView{
 		AttemptID:    av.AttemptID,
 		AssignmentID: av.AssignmentID,
 		Status:       av.Status,
@@ -632,10 +849,56 @@ func toDTOAttemptView(av AttemptView) dto.AttemptView {
 			RevealSolutions:      av.Policy.RevealSolutions,
 		},
 	}
+	if av.Section != nil {
+		sec := dto.SectionView(*av.Section)
+		out.Section = &sec
+	}
+	if len(av.Progress) > 0 {
+		out.Progress = make([]dto.QuestionProgressView, 0, len(av.Progress))
+		for _, p := range av.Progress {
+			out.Progress = append(out.Progress, dto.QuestionProgressView{
+				Index:      p.Index,
+				QuestionID: p.QuestionID,
+				Answered:   p.Answered,
+			})
+		}
+	}
+	return out
+}
+
+func toDTOSavedAnswer(p *AnswerPayload) *dto.SavedAnswerView {
+	if p == nil {
+		return nil
+	}
+	out := &dto.SavedAnswerView{Kind: answerKindToString(p.Kind, "")}
+	switch p.Kind {
+	case AnswerSingle:
+		out.Selected = []int{p.Single}
+	case AnswerMulti:
+		out.Selected = append([]int(nil), p.Multi...)
+	case AnswerText:
+		out.Text = p.Text
+	case AnswerCode:
+		if p.Code != nil {
+			out.Code = &dto.CodeAnswerView{Lang: p.Code.Lang, Body: p.Code.Body}
+		}
+	}
+	return out
 }
 
 func writeDomainErr(c *gin.Context, err error) {
+	var fieldErr *FieldValidationError
 	switch {
+	case errors.As(err, &fieldErr):
+		missing := fieldErr.Missing
+		if missing == nil {
+			missing = []string{}
+		}
+		body := gin.H{"code": "invalid_fields", "message": err.Error(), "missing": missing}
+		if len(fieldErr.Invalid) > 0 {
+			body["invalid"] = fieldErr.Invalid
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": body})
 	case errors.Is(err, ErrClosed):
 		c.JSON(http.StatusGone, errJSON("attempt_closed", err.Error()))
 	case errors.Is(err, ErrVersionMismatch):
@@ -652,6 +915,28 @@ func writeDomainErr(c *gin.Context, err error) {
 		c.JSON(http.StatusGone, errJSON("question_time_limit", err.Error()))
 	case errors.Is(err, ErrAssignmentNotFound):
 		c.JSON(http.StatusNotFound, errJSON("assignment_not_found", err.Error()))
+	case errors.Is(err, ErrNavigationDisabled):
+		c.JSON(http.StatusForbidden, errJSON("navigation_disabled", err.Error()))
+	case errors.Is(err, ErrAnswerLocked):
+		c.JSON(http.StatusConflict, errJSON("answer_locked", err.Error()))
+	case errors.Is(err, ErrQuestionNotInPlan):
+		c.JSON(http.StatusNotFound, errJSON("question_not_found", err.Error()))
+	case errors.Is(err, ErrAssignmentClosed):
+		c.JSON(http.StatusForbidden, errJSON("assignment_closed", err.Error()))
+	case errors.Is(err, ErrNotAvailable):
+		c.JSON(http.StatusForbidden, errJSON("not_available", err.Error()))
+	case errors.Is(err, ErrSectionTimeLimit):
+		c.JSON(http.StatusConflict, errJSON("section_time_limit", err.Error()))
+	case errors.Is(err, ErrOutsideSection):
+		c.JSON(http.StatusForbidden, errJSON("outside_section", err.Error()))
+	case errors.Is(err, ErrGradingJobNotFound):
+		c.JSON(http.StatusNotFound, errJSON("job_not_found", err.Error()))
+	case errors.Is(err, ErrNoSuggestion):
+		c.JSON(http.StatusConflict, errJSON("no_suggestion", err.Error()))
+	case errors.Is(err, ErrGraderNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, errJSON("ai_grading_unavailable", err.Error()))
+	case errors.Is(err, ErrGradingFailed):
+		c.JSON(http.StatusBadGateway, errJSON("ai_grading_failed", err.Error()))
 	default:
 		c.JSON(http.StatusInternalServerError, errJSON("internal", err.Error()))
 	}
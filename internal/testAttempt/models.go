@@ -19,6 +19,11 @@ var (
 	ErrAssignmentNotFound = errors.New("assignment not found")
 	ErrMaxAttempts        = errors.New("max attempts reached")
 	ErrQuestionTimeLimit  = errors.New("question time limit exceeded")
+	ErrNavigationDisabled = errors.New("navigation disabled")
+	ErrAnswerLocked       = errors.New("answer locked")
+	ErrQuestionNotInPlan  = errors.New("question not in plan")
+	ErrAssignmentClosed   = errors.New("assignment closed")
+	ErrNotAvailable       = errors.New("assignment not available")
 )
 
 type AttemptStatus string
@@ -95,10 +100,21 @@ type Answer struct {
 	Payload    AnswerPayload
 	IsCorrect  *bool
 	Score      *float64
+	// CodeResults holds per-test-case outcomes of an executed code answer.
+	CodeResults []CodeCaseResult
+	// Suggestion is the grader's proposal; GradedBy, GradedAt and Decision
+	// record the teacher who set Score by hand.
+	Suggestion *GradeSuggestion
+	GradedBy   *UserID
+	GradedAt   *time.Time
+	Decision   string
 }
 
 func (a Answer) deepCopy() Answer {
 	cp := a
+	if a.CodeResults != nil {
+		cp.CodeResults = append([]CodeCaseResult(nil), a.CodeResults...)
+	}
 	if a.Payload.Multi != nil {
 		cp.Payload.Multi = append([]int(nil), a.Payload.Multi...)
 	}
@@ -106,6 +122,10 @@ func (a Answer) deepCopy() Answer {
 		c := *a.Payload.Code
 		cp.Payload.Code = &c
 	}
+	if a.Suggestion != nil {
+		sg := *a.Suggestion
+		cp.Suggestion = &sg
+	}
 	return cp
 }
 
@@ -135,6 +155,9 @@ type Attempt struct {
 	cursor       int // index for next question
 	answers      map[QuestionID]Answer
 	totalVisible int // total questions in the plan
+
+	sections         []Section
+	sectionStartedAt *time.Time
 }
 
 func (a *Attempt) Policy() AttemptPolicy {
@@ -172,6 +195,8 @@ func (a *Attempt) InitializePlan(order []QuestionID) {
 	a.totalVisible = len(cp)
 	a.cursor = 0
 	a.questionOpenedAt = nil
+	a.sections = nil
+	a.sectionStartedAt = nil
 }
 
 func (a *Attempt) ID() AttemptID            { return a.id }
@@ -260,25 +285,18 @@ func (a *Attempt) Plan() []QuestionID {
 }
 
 func (a *Attempt) NextQuestionID(now time.Time) (QuestionID, error) {
+	if a.status != StatusActive {
+		return "", fmt.Errorf("%w: status=%s", ErrClosed, a.status)
+	}
 	if a.exceeded(now.UTC()) {
-		if a.status != StatusExpired {
-			dl := a.deadline()
-			a.status = StatusExpired
-			a.expiredAt = &dl
-		}
-		return "", fmt.Errorf("%w: attempt is %s", ErrClosed, a.status)
+		return "", fmt.Errorf("%w: attempt is overdue", ErrClosed)
 	}
 	if a.policy.QuestionTimeLimit > 0 && a.questionOpenedAt != nil {
 		if now.UTC().Sub(*a.questionOpenedAt) > a.policy.QuestionTimeLimit {
-			a.status = StatusExpired
-			t := now.UTC()
-			a.expiredAt = &t
 			return "", ErrQuestionTimeLimit
 		}
 	}
-	if a.status != StatusActive {
-		return "", fmt.Errorf("%w: status=%s", ErrClosed, a.status)
-	}
+	a.closeLapsedSection(now.UTC())
 	if a.cursor >= len(a.order) {
 		return "", ErrNoMoreQuestions
 	}
@@ -291,18 +309,14 @@ func (a *Attempt) NextQuestionID(now time.Time) (QuestionID, error) {
 	} else {
 		a.questionOpenedAt = nil
 	}
+	a.openSection(now.UTC())
 	return qid, nil
 }
 
 func (a *Attempt) AnswerCurrent(clientVersion int, now time.Time, payload AnswerPayload) (int, QuestionID, error) {
 	now = now.UTC()
-	if a.exceeded(now) {
-		if a.status != StatusExpired {
-			dl := a.deadline()
-			a.status = StatusExpired
-			a.expiredAt = &dl
-		}
-		return a.version, "", fmt.Errorf("%w: attempt is %s", ErrClosed, a.status)
+	if a.status == StatusActive && a.exceeded(now) {
+		return a.version, "", fmt.Errorf("%w: attempt is overdue", ErrClosed)
 	}
 	if a.status != StatusActive {
 		return a.version, "", fmt.Errorf("%w: status=%s", ErrClosed, a.status)
@@ -313,43 +327,139 @@ func (a *Attempt) AnswerCurrent(clientVersion int, now time.Time, payload Answer
 	if err := payload.Validate(); err != nil {
 		return a.version, "", err
 	}
+	if a.closeLapsedSection(now) {
+		return a.version, "", ErrSectionTimeLimit
+	}
 	if a.cursor >= len(a.order) {
 		return a.version, "", ErrNoMoreQuestions
 	}
 	if a.policy.QuestionTimeLimit > 0 && a.questionOpenedAt != nil {
 		if now.Sub(*a.questionOpenedAt) > a.policy.QuestionTimeLimit {
-			a.status = StatusExpired
-			t := now
-			a.expiredAt = &t
 			return a.version, "", ErrQuestionTimeLimit
 		}
 	}
 	qid := a.order[a.cursor]
 	a.answers[qid] = Answer{QuestionID: qid, Payload: payload}
-	a.cursor++
+	a.advanceCursor()
 	a.version++
-	a.questionOpenedAt = nil
 	return a.version, qid, nil
 }
 
-func (a *Attempt) Submit(clientVersion int, now time.Time, score, max float64) (int, error) {
+// QuestionAt moves the cursor to the question at the given plan index.
+// Unless the policy is navigable only the current question can be opened.
+func (a *Attempt) QuestionAt(index int, now time.Time) (QuestionID, error) {
 	now = now.UTC()
-	if a.exceeded(now) && a.status == StatusActive {
-		dl := a.deadline()
-		a.status = StatusExpired
-		a.expiredAt = &dl
+	if a.status == StatusActive && a.exceeded(now) {
+		return "", fmt.Errorf("%w: attempt is overdue", ErrClosed)
+	}
+	if a.status != StatusActive {
+		return "", fmt.Errorf("%w: status=%s", ErrClosed, a.status)
+	}
+	a.closeLapsedSection(now)
+	if index < 0 || index >= len(a.order) {
+		return "", fmt.Errorf("%w: index=%d", ErrQuestionNotInPlan, index)
+	}
+	if !a.policy.navigable() && index != a.cursor {
+		return "", ErrNavigationDisabled
+	}
+	if !a.sameSection(index) {
+		return "", fmt.Errorf("%w: index=%d", ErrOutsideSection, index)
+	}
+	if index != a.cursor {
+		a.setCursor(index)
+	}
+	if a.policy.QuestionTimeLimit > 0 {
+		if a.questionOpenedAt != nil && now.Sub(*a.questionOpenedAt) > a.policy.QuestionTimeLimit {
+			return "", ErrQuestionTimeLimit
+		}
+		if a.questionOpenedAt == nil {
+			t := now
+			a.questionOpenedAt = &t
+		}
+	}
+	a.openSection(now)
+	return a.order[index], nil
+}
+
+// AnswerQuestion stores an answer for any question of the plan. Answering a
+// question other than the current one requires a navigable policy, and with
+// LockAnswerOnConfirm an existing answer cannot be replaced.
+func (a *Attempt) AnswerQuestion(clientVersion int, now time.Time, qid QuestionID, payload AnswerPayload) (int, error) {
+	now = now.UTC()
+	if a.status == StatusActive && a.exceeded(now) {
+		return a.version, fmt.Errorf("%w: attempt is overdue", ErrClosed)
 	}
 	if a.status != StatusActive {
-		return a.version, fmt.Errorf("%w: submit from status=%s is not allowed", ErrInvalidState, a.status)
+		return a.version, fmt.Errorf("%w: status=%s", ErrClosed, a.status)
 	}
 	if clientVersion != a.version {
 		return a.version, fmt.Errorf("%w: have=%d want=%d", ErrVersionMismatch, a.version, clientVersion)
 	}
-	if err := validateScores(score, max); err != nil {
+	if err := payload.Validate(); err != nil {
+		return a.version, err
+	}
+	if a.closeLapsedSection(now) {
+		return a.version, ErrSectionTimeLimit
+	}
+	idx := a.planIndex(qid)
+	if idx < 0 {
+		return a.version, fmt.Errorf("%w: %s", ErrQuestionNotInPlan, qid)
+	}
+	if !a.policy.navigable() && idx != a.cursor {
+		return a.version, ErrNavigationDisabled
+	}
+	if !a.sameSection(idx) {
+		return a.version, fmt.Errorf("%w: %s", ErrOutsideSection, qid)
+	}
+	if _, answered := a.answers[qid]; answered && a.policy.LockAnswerOnConfirm {
+		return a.version, fmt.Errorf("%w: %s", ErrAnswerLocked, qid)
+	}
+	if a.policy.QuestionTimeLimit > 0 && a.questionOpenedAt != nil {
+		if now.Sub(*a.questionOpenedAt) > a.policy.QuestionTimeLimit {
+			return a.version, ErrQuestionTimeLimit
+		}
+	}
+	a.answers[qid] = Answer{QuestionID: qid, Payload: payload}
+	if idx == a.cursor {
+		a.advanceCursor()
+	}
+	a.version++
+	return a.version, nil
+}
+
+type QuestionProgress struct {
+	Index      int
+	QuestionID QuestionID
+	Answered   bool
+}
+
+// Progress reports every question of the plan together with whether it has
+// an answer, so skipped questions can be found.
+func (a *Attempt) Progress() []QuestionProgress {
+	out := make([]QuestionProgress, 0, len(a.order))
+	for i, qid := range a.order {
+		_, answered := a.answers[qid]
+		out = append(out, QuestionProgress{Index: i, QuestionID: qid, Answered: answered})
+	}
+	return out
+}
+
+func (a *Attempt) planIndex(qid QuestionID) int {
+	for i, id := range a.order {
+		if id == qid {
+			return i
+		}
+	}
+	return -1
+}
+
+func (a *Attempt) Submit(clientVersion int, now time.Time, score, max float64) (int, error) {
+	now = now.UTC()
+	if err := a.CheckSubmit(clientVersion, now); err != nil {
 		return a.version, err
 	}
-	if a.policy.RequireAllAnswered && len(a.answers) < a.totalVisible {
-		return a.version, fmt.Errorf("%w: all questions must be answered", ErrInvalidState)
+	if err := validateScores(score, max); err != nil {
+		return a.version, err
 	}
 	a.score, a.maxScore = score, max
 	a.status = StatusSubmitted
@@ -359,6 +469,46 @@ func (a *Attempt) Submit(clientVersion int, now time.Time, score, max float64) (
 	return a.version, nil
 }
 
+// CheckSubmit reports whether Submit would accept the attempt, so that work
+// needed for scoring is only done for an attempt that is about to close.
+func (a *Attempt) CheckSubmit(clientVersion int, now time.Time) error {
+	now = now.UTC()
+	if a.exceeded(now) && a.status == StatusActive {
+		dl := a.deadline()
+		a.status = StatusExpired
+		a.expiredAt = &dl
+	}
+	if a.status != StatusActive {
+		return fmt.Errorf("%w: submit from status=%s is not allowed", ErrInvalidState, a.status)
+	}
+	if clientVersion != a.version {
+		return fmt.Errorf("%w: have=%d want=%d", ErrVersionMismatch, a.version, clientVersion)
+	}
+	a.closeLapsedSection(now)
+	if a.policy.RequireAllAnswered && a.hasOpenUnanswered() {
+		return fmt.Errorf("%w: all questions must be answered", ErrInvalidState)
+	}
+	return nil
+}
+
+// hasOpenUnanswered reports unanswered questions that can still be reached;
+// questions of sections that are already closed no longer count.
+func (a *Attempt) hasOpenUnanswered() bool {
+	from := 0
+	if len(a.sections) > 0 {
+		from = len(a.order)
+		if i := a.sectionAt(a.cursor); i >= 0 {
+			from, _ = a.sectionBounds(i)
+		}
+	}
+	for _, qid := range a.order[from:] {
+		if _, ok := a.answers[qid]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *Attempt) Cancel(clientVersion int, now time.Time) (int, error) {
 	now = now.UTC()
 	if a.exceeded(now) && a.status == StatusActive {
@@ -377,6 +527,49 @@ func (a *Attempt) Cancel(clientVersion int, now time.Time) (int, error) {
 	return a.version, nil
 }
 
+// OverdueAt reports when an active attempt ran out of time, either because
+// the attempt deadline passed or the current question stayed open too long.
+// Methods that find the attempt overdue return ErrClosed or
+// ErrQuestionTimeLimit and leave it active, so that it is closed by Expire
+// with the score of the answers given so far.
+func (a *Attempt) OverdueAt(now time.Time) (time.Time, bool) {
+	if a.status != StatusActive {
+		return time.Time{}, false
+	}
+	now = now.UTC()
+	var (
+		at      time.Time
+		overdue bool
+	)
+	if a.exceeded(now) {
+		at, overdue = a.deadline(), true
+	}
+	if a.policy.QuestionTimeLimit > 0 && a.questionOpenedAt != nil {
+		limit := a.questionOpenedAt.Add(a.policy.QuestionTimeLimit)
+		if now.After(limit) && (!overdue || limit.Before(at)) {
+			at, overdue = limit, true
+		}
+	}
+	return at, overdue
+}
+
+// Expire closes an overdue attempt and records the score earned so far.
+func (a *Attempt) Expire(now time.Time, score, max, pending float64) (int, error) {
+	at, overdue := a.OverdueAt(now)
+	if !overdue {
+		return a.version, fmt.Errorf("%w: attempt is not overdue (status=%s)", ErrInvalidState, a.status)
+	}
+	if err := validateScores(score, max); err != nil {
+		return a.version, err
+	}
+	a.score, a.maxScore, a.pending = score, max, pending
+	a.status = StatusExpired
+	a.expiredAt = &at
+	a.questionOpenedAt = nil
+	a.version++
+	return a.version, nil
+}
+
 func (a *Attempt) deadline() time.Time {
 	if a.policy.MaxAttemptTime <= 0 {
 		return time.Time{}
@@ -412,6 +605,9 @@ type AttemptPolicy struct {
 	MaxAttempts         int
 }
 
+// ScoreRevealMode controls when a participant sees scores. With "always" the
+// running total is shown during the attempt too, but which answers earned it
+// only once the attempt is over.
 type ScoreRevealMode string
 
 const (
@@ -420,6 +616,34 @@ const (
 	ScoreRevealAlways      ScoreRevealMode = "always"
 )
 
+// navigable reports whether questions may be visited out of order. A
+// question time limit rules navigation out, since leaving a question and
+// coming back would restart its clock.
+func (p AttemptPolicy) navigable() bool {
+	return p.AllowNavigation && p.QuestionTimeLimit <= 0
+}
+
+func (p AttemptPolicy) scoreVisible(status AttemptStatus) bool {
+	switch p.RevealScoreMode {
+	case ScoreRevealNever:
+		return false
+	case ScoreRevealAlways:
+		return status != StatusCanceled
+	default:
+		return status == StatusSubmitted || status == StatusExpired
+	}
+}
+
+// answerScoresVisible reports whether per-question scores and test-case
+// results may be shown; never while the attempt can still be changed.
+func (p AttemptPolicy) answerScoresVisible(status AttemptStatus) bool {
+	return p.scoreVisible(status) && (status == StatusSubmitted || status == StatusExpired)
+}
+
+func (p AttemptPolicy) solutionsVisible(status AttemptStatus) bool {
+	return p.RevealSolutions && (status == StatusSubmitted || status == StatusExpired)
+}
+
 func validateScores(score, max float64) error {
 	if math.IsNaN(score) || math.IsNaN(max) || math.IsInf(score, 0) || math.IsInf(max, 0) {
 		return fmt.Errorf("%w: score/max must be finite numbers", ErrValidation)
@@ -0,0 +1,229 @@
+package testAttempt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newPlannedAttempt(policy AttemptPolicy, order ...QuestionID) (*Attempt, time.Time) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	a := NewAttempt("a1", "as1", "t1", 1, nil, nil, now, policy, 42, "", "")
+	a.InitializePlan(order)
+	return a, now
+}
+
+func TestAnswerQuestionNavigation(t *testing.T) {
+	a, now := newPlannedAttempt(AttemptPolicy{AllowNavigation: true}, "q1", "q2", "q3")
+
+	if _, err := a.QuestionAt(2, now); err != nil {
+		t.Fatalf("QuestionAt: %v", err)
+	}
+	v, err := a.AnswerQuestion(0, now, "q3", AnswerPayload{Kind: AnswerSingle, Single: 1})
+	if err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	if _, err := a.AnswerQuestion(v, now, "q1", AnswerPayload{Kind: AnswerSingle, Single: 0}); err != nil {
+		t.Fatalf("answer earlier question: %v", err)
+	}
+	if _, err := a.AnswerQuestion(v, now, "q2", AnswerPayload{Kind: AnswerSingle}); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("expected version mismatch, got %v", err)
+	}
+
+	progress := a.Progress()
+	if !progress[0].Answered || progress[1].Answered || !progress[2].Answered {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+}
+
+func TestAnswerQuestionPolicyEnforcement(t *testing.T) {
+	a, now := newPlannedAttempt(AttemptPolicy{}, "q1", "q2")
+	if _, err := a.QuestionAt(1, now); !errors.Is(err, ErrNavigationDisabled) {
+		t.Fatalf("expected navigation disabled, got %v", err)
+	}
+	if _, err := a.AnswerQuestion(0, now, "q2", AnswerPayload{Kind: AnswerSingle}); !errors.Is(err, ErrNavigationDisabled) {
+		t.Fatalf("expected navigation disabled, got %v", err)
+	}
+	if _, err := a.AnswerQuestion(0, now, "missing", AnswerPayload{Kind: AnswerSingle}); !errors.Is(err, ErrQuestionNotInPlan) {
+		t.Fatalf("expected question not in plan, got %v", err)
+	}
+
+	locked, now := newPlannedAttempt(AttemptPolicy{AllowNavigation: true, LockAnswerOnConfirm: true}, "q1", "q2")
+	v, err := locked.AnswerQuestion(0, now, "q1", AnswerPayload{Kind: AnswerSingle})
+	if err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	if _, err := locked.AnswerQuestion(v, now, "q1", AnswerPayload{Kind: AnswerSingle, Single: 1}); !errors.Is(err, ErrAnswerLocked) {
+		t.Fatalf("expected answer locked, got %v", err)
+	}
+}
+
+func TestQuestionTimeLimitSurvivesNavigation(t *testing.T) {
+	a, now := newPlannedAttempt(AttemptPolicy{AllowNavigation: true, QuestionTimeLimit: time.Minute}, "q1", "q2")
+	if _, err := a.QuestionAt(0, now); err != nil {
+		t.Fatalf("QuestionAt: %v", err)
+	}
+
+	later := now.Add(50 * time.Second)
+	if _, err := a.QuestionAt(1, later); !errors.Is(err, ErrNavigationDisabled) {
+		t.Fatalf("expected navigation disabled, got %v", err)
+	}
+	if _, err := a.AnswerQuestion(0, later, "q2", AnswerPayload{Kind: AnswerSingle}); !errors.Is(err, ErrNavigationDisabled) {
+		t.Fatalf("expected navigation disabled, got %v", err)
+	}
+	if _, err := a.QuestionAt(0, later); err != nil {
+		t.Fatalf("reopen current question: %v", err)
+	}
+	if _, err := a.AnswerQuestion(0, now.Add(2*time.Minute), "q1", AnswerPayload{Kind: AnswerSingle}); !errors.Is(err, ErrQuestionTimeLimit) {
+		t.Fatalf("expected question time limit, got %v", err)
+	}
+}
+
+func TestPolicyRevealVisibility(t *testing.T) {
+	cases := []struct {
+		mode   ScoreRevealMode
+		status AttemptStatus
+		want   bool
+	}{
+		{ScoreRevealNever, StatusSubmitted, false},
+		{ScoreRevealAfterSubmit, StatusActive, false},
+		{ScoreRevealAfterSubmit, StatusExpired, true},
+		{"", StatusSubmitted, true},
+		{ScoreRevealAlways, StatusActive, true},
+		{ScoreRevealAlways, StatusCanceled, false},
+	}
+	for _, tc := range cases {
+		p := AttemptPolicy{RevealScoreMode: tc.mode}
+		if got := p.scoreVisible(tc.status); got != tc.want {
+			t.Errorf("scoreVisible(%q, %s) = %v, want %v", tc.mode, tc.status, got, tc.want)
+		}
+	}
+	always := AttemptPolicy{RevealScoreMode: ScoreRevealAlways}
+	if always.answerScoresVisible(StatusActive) || !always.answerScoresVisible(StatusSubmitted) {
+		t.Error("per-question scores must stay hidden until the attempt is over")
+	}
+	if (AttemptPolicy{RevealSolutions: true}).solutionsVisible(StatusActive) {
+		t.Error("solutions must stay hidden while the attempt is active")
+	}
+}
+
+func TestExpireOverdueAttempt(t *testing.T) {
+	a, now := newPlannedAttempt(AttemptPolicy{MaxAttemptTime: 10 * time.Minute}, "q1", "q2")
+	if _, overdue := a.OverdueAt(now.Add(5 * time.Minute)); overdue {
+		t.Fatal("attempt should not be overdue before its deadline")
+	}
+	if _, err := a.Expire(now.Add(5*time.Minute), 0, 2, 0); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("expected invalid state, got %v", err)
+	}
+
+	if _, err := a.Expire(now.Add(time.Hour), 0, 0, 0); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected a zero maximum to be rejected like on submit, got %v", err)
+	}
+	if _, err := a.Expire(now.Add(time.Hour), 1, 2, 0); err != nil {
+		t.Fatalf("Expire: %v", err)
+	}
+	if a.Status() != StatusExpired {
+		t.Fatalf("expected expired status, got %s", a.Status())
+	}
+	if a.ExpiredAt() == nil || !a.ExpiredAt().Equal(now.Add(10*time.Minute)) {
+		t.Fatalf("expected expiry at deadline, got %v", a.ExpiredAt())
+	}
+	if score, max := a.Score(); score != 1 || max != 2 {
+		t.Fatalf("unexpected score %v/%v", score, max)
+	}
+}
+
+func TestCheckSubmit(t *testing.T) {
+	a, now := newPlannedAttempt(AttemptPolicy{RequireAllAnswered: true}, "q1")
+	if err := a.CheckSubmit(0, now); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("expected unanswered questions to block submit, got %v", err)
+	}
+	v, err := a.AnswerQuestion(0, now, "q1", AnswerPayload{Kind: AnswerSingle})
+	if err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	if err := a.CheckSubmit(v-1, now); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("expected version mismatch, got %v", err)
+	}
+	if _, err := a.Submit(v, now, 1, 1); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if err := a.CheckSubmit(a.Version(), now); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("expected a submitted attempt to be rejected, got %v", err)
+	}
+}
+
+func TestSectionTimeLimitMovesToNextSection(t *testing.T) {
+	a, now := newPlannedAttempt(AttemptPolicy{AllowNavigation: true})
+	a.InitializeSections([]Section{
+		{Name: "algebra", TimeLimit: 5 * time.Minute, Questions: []QuestionID{"a1", "a2"}},
+		{Name: "geometry", Questions: []QuestionID{"g1"}},
+	})
+
+	if qid, err := a.NextQuestionID(now); err != nil || qid != "a1" {
+		t.Fatalf("NextQuestionID = %s, %v", qid, err)
+	}
+	if _, err := a.QuestionAt(2, now); !errors.Is(err, ErrOutsideSection) {
+		t.Fatalf("expected outside section, got %v", err)
+	}
+	sec, ok := a.CurrentSection(now.Add(time.Minute))
+	if !ok || sec.Name != "algebra" || sec.TimeLeft != 4*time.Minute {
+		t.Fatalf("unexpected section progress: %+v", sec)
+	}
+
+	late := now.Add(6 * time.Minute)
+	if _, err := a.AnswerQuestion(0, late, "a2", AnswerPayload{Kind: AnswerSingle}); !errors.Is(err, ErrSectionTimeLimit) {
+		t.Fatalf("expected section time limit, got %v", err)
+	}
+	if qid, err := a.NextQuestionID(late); err != nil || qid != "g1" {
+		t.Fatalf("expected to move on to geometry, got %s, %v", qid, err)
+	}
+	if sec, ok := a.CurrentSection(late); !ok || sec.Index != 1 || sec.TimeLimit != 0 {
+		t.Fatalf("unexpected section progress: %+v", sec)
+	}
+}
+
+func TestAnsweringStaysInSectionUntilItIsDone(t *testing.T) {
+	a, now := newPlannedAttempt(AttemptPolicy{AllowNavigation: true})
+	a.InitializeSections([]Section{
+		{Name: "algebra", Questions: []QuestionID{"a1", "a2"}},
+		{Name: "geometry", Questions: []QuestionID{"g1"}},
+	})
+
+	if _, err := a.QuestionAt(1, now); err != nil {
+		t.Fatalf("QuestionAt: %v", err)
+	}
+	v, err := a.AnswerQuestion(0, now, "a2", AnswerPayload{Kind: AnswerSingle})
+	if err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	if a.Cursor() != 0 {
+		t.Fatalf("expected to go back to the skipped a1, cursor=%d", a.Cursor())
+	}
+	if _, err := a.AnswerQuestion(v, now, "a1", AnswerPayload{Kind: AnswerSingle}); err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	if qid, err := a.NextQuestionID(now); err != nil || qid != "g1" {
+		t.Fatalf("expected geometry once algebra is done, got %s, %v", qid, err)
+	}
+}
+
+func TestPlanSectionsDrawsPerSection(t *testing.T) {
+	tpl := &AssignmentTemplate{
+		Sections: []TemplateSection{
+			{Name: "algebra", DrawCount: 2, Shuffle: true},
+			{Name: "geometry", DrawCount: 1},
+		},
+		Questions: []TemplateQuestion{
+			{ID: "a1", Section: "algebra"}, {ID: "a2", Section: "algebra"}, {ID: "a3", Section: "algebra"},
+			{ID: "g1", Section: "geometry"}, {ID: "g2", Section: "geometry"},
+		},
+	}
+	sections := planSections(tpl, tpl.VisibleQuestions(), 42)
+	if len(sections) != 2 || len(sections[0].Questions) != 2 || len(sections[1].Questions) != 1 {
+		t.Fatalf("unexpected sections: %+v", sections)
+	}
+	if sections[1].Questions[0] != "g1" {
+		t.Fatalf("unshuffled section must keep template order, got %v", sections[1].Questions)
+	}
+}
@@ -0,0 +1,115 @@
+package testAttempt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidFields = errors.New("invalid participant fields")
+
+// FieldValidationError lists the participant fields that block an attempt
+// from starting: required keys left empty and values rejected by a validator.
+type FieldValidationError struct {
+	Missing []string
+	Invalid map[string]string // key -> reason
+}
+
+func (e *FieldValidationError) Error() string {
+	parts := make([]string, 0, 2)
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Invalid) > 0 {
+		keys := make([]string, 0, len(e.Invalid))
+		for k := range e.Invalid {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			keys[i] = k + " (" + e.Invalid[k] + ")"
+		}
+		parts = append(parts, "invalid: "+strings.Join(keys, ", "))
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidFields, strings.Join(parts, "; "))
+}
+
+func (e *FieldValidationError) Unwrap() error { return ErrInvalidFields }
+
+// check returns why value does not satisfy the field validators, or "".
+func (f AssignmentFieldSpec) check(value string) string {
+	if f.MaxLength > 0 && utf8.RuneCountInString(value) > f.MaxLength {
+		return fmt.Sprintf("must be at most %d characters", f.MaxLength)
+	}
+	if len(f.Options) > 0 && !slices.Contains(f.Options, value) {
+		return "must be one of: " + strings.Join(f.Options, ", ")
+	}
+	if f.Pattern != "" {
+		re, err := regexp.Compile(`^(?:` + f.Pattern + `)$`)
+		if err != nil || !re.MatchString(value) {
+			return "has an invalid format"
+		}
+	}
+	return ""
+}
+
+// validateParticipantFields checks normalized fields against the template
+// field specs.
+func validateParticipantFields(fields map[string]string, tpl *AssignmentTemplate) error {
+	if tpl == nil {
+		return nil
+	}
+	verr := &FieldValidationError{}
+	for _, f := range tpl.Fields {
+		val, ok := fields[f.Key]
+		if !ok {
+			if f.Required {
+				verr.Missing = append(verr.Missing, f.Key)
+			}
+			continue
+		}
+		if reason := f.check(val); reason != "" {
+			if verr.Invalid == nil {
+				verr.Invalid = make(map[string]string)
+			}
+			verr.Invalid[f.Key] = reason
+		}
+	}
+	if len(verr.Missing) == 0 && len(verr.Invalid) == 0 {
+		return nil
+	}
+	return verr
+}
+
+// prefillNameFields fills empty first_name/last_name fields of signed-in
+// participants from their account.
+func (s *Service) prefillNameFields(ctx context.Context, userID *UserID, fields map[string]string, tpl *AssignmentTemplate) {
+	if userID == nil || s.users == nil || tpl == nil {
+		return
+	}
+	wanted := assignmentFieldSet(tpl)
+	_, wantFirst := wanted["first_name"]
+	_, wantLast := wanted["last_name"]
+	if (!wantFirst || fields["first_name"] != "") && (!wantLast || fields["last_name"] != "") {
+		return
+	}
+	infos, err := s.users.Lookup(ctx, []UserID{*userID})
+	if err != nil {
+		return
+	}
+	info, ok := infos[*userID]
+	if !ok {
+		return
+	}
+	if name := strings.TrimSpace(info.FirstName); wantFirst && fields["first_name"] == "" && name != "" {
+		fields["first_name"] = name
+	}
+	if name := strings.TrimSpace(info.LastName); wantLast && fields["last_name"] == "" && name != "" {
+		fields["last_name"] = name
+	}
+}
@@ -0,0 +1,37 @@
+package testAttempt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateParticipantFields(t *testing.T) {
+	tpl := &AssignmentTemplate{Fields: []AssignmentFieldSpec{
+		{Key: "first_name", Required: true},
+		{Key: "last_name", Required: true},
+		{Key: "group", Pattern: `[A-Z]{2}-\d{2}`, MaxLength: 5},
+		{Key: "track", Options: []string{"math", "physics"}},
+	}}
+
+	err := validateParticipantFields(normalizeParticipantFields(map[string]string{
+		"first_name": "  ",
+		"group":      "ab-12",
+		"track":      "math",
+	}, tpl), tpl)
+	var fieldErr *FieldValidationError
+	if !errors.As(err, &fieldErr) || !errors.Is(err, ErrInvalidFields) {
+		t.Fatalf("expected field validation error, got %v", err)
+	}
+	if !reflect.DeepEqual(fieldErr.Missing, []string{"first_name", "last_name"}) {
+		t.Fatalf("unexpected missing keys: %v", fieldErr.Missing)
+	}
+	if _, ok := fieldErr.Invalid["group"]; !ok || len(fieldErr.Invalid) != 1 {
+		t.Fatalf("unexpected invalid fields: %v", fieldErr.Invalid)
+	}
+
+	ok := map[string]string{"first_name": "Ada", "last_name": "Lovelace", "group": "AB-12"}
+	if err := validateParticipantFields(ok, tpl); err != nil {
+		t.Fatalf("valid fields rejected: %v", err)
+	}
+}
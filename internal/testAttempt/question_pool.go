@@ -0,0 +1,54 @@
+package testAttempt
+
+import "math/rand"
+
+// QuestionPool is a set of template questions from which Count are drawn at
+// random for every attempt. Questions not listed in any pool are always asked.
+type QuestionPool struct {
+	Count       int
+	QuestionIDs []QuestionID
+}
+
+// drawFromPools narrows the visible questions to the fixed ones plus a random
+// draw from each pool. The draw is deterministic for a given seed and never
+// picks the same question twice, even when pools overlap.
+func drawFromPools(vis []VisibleQuestion, pools []QuestionPool, seed int64) []VisibleQuestion {
+	if len(pools) == 0 {
+		return vis
+	}
+	pooled := make(map[QuestionID]struct{})
+	for _, p := range pools {
+		for _, id := range p.QuestionIDs {
+			pooled[id] = struct{}{}
+		}
+	}
+	selected := make(map[QuestionID]struct{}, len(vis))
+	for _, q := range vis {
+		if _, ok := pooled[QuestionID(q.ID)]; !ok {
+			selected[QuestionID(q.ID)] = struct{}{}
+		}
+	}
+	r := rand.New(rand.NewSource(seed))
+	for _, p := range pools {
+		candidates := make([]QuestionID, 0, len(p.QuestionIDs))
+		for _, id := range p.QuestionIDs {
+			if _, taken := selected[id]; !taken {
+				candidates = append(candidates, id)
+			}
+		}
+		r.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+		if p.Count < len(candidates) {
+			candidates = candidates[:p.Count]
+		}
+		for _, id := range candidates {
+			selected[id] = struct{}{}
+		}
+	}
+	out := make([]VisibleQuestion, 0, len(selected))
+	for _, q := range vis {
+		if _, ok := selected[QuestionID(q.ID)]; ok {
+			out = append(out, q)
+		}
+	}
+	return out
+}
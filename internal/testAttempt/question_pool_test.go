@@ -0,0 +1,51 @@
+package testAttempt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDrawFromPools(t *testing.T) {
+	vis := []VisibleQuestion{{ID: "fixed"}, {ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	pools := []QuestionPool{
+		{Count: 2, QuestionIDs: []QuestionID{"a", "b", "c"}},
+		{Count: 2, QuestionIDs: []QuestionID{"c", "d"}},
+	}
+
+	got := drawFromPools(vis, pools, 7)
+	if len(got) != 5 {
+		t.Fatalf("expected fixed question plus 4 drawn, got %d: %+v", len(got), got)
+	}
+	if got[0].ID != "fixed" {
+		t.Fatalf("fixed question must always be included, got %+v", got)
+	}
+	seen := map[string]bool{}
+	for _, q := range got {
+		if seen[q.ID] {
+			t.Fatalf("question %s drawn twice", q.ID)
+		}
+		seen[q.ID] = true
+	}
+	if again := drawFromPools(vis, pools, 7); !reflect.DeepEqual(got, again) {
+		t.Fatalf("draw must be deterministic per seed: %+v vs %+v", got, again)
+	}
+}
+
+func TestScoreAttemptLeavesOutUndrawnQuestions(t *testing.T) {
+	single := func(id QuestionID) TemplateQuestion {
+		return TemplateQuestion{ID: id, Type: "single", Options: []TemplateOption{{ID: "o0"}, {ID: "o1"}}}
+	}
+	questions := []TemplateQuestion{single("fixed"), single("a"), single("b")}
+	a, _ := newPlannedAttempt(AttemptPolicy{}, "fixed", "a")
+
+	pooled := &AssignmentTemplate{Questions: questions, Pools: []QuestionPool{{Count: 1, QuestionIDs: []QuestionID{"a", "b"}}}}
+	if _, max, _, err := scoreAttempt(a, pooled.QuestionsForScoring()); err != nil || max != 2 {
+		t.Fatalf("expected the question not drawn to be left out, got max %v err %v", max, err)
+	}
+
+	// A plan cut short by MaxQuestions is scored against every question.
+	truncated := &AssignmentTemplate{Questions: questions}
+	if _, max, _, err := scoreAttempt(a, truncated.QuestionsForScoring()); err != nil || max != 3 {
+		t.Fatalf("expected every question to count, got max %v err %v", max, err)
+	}
+}
@@ -29,6 +29,8 @@ func RehydrateAttempt(
 	clientIP string,
 	clientFingerprint string,
 	questionOpenedAt *time.Time,
+	sections []Section,
+	sectionStartedAt *time.Time,
 ) (*Attempt, error) {
 	if version < 0 {
 		return nil, fmt.Errorf("version must be non-negative: %d", version)
@@ -95,6 +97,21 @@ func RehydrateAttempt(
 		t := questionOpenedAt.UTC()
 		a.questionOpenedAt = &t
 	}
+	if len(sections) > 0 {
+		planned := 0
+		for _, sec := range sections {
+			planned += len(sec.Questions)
+			sec.Questions = append([]QuestionID(nil), sec.Questions...)
+			a.sections = append(a.sections, sec)
+		}
+		if planned != len(order) {
+			return nil, fmt.Errorf("sections cover %d questions, plan has %d", planned, len(order))
+		}
+	}
+	if sectionStartedAt != nil {
+		t := sectionStartedAt.UTC()
+		a.sectionStartedAt = &t
+	}
 
 	if a.status != StatusActive {
 		if a.cursor > a.totalVisible {
@@ -6,11 +6,29 @@ type Repository interface {
 	Create(ctx context.Context, a *Attempt) (AttemptID, error)
 	GetByID(ctx context.Context, id AttemptID) (*Attempt, error)
 	GetActiveByUserAndAssignment(ctx context.Context, user UserID, assignment AssignmentID) (*Attempt, error)
+	// ListActiveTimed returns active attempts that have an attempt deadline
+	// or an open question timer, i.e. the ones that can become overdue.
+	ListActiveTimed(ctx context.Context) ([]*Attempt, error)
+	// ListClosedUngraded returns submitted and expired attempts with answers
+	// that have neither a score nor code results yet.
+	ListClosedUngraded(ctx context.Context) ([]*Attempt, error)
 
 	SaveAnswer(ctx context.Context, a *Attempt, answered QuestionID) error
+	// SaveSuggestion stores an AI grading suggestion without touching the
+	// rest of the answer, which a teacher may have graded meanwhile.
+	SaveSuggestion(ctx context.Context, id AttemptID, question QuestionID, suggestion *GradeSuggestion) error
 	SaveProgress(ctx context.Context, a *Attempt) error
 	Submit(ctx context.Context, a *Attempt) error
+	// SaveCodeResults stores test-case results of a closed attempt together
+	// with its updated score.
+	SaveCodeResults(ctx context.Context, a *Attempt) error
 	Cancel(ctx context.Context, a *Attempt) error
+	// AnonymiseUser strips the personal data of a deleted user from their
+	// attempts. Answers and scores stay for the teachers' reports.
+	AnonymiseUser(ctx context.Context, user UserID) error
+	// Expire persists an expired attempt unless it left the active state or
+	// was changed since it was loaded, and reports whether it did.
+	Expire(ctx context.Context, a *Attempt) (bool, error)
 
 	ListSummariesByAssignments(ctx context.Context, assignments []AssignmentID) ([]AttemptSummary, error)
 	CountAttempts(ctx context.Context, filter AttemptCountFilter) (AttemptCounts, error)
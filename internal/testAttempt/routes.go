@@ -2,7 +2,10 @@ package testAttempt
 
 import "github.com/gin-gonic/gin"
 
-func RegisterRoutes(v1 gin.IRouter, h *Handlers, optionalAuth gin.HandlerFunc, authRequired gin.HandlerFunc) {
+// RegisterRoutes registers the attempt routes. Taking an attempt is open to
+// every role and to guests the test allows; reviewing and grading attempts
+// needs authRequired followed by requireGrader.
+func RegisterRoutes(v1 gin.IRouter, h *Handlers, optionalAuth gin.HandlerFunc, authRequired gin.HandlerFunc, requireGrader gin.HandlerFunc) {
 	open := v1.Group("/attempts")
 	if optionalAuth != nil {
 		open.Use(optionalAuth)
@@ -11,18 +14,27 @@ func RegisterRoutes(v1 gin.IRouter, h *Handlers, optionalAuth gin.HandlerFunc, a
 		open.POST("/start", h.Start)
 		open.GET("/:id/question", h.NextQuestion)
 		open.POST("/:id/answer", h.Answer)
+		open.GET("/:id/questions/:index", h.QuestionAt)
+		open.POST("/:id/answers/:question_id", h.AnswerQuestion)
 		open.POST("/:id/submit", h.Submit)
 		open.POST("/:id/cancel", h.Cancel)
+		open.GET("/:id/result", h.Result)
 	}
 
 	secured := v1.Group("/attempts")
 	if authRequired != nil {
 		secured.Use(authRequired)
 	}
+	if requireGrader != nil {
+		secured.Use(requireGrader)
+	}
 	{
 		secured.GET("", h.ListByAssignment)
 		secured.GET("/export", h.Export)
+		secured.POST("/ai-grade", h.AIGradeAssignment)
+		secured.GET("/ai-grade/:jobID", h.AIGradeJob)
 		secured.GET("/:id/details", h.Details)
 		secured.POST("/:id/grade", h.Grade)
+		secured.POST("/:id/ai-grade", h.AIGrade)
 	}
 }
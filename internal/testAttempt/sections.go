@@ -0,0 +1,206 @@
+package testAttempt
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+var (
+	ErrSectionTimeLimit = errors.New("section time limit exceeded")
+	ErrOutsideSection   = errors.New("question outside current section")
+)
+
+// Section is a contiguous part of an attempt plan. Sections are taken in
+// order: navigation stays within the current one, and once its TimeLimit
+// runs out the attempt moves on to the next section.
+type Section struct {
+	Name      string
+	TimeLimit time.Duration
+	Questions []QuestionID
+}
+
+// TemplateSection describes how a section of an assignment is drawn.
+type TemplateSection struct {
+	Name      string
+	DrawCount int
+	Shuffle   bool
+	TimeLimit time.Duration
+}
+
+// SectionProgress describes the section holding the attempt cursor.
+type SectionProgress struct {
+	Index     int
+	Name      string
+	Total     int
+	Start     int
+	End       int
+	TimeLimit time.Duration
+	TimeLeft  time.Duration
+}
+
+// InitializeSections builds the plan section by section.
+func (a *Attempt) InitializeSections(sections []Section) {
+	order := make([]QuestionID, 0)
+	cp := make([]Section, 0, len(sections))
+	for _, sec := range sections {
+		order = append(order, sec.Questions...)
+		sec.Questions = append([]QuestionID(nil), sec.Questions...)
+		cp = append(cp, sec)
+	}
+	a.InitializePlan(order)
+	a.sections = cp
+}
+
+func (a *Attempt) Sections() []Section {
+	out := make([]Section, 0, len(a.sections))
+	for _, sec := range a.sections {
+		sec.Questions = append([]QuestionID(nil), sec.Questions...)
+		out = append(out, sec)
+	}
+	return out
+}
+
+func (a *Attempt) SectionStartedAt() *time.Time {
+	if a.sectionStartedAt == nil {
+		return nil
+	}
+	cp := *a.sectionStartedAt
+	return &cp
+}
+
+// CurrentSection reports the section holding the cursor; ok is false for
+// attempts without sections or once every section is done.
+func (a *Attempt) CurrentSection(now time.Time) (SectionProgress, bool) {
+	i := a.sectionAt(a.cursor)
+	if i < 0 {
+		return SectionProgress{}, false
+	}
+	start, end := a.sectionBounds(i)
+	sec := a.sections[i]
+	p := SectionProgress{
+		Index:     i,
+		Name:      sec.Name,
+		Total:     len(a.sections),
+		Start:     start,
+		End:       end,
+		TimeLimit: sec.TimeLimit,
+	}
+	if sec.TimeLimit > 0 {
+		p.TimeLeft = sec.TimeLimit
+		if a.sectionStartedAt != nil {
+			p.TimeLeft = max(sec.TimeLimit-now.UTC().Sub(*a.sectionStartedAt), 0)
+		}
+	}
+	return p, true
+}
+
+// sectionAt returns the index of the section holding plan position idx, or -1.
+func (a *Attempt) sectionAt(idx int) int {
+	start := 0
+	for i, sec := range a.sections {
+		end := start + len(sec.Questions)
+		if idx >= start && idx < end {
+			return i
+		}
+		start = end
+	}
+	return -1
+}
+
+func (a *Attempt) sectionBounds(i int) (int, int) {
+	start := 0
+	for j := 0; j < i; j++ {
+		start += len(a.sections[j].Questions)
+	}
+	return start, start + len(a.sections[i].Questions)
+}
+
+// setCursor moves the cursor, restarting the section clock when the move
+// leaves the current section.
+func (a *Attempt) setCursor(idx int) {
+	if a.sectionAt(idx) != a.sectionAt(a.cursor) {
+		a.sectionStartedAt = nil
+	}
+	a.cursor = idx
+	a.questionOpenedAt = nil
+}
+
+// openSection starts the clock of the current section when its first
+// question is shown.
+func (a *Attempt) openSection(now time.Time) {
+	if a.sectionStartedAt == nil && a.sectionAt(a.cursor) >= 0 {
+		t := now
+		a.sectionStartedAt = &t
+	}
+}
+
+// closeLapsedSection skips the rest of the current section once its time
+// limit has run out and reports whether it did.
+func (a *Attempt) closeLapsedSection(now time.Time) bool {
+	i := a.sectionAt(a.cursor)
+	if i < 0 || a.sections[i].TimeLimit <= 0 || a.sectionStartedAt == nil {
+		return false
+	}
+	if now.Sub(*a.sectionStartedAt) < a.sections[i].TimeLimit {
+		return false
+	}
+	_, end := a.sectionBounds(i)
+	a.setCursor(end)
+	return true
+}
+
+// advanceCursor moves on from an answered current question to the next
+// unanswered one of its section, coming back to questions skipped earlier,
+// and leaves the section only once all of its questions are answered.
+func (a *Attempt) advanceCursor() {
+	start, end := 0, len(a.order)
+	if i := a.sectionAt(a.cursor); i >= 0 {
+		start, end = a.sectionBounds(i)
+	}
+	for n := 1; n < end-start; n++ {
+		idx := start + (a.cursor-start+n)%(end-start)
+		if _, answered := a.answers[a.order[idx]]; !answered {
+			a.setCursor(idx)
+			return
+		}
+	}
+	a.setCursor(end)
+}
+
+// sameSection reports whether plan position idx may be visited from the
+// current cursor position.
+func (a *Attempt) sameSection(idx int) bool {
+	return len(a.sections) == 0 || a.sectionAt(idx) == a.sectionAt(a.cursor)
+}
+
+// planSections draws the questions of every template section. Questions are
+// taken in template order unless the section shuffles them, and DrawCount
+// limits how many are kept.
+func planSections(tpl *AssignmentTemplate, vis []VisibleQuestion, seed int64) []Section {
+	sectionOf := make(map[QuestionID]string, len(tpl.Questions))
+	for _, q := range tpl.Questions {
+		sectionOf[q.ID] = q.Section
+	}
+	byName := make(map[string][]QuestionID, len(tpl.Sections))
+	for _, q := range vis {
+		id := QuestionID(q.ID)
+		byName[sectionOf[id]] = append(byName[sectionOf[id]], id)
+	}
+	out := make([]Section, 0, len(tpl.Sections))
+	for i, def := range tpl.Sections {
+		ids := byName[def.Name]
+		if def.Shuffle {
+			r := rand.New(rand.NewSource(seed + int64(i)))
+			r.Shuffle(len(ids), func(x, y int) { ids[x], ids[y] = ids[y], ids[x] })
+		}
+		if def.DrawCount > 0 && len(ids) > def.DrawCount {
+			ids = ids[:def.DrawCount]
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		out = append(out, Section{Name: def.Name, TimeLimit: def.TimeLimit, Questions: ids})
+	}
+	return out
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"math/rand"
 	"strings"
 	"time"
@@ -32,6 +33,24 @@ type VisibleQuestion struct {
 	ImageURL     string
 	Weight       float64
 	Options      []VisibleOption
+	Examples     []CodeExample
+}
+
+// CodeExample is a non-hidden test case shown to participants of a code question.
+type CodeExample struct {
+	Stdin          string
+	ExpectedStdout string
+}
+
+// CodeExamples returns the test cases participants are allowed to see.
+func (k CodeAnswerKey) CodeExamples() []CodeExample {
+	var out []CodeExample
+	for _, tc := range k.TestCases {
+		if !tc.Hidden {
+			out = append(out, CodeExample{Stdin: tc.Stdin, ExpectedStdout: tc.ExpectedStdout})
+		}
+	}
+	return out
 }
 
 type QuestionForScoring struct {
@@ -39,6 +58,11 @@ type QuestionForScoring struct {
 	Type        string
 	Weight      float64
 	CorrectJSON []byte
+	// Solution is the teacher's reference answer or rubric, used by AI grading.
+	Solution string
+	// Drawn questions come from a pool or section draw and count only for
+	// attempts whose plan includes them.
+	Drawn bool
 }
 
 type AttemptMetadata struct {
@@ -63,6 +87,11 @@ type AssignmentDescriptor struct {
 	Title    string
 	Comment  string
 	Template *AssignmentTemplate
+
+	// AvailableFrom and AvailableUntil override the template window when set.
+	AvailableFrom  *time.Time
+	AvailableUntil *time.Time
+	Closed         bool
 }
 
 type AssignmentTemplate struct {
@@ -75,6 +104,8 @@ type AssignmentTemplate struct {
 	AvailableUntil *time.Time
 	Policy         AttemptPolicy
 	Questions      []TemplateQuestion
+	Pools          []QuestionPool
+	Sections       []TemplateSection
 	Fields         []AssignmentFieldSpec
 }
 
@@ -86,6 +117,11 @@ type TemplateQuestion struct {
 	CorrectOptions []int
 	Type           string
 	Weight         float64
+	Scoring        MultiScoringRule
+	TextAnswer     *TextAnswerKey
+	CodeAnswer     *CodeAnswerKey
+	Solution       string
+	Section        string
 	Options        []TemplateOption
 }
 
@@ -99,6 +135,10 @@ type AssignmentFieldSpec struct {
 	Key      string
 	Label    string
 	Required bool
+	// Optional validators applied to non-empty values.
+	Pattern   string
+	MaxLength int
+	Options   []string
 }
 
 func (tpl *AssignmentTemplate) VisibleQuestions() []VisibleQuestion {
@@ -119,6 +159,10 @@ func (tpl *AssignmentTemplate) VisibleQuestions() []VisibleQuestion {
 		for _, o := range q.Options {
 			opts = append(opts, VisibleOption(o))
 		}
+		var examples []CodeExample
+		if q.CodeAnswer != nil {
+			examples = q.CodeAnswer.CodeExamples()
+		}
 		out = append(out, VisibleQuestion{
 			ID:           string(q.ID),
 			Type:         qType,
@@ -126,6 +170,7 @@ func (tpl *AssignmentTemplate) VisibleQuestions() []VisibleQuestion {
 			ImageURL:     q.ImageURL,
 			Weight:       weight,
 			Options:      opts,
+			Examples:     examples,
 		})
 	}
 	return out
@@ -135,6 +180,12 @@ func (tpl *AssignmentTemplate) QuestionsForScoring() []QuestionForScoring {
 	if tpl == nil {
 		return nil
 	}
+	pooled := make(map[QuestionID]bool)
+	for _, p := range tpl.Pools {
+		for _, id := range p.QuestionIDs {
+			pooled[id] = true
+		}
+	}
 	out := make([]QuestionForScoring, 0, len(tpl.Questions))
 	for _, q := range tpl.Questions {
 		qType := q.Type
@@ -149,10 +200,13 @@ func (tpl *AssignmentTemplate) QuestionsForScoring() []QuestionForScoring {
 		if len(correct) == 0 && q.CorrectOption != 0 {
 			correct = []int{q.CorrectOption}
 		}
-		payload, _ := json.Marshal(map[string]any{
-			"selected": correct,
-		})
-		if qType == "text" || qType == "code" {
+		payload, _ := json.Marshal(choiceKey{Selected: correct, Scoring: q.Scoring})
+		switch {
+		case qType == "text" && q.TextAnswer != nil && len(q.TextAnswer.AcceptedAnswers) > 0:
+			payload, _ = json.Marshal(q.TextAnswer)
+		case qType == "code" && q.CodeAnswer != nil && len(q.CodeAnswer.TestCases) > 0:
+			payload, _ = json.Marshal(q.CodeAnswer)
+		case qType == "text" || qType == "code":
 			payload = nil
 		}
 		out = append(out, QuestionForScoring{
@@ -160,6 +214,8 @@ func (tpl *AssignmentTemplate) QuestionsForScoring() []QuestionForScoring {
 			Type:        qType,
 			Weight:      weight,
 			CorrectJSON: payload,
+			Solution:    q.Solution,
+			Drawn:       pooled[q.ID] || len(tpl.Sections) > 0,
 		})
 	}
 	return out
@@ -214,10 +270,17 @@ type Service struct {
 	clock       Clock
 	policy      Policy
 	users       UserDirectory
+	runner      CodeRunner
+	grader      AnswerGrader
+	gradingJobs *gradingJobRegistry
+	codeJobs    *codeJobs
 }
 
-func NewTestAttemptService(repo Repository, tests TestReadModel, assignments AssignmentReadModel, tx Transactor, clock Clock, policy Policy, users UserDirectory) *Service {
-	return &Service{repo: repo, tests: tests, assignments: assignments, tx: tx, clock: clock, policy: policy, users: users}
+// NewTestAttemptService wires the attempt use cases. runner may be nil, in
+// which case code answers are left for manual grading; grader may be nil, in
+// which case AI grading suggestions are unavailable.
+func NewTestAttemptService(repo Repository, tests TestReadModel, assignments AssignmentReadModel, tx Transactor, clock Clock, policy Policy, users UserDirectory, runner CodeRunner, grader AnswerGrader) *Service {
+	return &Service{repo: repo, tests: tests, assignments: assignments, tx: tx, clock: clock, policy: policy, users: users, runner: runner, grader: grader, gradingJobs: newGradingJobRegistry(), codeJobs: newCodeJobs()}
 }
 
 func (s *Service) getOwnedAssignmentDescriptor(ctx context.Context, requester UserID, assignmentID AssignmentID) (AssignmentDescriptor, error) {
@@ -236,10 +299,22 @@ func (s *Service) StartAttempt(ctx context.Context, userID *UserID, guestName *s
 	if err != nil {
 		return "", err
 	}
+	// A running attempt is resumed even once the assignment is closed or its
+	// window is over; only new attempts are refused.
+	if userID != nil {
+		if active, err := s.repo.GetActiveByUserAndAssignment(ctx, *userID, assignmentID); err == nil && active != nil && active.ID() != "" {
+			return active.ID(), nil
+		}
+	}
+
 	testID := assignment.TestID
 
 	template := assignment.Template
 	participantFields := normalizeParticipantFields(fields, template)
+	s.prefillNameFields(ctx, userID, participantFields, template)
+	if err := validateParticipantFields(participantFields, template); err != nil {
+		return "", err
+	}
 	if guestName != nil {
 		name := strings.TrimSpace(*guestName)
 		if name == "" {
@@ -264,6 +339,9 @@ func (s *Service) StartAttempt(ctx context.Context, userID *UserID, guestName *s
 		policy      AttemptPolicy
 	)
 
+	if assignment.Closed {
+		return "", ErrAssignmentClosed
+	}
 	if template != nil {
 		durationSec = template.DurationSec
 		from = template.AvailableFrom
@@ -280,18 +358,18 @@ func (s *Service) StartAttempt(ctx context.Context, userID *UserID, guestName *s
 	if err := s.policy.CanStartAttempt(ctx, userID, guestName, testID); err != nil {
 		return "", fmt.Errorf("%w: %v", ErrForbidden, err)
 	}
+	if assignment.AvailableFrom != nil {
+		from = assignment.AvailableFrom
+	}
+	if assignment.AvailableUntil != nil {
+		until = assignment.AvailableUntil
+	}
 	now := s.clock.Now()
 	if from != nil && now.Before(*from) {
-		return "", errors.New("test not yet available")
+		return "", fmt.Errorf("%w: test not yet available", ErrNotAvailable)
 	}
 	if until != nil && now.After(*until) {
-		return "", errors.New("test window expired")
-	}
-
-	if userID != nil {
-		if active, err := s.repo.GetActiveByUserAndAssignment(ctx, *userID, assignmentID); err == nil && active != nil && active.ID() != "" {
-			return active.ID(), nil
-		}
+		return "", fmt.Errorf("%w: test window expired", ErrNotAvailable)
 	}
 
 	if policy.MaxAttempts > 0 {
@@ -332,7 +410,7 @@ func (s *Service) StartAttempt(ctx context.Context, userID *UserID, guestName *s
 	}
 	var vis []VisibleQuestion
 	if template != nil {
-		vis = template.VisibleQuestions()
+		vis = drawFromPools(template.VisibleQuestions(), template.Pools, seed)
 	} else {
 		vis, err = s.tests.ListVisibleQuestions(ctx, string(testID))
 		if err != nil {
@@ -340,6 +418,15 @@ func (s *Service) StartAttempt(ctx context.Context, userID *UserID, guestName *s
 		}
 	}
 
+	a := NewAttempt(NewAttemptID(), assignmentID, testID, uid, guestName, participantFields, now, policy, seed, meta.ClientIP, meta.Fingerprint)
+
+	// Sections carry their own shuffle flag and draw count instead of the
+	// test-wide ShuffleQuestions and MaxQuestions.
+	if template != nil && len(template.Sections) > 0 {
+		a.InitializeSections(planSections(template, vis, seed))
+		return s.repo.Create(ctx, a)
+	}
+
 	var order []QuestionID
 	if policy.ShuffleQuestions {
 		order = shuffleQuestionIDs(vis, seed)
@@ -353,8 +440,6 @@ func (s *Service) StartAttempt(ctx context.Context, userID *UserID, guestName *s
 		order = order[:policy.MaxQuestions]
 	}
 
-	a := NewAttempt(NewAttemptID(), assignmentID, testID, uid, guestName, participantFields, now, policy, seed, meta.ClientIP, meta.Fingerprint)
-
 	a.InitializePlan(order)
 
 	return s.repo.Create(ctx, a)
@@ -376,8 +461,9 @@ func (s *Service) NextQuestion(ctx context.Context, requester *UserID, id Attemp
 	}
 	qid, err := a.NextQuestionID(now)
 	if err != nil {
-		if errors.Is(err, ErrQuestionTimeLimit) {
-			_ = s.repo.SaveProgress(ctx, a)
+		if _, overdue := a.OverdueAt(now); overdue {
+			// If closing fails the sweeper retries it.
+			_ = s.closeOverdue(ctx, a, now)
 		}
 		return attemptToView(a, now), QuestionView{}, err
 	}
@@ -421,7 +507,10 @@ func (s *Service) AnswerCurrent(ctx context.Context, requester *UserID, id Attem
 	now := s.clock.Now()
 	newVersion, qid, err := a.AnswerCurrent(version, now, payload)
 	if err != nil {
-		if errors.Is(err, ErrQuestionTimeLimit) {
+		if _, overdue := a.OverdueAt(now); overdue {
+			// If closing fails the sweeper retries it.
+			_ = s.closeOverdue(ctx, a, now)
+		} else if errors.Is(err, ErrSectionTimeLimit) {
 			_ = s.repo.SaveProgress(ctx, a)
 		}
 		return AttemptView{}, AnsweredView{}, err
@@ -437,6 +526,92 @@ func (s *Service) AnswerCurrent(ctx context.Context, requester *UserID, id Attem
 	return av, AnsweredView{QuestionID: string(qid)}, nil
 }
 
+func (s *Service) QuestionAt(ctx context.Context, requester *UserID, id AttemptID, index int) (AttemptView, QuestionView, *AnswerPayload, error) {
+	a, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return AttemptView{}, QuestionView{}, nil, err
+	}
+	if err := s.policy.CanModifyAttempt(ctx, requester, a); err != nil {
+		return AttemptView{}, QuestionView{}, nil, fmt.Errorf("%w: %v", ErrForbidden, err)
+	}
+
+	now := s.clock.Now()
+	descriptor, err := s.assignments.GetAssignment(ctx, a.Assignment())
+	if err != nil {
+		return AttemptView{}, QuestionView{}, nil, err
+	}
+	qid, err := a.QuestionAt(index, now)
+	if err != nil {
+		if _, overdue := a.OverdueAt(now); overdue {
+			// If closing fails the sweeper retries it.
+			_ = s.closeOverdue(ctx, a, now)
+		}
+		return attemptToView(a, now), QuestionView{}, nil, err
+	}
+
+	vis, err := s.getVisibleQuestions(ctx, descriptor, a.Test())
+	if err != nil {
+		return AttemptView{}, QuestionView{}, nil, err
+	}
+	var (
+		vq    VisibleQuestion
+		found bool
+	)
+	for _, q := range vis {
+		if q.ID == string(qid) {
+			vq, found = q, true
+			break
+		}
+	}
+	if !found {
+		return AttemptView{}, QuestionView{}, nil, errors.New("question not found in test")
+	}
+	options := vq.Options
+	if a.Policy().ShuffleAnswers {
+		options = shuffleOptions(vq.Options, a.Seed(), index)
+	}
+	if err := s.repo.SaveProgress(ctx, a); err != nil {
+		return AttemptView{}, QuestionView{}, nil, err
+	}
+
+	var saved *AnswerPayload
+	if ans, ok := a.Answers()[qid]; ok {
+		payload := ans.Payload
+		saved = &payload
+	}
+	return attemptToView(a, now), makeQuestionView(vq, options), saved, nil
+}
+
+func (s *Service) AnswerQuestion(ctx context.Context, requester *UserID, id AttemptID, questionID QuestionID, version int, payload AnswerPayload) (AttemptView, AnsweredView, error) {
+	a, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return AttemptView{}, AnsweredView{}, err
+	}
+	if err := s.policy.CanModifyAttempt(ctx, requester, a); err != nil {
+		return AttemptView{}, AnsweredView{}, fmt.Errorf("%w: %v", ErrForbidden, err)
+	}
+	now := s.clock.Now()
+	newVersion, err := a.AnswerQuestion(version, now, questionID, payload)
+	if err != nil {
+		if _, overdue := a.OverdueAt(now); overdue {
+			// If closing fails the sweeper retries it.
+			_ = s.closeOverdue(ctx, a, now)
+		} else if errors.Is(err, ErrSectionTimeLimit) {
+			_ = s.repo.SaveProgress(ctx, a)
+		}
+		return AttemptView{}, AnsweredView{}, err
+	}
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		return s.repo.SaveAnswer(ctx, a, questionID)
+	})
+	if err != nil {
+		return AttemptView{}, AnsweredView{}, err
+	}
+	av := attemptToView(a, now)
+	av.Version = newVersion
+	return av, AnsweredView{QuestionID: string(questionID)}, nil
+}
+
 func (s *Service) ListAssignmentAttempts(ctx context.Context, requester UserID, assignmentID AssignmentID) ([]AttemptSummary, error) {
 	descriptor, err := s.getOwnedAssignmentDescriptor(ctx, requester, assignmentID)
 	if err != nil {
@@ -506,11 +681,6 @@ func (s *Service) AttemptDetails(ctx context.Context, requester UserID, attemptI
 	if err != nil {
 		return AttemptDetails{}, err
 	}
-	vqByID := make(map[string]VisibleQuestion, len(visibleQuestions))
-	for _, q := range visibleQuestions {
-		vqByID[q.ID] = q
-	}
-
 	qTypes := make(map[QuestionID]string)
 	if descriptor.Template != nil {
 		for _, q := range descriptor.Template.Questions {
@@ -547,74 +717,123 @@ func (s *Service) AttemptDetails(ctx context.Context, requester UserID, attemptI
 			PendingScore: a.PendingScore(),
 			Participant:  buildParticipant(a, info),
 		},
-		Answers: make([]AnsweredQuestion, 0, a.Total()),
+		Answers: buildAnsweredQuestions(a, visibleQuestions, qTypes),
+	}
+	// Grading review is for the owner only, so it is not part of
+	// buildAnsweredQuestions, which also serves participant results.
+	answers := a.Answers()
+	for i := range result.Answers {
+		if ans, ok := answers[QuestionID(result.Answers[i].QuestionID)]; ok {
+			result.Answers[i].Suggestion = ans.Suggestion
+			result.Answers[i].GradedBy = ans.GradedBy
+			result.Answers[i].GradedAt = ans.GradedAt
+			result.Answers[i].Decision = ans.Decision
+		}
+	}
+
+	return result, nil
+}
+
+// AttemptResult returns the outcome of an attempt to its participant. Scores
+// and correct options are only included as far as the attempt policy allows.
+func (s *Service) AttemptResult(ctx context.Context, requester *UserID, attemptID AttemptID) (AttemptResult, error) {
+	a, err := s.repo.GetByID(ctx, attemptID)
+	if err != nil {
+		return AttemptResult{}, err
+	}
+	if err := s.policy.CanModifyAttempt(ctx, requester, a); err != nil {
+		return AttemptResult{}, fmt.Errorf("%w: %v", ErrForbidden, err)
+	}
+	descriptor, err := s.assignments.GetAssignment(ctx, a.Assignment())
+	if err != nil {
+		return AttemptResult{}, err
+	}
+	visibleQuestions, err := s.getVisibleQuestions(ctx, descriptor, a.Test())
+	if err != nil {
+		return AttemptResult{}, err
+	}
+	qs, err := s.getQuestionsForScoring(ctx, descriptor, a.Test())
+	if err != nil {
+		return AttemptResult{}, err
+	}
+	qTypes := make(map[QuestionID]string, len(qs))
+	qByID := make(map[QuestionID]QuestionForScoring, len(qs))
+	for _, q := range qs {
+		qTypes[QuestionID(q.ID)] = q.Type
+		qByID[QuestionID(q.ID)] = q
+	}
+
+	policy := a.Policy()
+	result := AttemptResult{
+		AttemptID:        string(a.ID()),
+		AssignmentID:     string(a.Assignment()),
+		Status:           a.Status(),
+		SubmittedAt:      a.SubmittedAt(),
+		ExpiredAt:        a.ExpiredAt(),
+		ScoreVisible:     policy.scoreVisible(a.Status()),
+		SolutionsVisible: policy.solutionsVisible(a.Status()),
+		Answers:          buildAnsweredQuestions(a, visibleQuestions, qTypes),
+	}
+
+	if result.ScoreVisible {
+		score, maxScore := a.Score()
+		pending := a.PendingScore()
+		if a.Status() == StatusActive {
+			score, maxScore, pending, err = scoreAttempt(a, qs)
+			if err != nil {
+				return AttemptResult{}, err
+			}
+		}
+		result.Score = &score
+		result.MaxScore = &maxScore
+		result.PendingScore = &pending
 	}
 
-	plan := a.Plan()
 	answers := a.Answers()
-	for idx, qid := range plan {
-		vq, ok := vqByID[string(qid)]
+	vqByID := make(map[string]VisibleQuestion, len(visibleQuestions))
+	for _, q := range visibleQuestions {
+		vqByID[q.ID] = q
+	}
+	for i := range result.Answers {
+		item := &result.Answers[i]
+		qid := QuestionID(item.QuestionID)
+		q, ok := qByID[qid]
 		if !ok {
 			continue
 		}
-		opts := vq.Options
-		if a.Policy().ShuffleAnswers {
-			opts = shuffleOptions(vq.Options, a.Seed(), idx)
-		}
-
-		answered, ok := answers[qid]
-		kind := qTypes[qid]
-		if kind == "" {
-			kind = "single"
+		if policy.answerScoresVisible(a.Status()) {
+			ans, answered := answers[qid]
+			earned, pending, err := scoreAnswer(q, ans, answered)
+			if err != nil {
+				return AttemptResult{}, err
+			}
+			if !pending {
+				correct := earned >= q.Weight
+				item.Score = &earned
+				item.IsCorrect = &correct
+			} else {
+				item.Score = nil
+				item.IsCorrect = nil
+			}
+			item.CodeResults = publicCodeResults(item.CodeResults)
+		} else {
+			item.Score = nil
+			item.IsCorrect = nil
+			item.CodeResults = nil
 		}
-		var (
-			selectedIndexes = make(map[int]struct{})
-			textAnswer      string
-			codeAnswer      *CodePayload
-			isCorrect       *bool
-			scorePtr        *float64
-		)
-		if ok {
-			kind = answerKindToString(answered.Payload.Kind, kind)
-			switch answered.Payload.Kind {
-			case AnswerSingle:
-				selectedIndexes[answered.Payload.Single] = struct{}{}
-			case AnswerMulti:
-				for _, i := range answered.Payload.Multi {
-					selectedIndexes[i] = struct{}{}
+		if result.SolutionsVisible {
+			correctIDs := make(map[string]struct{})
+			original := vqByID[item.QuestionID].Options
+			for _, idx := range correctOptionIndexes(q) {
+				if idx >= 0 && idx < len(original) {
+					correctIDs[original[idx].ID] = struct{}{}
 				}
-			case AnswerText:
-				textAnswer = answered.Payload.Text
-			case AnswerCode:
-				codeAnswer = answered.Payload.Code
 			}
-			isCorrect = answered.IsCorrect
-			scorePtr = answered.Score
-		}
-
-		optionViews := make([]AnsweredOption, 0, len(opts))
-		for i, opt := range opts {
-			_, sel := selectedIndexes[i]
-			optionViews = append(optionViews, AnsweredOption{
-				ID:         opt.ID,
-				OptionText: opt.OptionText,
-				ImageURL:   opt.ImageURL,
-				Selected:   sel,
-			})
+			for j := range item.Options {
+				_, isCorrect := correctIDs[item.Options[j].ID]
+				item.Options[j].Correct = &isCorrect
+			}
 		}
-
-		result.Answers = append(result.Answers, AnsweredQuestion{
-			QuestionID:   vq.ID,
-			QuestionText: vq.QuestionText,
-			ImageURL:     vq.ImageURL,
-			Kind:         kind,
-			Weight:       vq.Weight,
-			Options:      optionViews,
-			TextAnswer:   textAnswer,
-			CodeAnswer:   codeAnswer,
-			IsCorrect:    isCorrect,
-			Score:        scorePtr,
-		})
 	}
 
 	return result, nil
@@ -628,6 +847,11 @@ func (s *Service) Submit(ctx context.Context, requester *UserID, id AttemptID, v
 	if err := s.policy.CanModifyAttempt(ctx, requester, a); err != nil {
 		return AttemptView{}, fmt.Errorf("%w: %v", ErrForbidden, err)
 	}
+	now := s.clock.Now()
+	// Scoring is only done for an attempt that will be submitted.
+	if err := a.CheckSubmit(version, now); err != nil {
+		return AttemptView{}, err
+	}
 	descriptor, err := s.assignments.GetAssignment(ctx, a.Assignment())
 	if err != nil {
 		return AttemptView{}, err
@@ -641,11 +865,11 @@ func (s *Service) Submit(ctx context.Context, requester *UserID, id AttemptID, v
 			return AttemptView{}, err
 		}
 	}
-	score, max, pending, err := simpleScore(qs, a.Answers())
+	score, max, pending, err := scoreAttempt(a, qs)
 	if err != nil {
 		return AttemptView{}, err
 	}
-	_, err = a.Submit(version, s.clock.Now(), score, max)
+	_, err = a.Submit(version, now, score, max)
 	if err != nil {
 		return AttemptView{}, err
 	}
@@ -656,6 +880,7 @@ func (s *Service) Submit(ctx context.Context, requester *UserID, id AttemptID, v
 	if err != nil {
 		return AttemptView{}, err
 	}
+	s.gradeCodeLater(a, qs)
 	return attemptToView(a, s.clock.Now()), nil
 }
 
@@ -727,9 +952,14 @@ func (s *Service) GradeAnswer(ctx context.Context, grader UserID, id AttemptID,
 	if isCorrect != nil {
 		ans.IsCorrect = isCorrect
 	}
+	gradedBy := grader
+	gradedAt := s.clock.Now().UTC()
+	ans.GradedBy = &gradedBy
+	ans.GradedAt = &gradedAt
+	ans.Decision = gradeDecision(ans.Suggestion, val)
 	answers[questionID] = ans
 	a.answers = answers
-	newScore, max, pending, err := simpleScore(qs, answers)
+	newScore, max, pending, err := scoreAttempt(a, qs)
 	if err != nil {
 		return AttemptDetails{}, err
 	}
@@ -751,15 +981,27 @@ func (s *Service) GradeAnswer(ctx context.Context, grader UserID, id AttemptID,
 }
 
 type AttemptView struct {
-	AttemptID    string            `json:"attempt_id"`
-	AssignmentID string            `json:"assignment_id"`
-	Status       string            `json:"status"`
-	Version      int               `json:"version"`
-	TimeLeftSec  int64             `json:"time_left_sec"`
-	Total        int               `json:"total"`
-	Cursor       int               `json:"cursor"`
-	GuestName    string            `json:"guest_name,omitempty"`
-	Policy       AttemptPolicyView `json:"policy"`
+	AttemptID    string                 `json:"attempt_id"`
+	AssignmentID string                 `json:"assignment_id"`
+	Status       string                 `json:"status"`
+	Version      int                    `json:"version"`
+	TimeLeftSec  int64                  `json:"time_left_sec"`
+	Total        int                    `json:"total"`
+	Cursor       int                    `json:"cursor"`
+	GuestName    string                 `json:"guest_name,omitempty"`
+	Policy       AttemptPolicyView      `json:"policy"`
+	Progress     []QuestionProgressView `json:"progress,omitempty"`
+	Section      *SectionView           `json:"section,omitempty"`
+}
+
+// SectionView describes the section the participant is currently in.
+type SectionView struct {
+	Index       int    `json:"index"`
+	Name        string `json:"name"`
+	Total       int    `json:"total"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	TimeLeftSec *int64 `json:"time_left_sec,omitempty"`
 }
 
 type AttemptPolicyView struct {
@@ -777,13 +1019,20 @@ type AttemptPolicyView struct {
 	RevealSolutions      bool   `json:"reveal_solutions"`
 }
 
+type QuestionProgressView struct {
+	Index      int    `json:"index"`
+	QuestionID string `json:"question_id"`
+	Answered   bool   `json:"answered"`
+}
+
 type QuestionView struct {
-	ID           string       `json:"id"`
-	Type         string       `json:"type,omitempty"`
-	QuestionText string       `json:"question_text"`
-	ImageURL     string       `json:"image_url,omitempty"`
-	Weight       float64      `json:"weight,omitempty"`
-	Options      []OptionView `json:"options"`
+	ID           string        `json:"id"`
+	Type         string        `json:"type,omitempty"`
+	QuestionText string        `json:"question_text"`
+	ImageURL     string        `json:"image_url,omitempty"`
+	Weight       float64       `json:"weight,omitempty"`
+	Options      []OptionView  `json:"options"`
+	Examples     []CodeExample `json:"examples,omitempty"`
 }
 
 type OptionView struct {
@@ -833,6 +1082,12 @@ type AnsweredQuestion struct {
 	CodeAnswer   *CodePayload
 	IsCorrect    *bool
 	Score        *float64
+	CodeResults  []CodeCaseResult
+	// Set by AttemptDetails only.
+	Suggestion *GradeSuggestion
+	GradedBy   *UserID
+	GradedAt   *time.Time
+	Decision   string
 }
 
 type AnsweredOption struct {
@@ -840,6 +1095,21 @@ type AnsweredOption struct {
 	OptionText string
 	ImageURL   string
 	Selected   bool
+	Correct    *bool
+}
+
+type AttemptResult struct {
+	AttemptID        string
+	AssignmentID     string
+	Status           AttemptStatus
+	SubmittedAt      *time.Time
+	ExpiredAt        *time.Time
+	ScoreVisible     bool
+	SolutionsVisible bool
+	Score            *float64
+	MaxScore         *float64
+	PendingScore     *float64
+	Answers          []AnsweredQuestion
 }
 
 func attemptToView(a *Attempt, now time.Time) AttemptView {
@@ -861,6 +1131,30 @@ func attemptToView(a *Attempt, now time.Time) AttemptView {
 		av.GuestName = *a.GuestName()
 	}
 	av.Policy = toPolicyView(a.Policy())
+	if sec, ok := a.CurrentSection(now); ok && a.Status() == StatusActive {
+		av.Section = &SectionView{
+			Index: sec.Index,
+			Name:  sec.Name,
+			Total: sec.Total,
+			Start: sec.Start,
+			End:   sec.End,
+		}
+		if sec.TimeLimit > 0 {
+			left := int64(sec.TimeLeft.Seconds())
+			av.Section.TimeLeftSec = &left
+		}
+	}
+	if a.Policy().navigable() {
+		progress := a.Progress()
+		av.Progress = make([]QuestionProgressView, 0, len(progress))
+		for _, p := range progress {
+			av.Progress = append(av.Progress, QuestionProgressView{
+				Index:      p.Index,
+				QuestionID: string(p.QuestionID),
+				Answered:   p.Answered,
+			})
+		}
+	}
 	return av
 }
 
@@ -873,7 +1167,7 @@ func toPolicyView(p AttemptPolicy) AttemptPolicyView {
 		DisableCopy:         p.DisableCopy,
 		DisableBrowserBack:  p.DisableBrowserBack,
 		ShowElapsedTime:     p.ShowElapsedTime,
-		AllowNavigation:     p.AllowNavigation,
+		AllowNavigation:     p.navigable(),
 		RevealScoreMode:     string(p.RevealScoreMode),
 		RevealSolutions:     p.RevealSolutions,
 	}
@@ -894,6 +1188,7 @@ func makeQuestionView(v VisibleQuestion, opts []VisibleOption) QuestionView {
 		ImageURL:     v.ImageURL,
 		Weight:       v.Weight,
 		Options:      make([]OptionView, 0, len(opts)),
+		Examples:     v.Examples,
 	}
 	for _, o := range opts {
 		out.Options = append(out.Options, OptionView(o))
@@ -908,6 +1203,92 @@ func (s *Service) getVisibleQuestions(ctx context.Context, descriptor Assignment
 	return s.tests.ListVisibleQuestions(ctx, string(testID))
 }
 
+func (s *Service) getQuestionsForScoring(ctx context.Context, descriptor AssignmentDescriptor, testID TestID) ([]QuestionForScoring, error) {
+	if descriptor.Template != nil {
+		return descriptor.Template.QuestionsForScoring(), nil
+	}
+	return s.tests.ListQuestionsForScoring(ctx, string(testID))
+}
+
+func buildAnsweredQuestions(a *Attempt, visibleQuestions []VisibleQuestion, qTypes map[QuestionID]string) []AnsweredQuestion {
+	vqByID := make(map[string]VisibleQuestion, len(visibleQuestions))
+	for _, q := range visibleQuestions {
+		vqByID[q.ID] = q
+	}
+
+	plan := a.Plan()
+	answers := a.Answers()
+	out := make([]AnsweredQuestion, 0, len(plan))
+	for idx, qid := range plan {
+		vq, ok := vqByID[string(qid)]
+		if !ok {
+			continue
+		}
+		opts := vq.Options
+		if a.Policy().ShuffleAnswers {
+			opts = shuffleOptions(vq.Options, a.Seed(), idx)
+		}
+
+		answered, ok := answers[qid]
+		kind := qTypes[qid]
+		if kind == "" {
+			kind = "single"
+		}
+		var (
+			selectedIndexes = make(map[int]struct{})
+			textAnswer      string
+			codeAnswer      *CodePayload
+			codeResults     []CodeCaseResult
+			isCorrect       *bool
+			scorePtr        *float64
+		)
+		if ok {
+			kind = answerKindToString(answered.Payload.Kind, kind)
+			switch answered.Payload.Kind {
+			case AnswerSingle:
+				selectedIndexes[answered.Payload.Single] = struct{}{}
+			case AnswerMulti:
+				for _, i := range answered.Payload.Multi {
+					selectedIndexes[i] = struct{}{}
+				}
+			case AnswerText:
+				textAnswer = answered.Payload.Text
+			case AnswerCode:
+				codeAnswer = answered.Payload.Code
+				codeResults = answered.CodeResults
+			}
+			isCorrect = answered.IsCorrect
+			scorePtr = answered.Score
+		}
+
+		optionViews := make([]AnsweredOption, 0, len(opts))
+		for i, opt := range opts {
+			_, sel := selectedIndexes[i]
+			optionViews = append(optionViews, AnsweredOption{
+				ID:         opt.ID,
+				OptionText: opt.OptionText,
+				ImageURL:   opt.ImageURL,
+				Selected:   sel,
+			})
+		}
+
+		out = append(out, AnsweredQuestion{
+			QuestionID:   vq.ID,
+			QuestionText: vq.QuestionText,
+			ImageURL:     vq.ImageURL,
+			Kind:         kind,
+			Weight:       vq.Weight,
+			Options:      optionViews,
+			TextAnswer:   textAnswer,
+			CodeAnswer:   codeAnswer,
+			IsCorrect:    isCorrect,
+			Score:        scorePtr,
+			CodeResults:  codeResults,
+		})
+	}
+	return out
+}
+
 func buildParticipant(a *Attempt, info *UserInfo) Participant {
 	if a.User() != 0 {
 		name := fmt.Sprintf("User #%d", a.User())
@@ -961,41 +1342,126 @@ func shuffleOptions(opts []VisibleOption, seed int64, position int) []VisibleOpt
 	return cp
 }
 
-func simpleScore(qs []QuestionForScoring, answers map[QuestionID]Answer) (float64, float64, float64, error) {
-	var score, max, pending float64
-	m := make(map[string]Answer, len(answers))
-	for id, a := range answers {
-		m[string(id)] = a
+// scoreAttempt leaves out drawn questions the attempt was not given, so pool
+// and section draws do not inflate the maximum score. Questions cut by
+// MaxQuestions still count, as they always have.
+func scoreAttempt(a *Attempt, qs []QuestionForScoring) (float64, float64, float64, error) {
+	inPlan := make(map[QuestionID]struct{}, len(a.order))
+	for _, id := range a.order {
+		inPlan[id] = struct{}{}
 	}
+	scored := make([]QuestionForScoring, 0, len(qs))
 	for _, q := range qs {
-		max += q.Weight
-		ans, ok := m[q.ID]
+		if _, ok := inPlan[QuestionID(q.ID)]; ok || !q.Drawn {
+			scored = append(scored, q)
+		}
+	}
+	return simpleScore(scored, a.Answers())
+}
+
+// runCodeAnswers executes code answers that have test cases and were not
+// run or graded yet. Runner failures leave the answer pending. It is only
+// called from background grading, see gradeCodeLater.
+func (s *Service) runCodeAnswers(ctx context.Context, a *Attempt, qs []QuestionForScoring) {
+	if s.runner == nil {
+		return
+	}
+	for _, q := range qs {
+		if q.Type != "code" {
+			continue
+		}
+		key, ok := decodeCodeAnswerKey(q.CorrectJSON)
 		if !ok {
-			if q.Type == "text" || q.Type == "code" {
-				pending += q.Weight
-			}
 			continue
 		}
-		if ans.Score != nil {
-			score += *ans.Score
+		qid := QuestionID(q.ID)
+		ans, ok := a.answers[qid]
+		if !ok || ans.Score != nil || len(ans.CodeResults) > 0 {
 			continue
 		}
-		if q.Type == "text" || q.Type == "code" {
-			pending += q.Weight
+		results, err := runCodeTests(ctx, s.runner, key, ans.Payload.Code)
+		if err != nil {
+			log.Printf("attempt %s: code question %s left pending: %v", a.ID(), qid, err)
 			continue
 		}
-		okEq, err := isCorrectJSON(q.Type, q.CorrectJSON, ans.Payload)
+		ans.CodeResults = results
+		a.answers[qid] = ans
+	}
+}
+
+func simpleScore(qs []QuestionForScoring, answers map[QuestionID]Answer) (float64, float64, float64, error) {
+	var score, max, pending float64
+	m := make(map[string]Answer, len(answers))
+	for id, a := range answers {
+		m[string(id)] = a
+	}
+	for _, q := range qs {
+		max += q.Weight
+		ans, ok := m[q.ID]
+		earned, isPending, err := scoreAnswer(q, ans, ok)
 		if err != nil {
 			return 0, 0, 0, err
 		}
-		if okEq {
-			score += q.Weight
+		if isPending {
+			pending += q.Weight
+			continue
 		}
+		score += earned
 	}
 	return score, max, pending, nil
 }
 
+// scoreAnswer returns the points earned for a single question, or pending=true
+// when the answer still needs manual grading.
+func scoreAnswer(q QuestionForScoring, ans Answer, answered bool) (float64, bool, error) {
+	textKey, autoText := decodeTextAnswerKey(q.CorrectJSON)
+	autoText = autoText && q.Type == "text"
+	_, autoCode := decodeCodeAnswerKey(q.CorrectJSON)
+	autoCode = autoCode && q.Type == "code"
+	if !answered {
+		return 0, (q.Type == "code" && !autoCode) || (q.Type == "text" && !autoText), nil
+	}
+	if ans.Score != nil {
+		return *ans.Score, false, nil
+	}
+	if q.Type == "code" {
+		fraction, executed := passedFraction(ans.CodeResults)
+		if !autoCode || !executed {
+			return 0, true, nil
+		}
+		return q.Weight * fraction, false, nil
+	}
+	if q.Type == "text" && !autoText {
+		return 0, true, nil
+	}
+	if key, ok := decodeChoiceKey(q.CorrectJSON); ok && (q.Type == "single" || q.Type == "multi") {
+		return q.Weight * choiceCredit(q.Type, key, ans.Payload), false, nil
+	}
+	okEq, err := isCorrectJSON(q.Type, q.CorrectJSON, ans.Payload)
+	if err != nil {
+		return 0, false, err
+	}
+	if okEq {
+		return q.Weight, false, nil
+	}
+	// Unmatched text answers wait for a teacher only when manual review is on.
+	return 0, autoText && textKey.ManualReview, nil
+}
+
+func correctOptionIndexes(q QuestionForScoring) []int {
+	key, _ := decodeChoiceKey(q.CorrectJSON)
+	return key.Selected
+}
+
 func isCorrectJSON(qType string, expected []byte, payload AnswerPayload) (bool, error) {
+	if qType == "text" {
+		key, ok := decodeTextAnswerKey(expected)
+		return ok && payload.Kind == AnswerText && key.Matches(payload.Text), nil
+	}
+	if key, ok := decodeChoiceKey(expected); ok {
+		return choiceCredit("single", key, payload) == 1, nil
+	}
+
 	var exp any
 	if err := json.Unmarshal(expected, &exp); err != nil {
 		return false, err
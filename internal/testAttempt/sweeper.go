@@ -0,0 +1,120 @@
+package testAttempt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Sweeper periodically expires active attempts that ran past their deadline
+// or question time limit, so abandoned attempts still get a score.
+type Sweeper struct {
+	svc      *Service
+	clock    Clock
+	interval time.Duration
+}
+
+func NewSweeper(svc *Service, clock Clock, interval time.Duration) *Sweeper {
+	return &Sweeper{svc: svc, clock: clock, interval: interval}
+}
+
+// Run sweeps once per interval until ctx is canceled.
+func (w *Sweeper) Run(ctx context.Context) {
+	if w.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := w.Sweep(ctx); err != nil {
+			log.Printf("attempt sweeper: %v", err)
+		} else if n > 0 {
+			log.Printf("attempt sweeper: expired %d attempt(s)", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep expires every overdue attempt and returns how many were closed.
+func (w *Sweeper) Sweep(ctx context.Context) (int, error) {
+	return w.svc.ExpireOverdue(ctx, w.clock.Now())
+}
+
+func (s *Service) ExpireOverdue(ctx context.Context, now time.Time) (int, error) {
+	attempts, err := s.repo.ListActiveTimed(ctx)
+	if err != nil {
+		return 0, err
+	}
+	scoring := make(map[AssignmentID][]QuestionForScoring)
+	var (
+		expired int
+		errs    []error
+	)
+	for _, a := range attempts {
+		if _, overdue := a.OverdueAt(now); !overdue {
+			continue
+		}
+		qs, ok := scoring[a.Assignment()]
+		if !ok {
+			descriptor, err := s.assignments.GetAssignment(ctx, a.Assignment())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("attempt %s: %w", a.ID(), err))
+				continue
+			}
+			qs, err = s.getQuestionsForScoring(ctx, descriptor, a.Test())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("attempt %s: %w", a.ID(), err))
+				continue
+			}
+			scoring[a.Assignment()] = qs
+		}
+		closed, err := s.expire(ctx, a, qs, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("attempt %s: %w", a.ID(), err))
+			continue
+		}
+		if closed {
+			expired++
+		}
+	}
+	return expired, errors.Join(errs...)
+}
+
+// closeOverdue expires an attempt that a participant's request found out of
+// time, scoring the answers given so far as the sweeper would.
+func (s *Service) closeOverdue(ctx context.Context, a *Attempt, now time.Time) error {
+	descriptor, err := s.assignments.GetAssignment(ctx, a.Assignment())
+	if err != nil {
+		return err
+	}
+	qs, err := s.getQuestionsForScoring(ctx, descriptor, a.Test())
+	if err != nil {
+		return err
+	}
+	_, err = s.expire(ctx, a, qs, now)
+	return err
+}
+
+// expire scores an overdue attempt, closes it and queues its code answers.
+// It reports false when the attempt left the active state in the meantime.
+func (s *Service) expire(ctx context.Context, a *Attempt, qs []QuestionForScoring, now time.Time) (bool, error) {
+	score, max, pending, err := scoreAttempt(a, qs)
+	if err != nil {
+		return false, err
+	}
+	if _, err := a.Expire(now, score, max, pending); err != nil {
+		return false, err
+	}
+	closed, err := s.repo.Expire(ctx, a)
+	if err != nil || !closed {
+		return false, err
+	}
+	s.gradeCodeLater(a, qs)
+	return true, nil
+}
@@ -0,0 +1,288 @@
+package testAttempt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+// memRepo keeps attempts in memory. Rows listed in left changed behind the
+// sweeper's back, like an attempt submitted or answered concurrently.
+type memRepo struct {
+	Repository
+	attempts map[AttemptID]*Attempt
+	left     map[AttemptID]bool
+}
+
+func newMemRepo(attempts ...*Attempt) *memRepo {
+	r := &memRepo{attempts: make(map[AttemptID]*Attempt), left: make(map[AttemptID]bool)}
+	for _, a := range attempts {
+		r.attempts[a.ID()] = a
+	}
+	return r
+}
+
+// GetByID returns a copy and, like RehydrateAttempt, refuses rows whose
+// scores could not have been stored.
+func (r *memRepo) GetByID(_ context.Context, id AttemptID) (*Attempt, error) {
+	a, ok := r.attempts[id]
+	if !ok {
+		return nil, errors.New("attempt not found")
+	}
+	if err := validatePersistedScores(a.status, a.score, a.maxScore); err != nil {
+		return nil, err
+	}
+	cp := *a
+	cp.answers = a.Answers()
+	return &cp, nil
+}
+
+func (r *memRepo) SaveAnswer(_ context.Context, a *Attempt, _ QuestionID) error {
+	r.attempts[a.ID()] = a
+	return nil
+}
+
+func (r *memRepo) SaveProgress(_ context.Context, a *Attempt) error {
+	r.attempts[a.ID()] = a
+	return nil
+}
+
+func (r *memRepo) SaveCodeResults(_ context.Context, a *Attempt) error {
+	r.attempts[a.ID()] = a
+	return nil
+}
+
+func (r *memRepo) ListActiveTimed(context.Context) ([]*Attempt, error) {
+	var out []*Attempt
+	for id, a := range r.attempts {
+		if a.Status() == StatusActive {
+			cp, _ := r.GetByID(context.Background(), id)
+			out = append(out, cp)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) ListClosedUngraded(context.Context) ([]*Attempt, error) {
+	var out []*Attempt
+	for id, a := range r.attempts {
+		if a.Status() == StatusSubmitted || a.Status() == StatusExpired {
+			cp, _ := r.GetByID(context.Background(), id)
+			out = append(out, cp)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) Expire(_ context.Context, a *Attempt) (bool, error) {
+	stored := r.attempts[a.ID()]
+	if r.left[a.ID()] || stored.Status() != StatusActive || stored.Version() != a.Version()-1 {
+		return false, nil
+	}
+	r.attempts[a.ID()] = a
+	return true, nil
+}
+
+type inlineTx struct{}
+
+func (inlineTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type allowAll struct{}
+
+func (allowAll) CanStartAttempt(context.Context, *UserID, *string, TestID) error { return nil }
+func (allowAll) CanModifyAttempt(context.Context, *UserID, *Attempt) error       { return nil }
+
+type stubAssignments struct{ tpl *AssignmentTemplate }
+
+func (s stubAssignments) GetAssignment(_ context.Context, id AssignmentID) (AssignmentDescriptor, error) {
+	return AssignmentDescriptor{ID: id, Template: s.tpl}, nil
+}
+
+func TestSweeperExpiresOverdueAttempts(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	timed := AttemptPolicy{MaxAttemptTime: 10 * time.Minute}
+	newAttempt := func(id AttemptID, policy AttemptPolicy) *Attempt {
+		a := NewAttempt(id, "as1", "t1", 1, nil, nil, start, policy, 1, "", "")
+		a.InitializePlan([]QuestionID{"q1"})
+		return a
+	}
+	overdue := newAttempt("overdue", timed)
+	if _, err := overdue.AnswerQuestion(0, start, "q1", AnswerPayload{Kind: AnswerSingle, Single: 1}); err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	onTime := newAttempt("on-time", AttemptPolicy{MaxAttemptTime: time.Hour})
+	submitted := newAttempt("submitted", timed)
+
+	repo := newMemRepo(overdue, onTime, submitted)
+	repo.left["submitted"] = true
+	tpl := &AssignmentTemplate{Questions: []TemplateQuestion{{
+		ID: "q1", Type: "single", CorrectOption: 1,
+		Options: []TemplateOption{{ID: "o0"}, {ID: "o1"}},
+	}}}
+	clock := &fakeClock{now: start.Add(5 * time.Minute)}
+	svc := NewTestAttemptService(repo, nil, stubAssignments{tpl: tpl}, nil, clock, nil, nil, nil, nil)
+	sweeper := NewSweeper(svc, clock, time.Minute)
+
+	if n, err := sweeper.Sweep(context.Background()); err != nil || n != 0 {
+		t.Fatalf("nothing is overdue yet, got n=%d err=%v", n, err)
+	}
+
+	clock.now = start.Add(11 * time.Minute)
+	n, err := sweeper.Sweep(context.Background())
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected only the overdue attempt to count, got %d", n)
+	}
+	got := repo.attempts["overdue"]
+	if got.Status() != StatusExpired {
+		t.Fatalf("expected expired attempt, got %s", got.Status())
+	}
+	if score, max := got.Score(); score != 1 || max != 1 {
+		t.Fatalf("expected score 1/1, got %v/%v", score, max)
+	}
+	if at := got.ExpiredAt(); at == nil || !at.Equal(start.Add(10*time.Minute)) {
+		t.Fatalf("expected expiry at the deadline, got %v", at)
+	}
+	if repo.attempts["on-time"].Status() != StatusActive {
+		t.Fatal("attempt within its time must stay active")
+	}
+}
+
+func TestExpiredCodeAnswersAreRunInBackground(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	a := NewAttempt("a1", "as1", "t1", 1, nil, nil, start, AttemptPolicy{MaxAttemptTime: time.Minute}, 1, "", "")
+	a.InitializePlan([]QuestionID{"q1"})
+	code := AnswerPayload{Kind: AnswerCode, Code: &CodePayload{Lang: "python", Body: "upper"}}
+	if _, err := a.AnswerQuestion(0, start, "q1", code); err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+
+	key := &CodeAnswerKey{TestCases: []CodeTestCase{{Stdin: "abc", ExpectedStdout: "ABC"}}}
+	tpl := &AssignmentTemplate{Questions: []TemplateQuestion{{ID: "q1", Type: "code", Weight: 2, CodeAnswer: key}}}
+	repo := newMemRepo(a)
+	clock := &fakeClock{now: start.Add(2 * time.Minute)}
+	svc := NewTestAttemptService(repo, nil, stubAssignments{tpl: tpl}, inlineTx{}, clock, nil, nil, echoRunner{}, nil)
+
+	if n, err := NewSweeper(svc, clock, time.Minute).Sweep(context.Background()); err != nil || n != 1 {
+		t.Fatalf("Sweep: n=%d err=%v", n, err)
+	}
+	svc.codeJobs.wg.Wait()
+
+	got := repo.attempts["a1"]
+	if score, max := got.Score(); score != 2 || max != 2 || got.PendingScore() != 0 {
+		t.Fatalf("expected code answer graded 2/2, got %v/%v pending %v", score, max, got.PendingScore())
+	}
+	if results := got.Answers()["q1"].CodeResults; len(results) != 1 || !results[0].Passed {
+		t.Fatalf("expected stored test-case results, got %+v", results)
+	}
+}
+
+func TestQuestionAtPastDeadlineExpiresWithScore(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	a := NewAttempt("a1", "as1", "t1", 1, nil, nil, start, AttemptPolicy{MaxAttemptTime: 10 * time.Minute, AllowNavigation: true}, 1, "", "")
+	a.InitializePlan([]QuestionID{"q1", "q2"})
+	if _, err := a.AnswerQuestion(0, start, "q1", AnswerPayload{Kind: AnswerSingle, Single: 1}); err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	single := func(id QuestionID) TemplateQuestion {
+		return TemplateQuestion{ID: id, Type: "single", CorrectOption: 1, Options: []TemplateOption{{ID: "o0"}, {ID: "o1"}}}
+	}
+	tpl := &AssignmentTemplate{Questions: []TemplateQuestion{single("q1"), single("q2")}}
+	repo := newMemRepo(a)
+	clock := &fakeClock{now: start.Add(11 * time.Minute)}
+	svc := NewTestAttemptService(repo, nil, stubAssignments{tpl: tpl}, inlineTx{}, clock, allowAll{}, nil, nil, nil)
+
+	view, _, _, err := svc.QuestionAt(context.Background(), nil, "a1", 1)
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+	if view.Status != string(StatusExpired) {
+		t.Fatalf("expected the view to show the expired attempt, got %q", view.Status)
+	}
+
+	got, err := repo.GetByID(context.Background(), "a1")
+	if err != nil {
+		t.Fatalf("load the expired attempt: %v", err)
+	}
+	if score, max := got.Score(); got.Status() != StatusExpired || score != 1 || max != 2 {
+		t.Fatalf("expected expired with 1/2, got %s %v/%v", got.Status(), score, max)
+	}
+}
+
+func TestAnswerPastQuestionTimeLimitExpiresWithScore(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	a := NewAttempt("a1", "as1", "t1", 1, nil, nil, start, AttemptPolicy{QuestionTimeLimit: time.Minute}, 1, "", "")
+	a.InitializePlan([]QuestionID{"q1", "q2"})
+	single := func(id QuestionID) TemplateQuestion {
+		return TemplateQuestion{ID: id, Type: "single", CorrectOption: 1, Options: []TemplateOption{{ID: "o0"}, {ID: "o1"}}}
+	}
+	tpl := &AssignmentTemplate{Questions: []TemplateQuestion{single("q1"), single("q2")}}
+	repo := newMemRepo(a)
+	clock := &fakeClock{now: start}
+	svc := NewTestAttemptService(repo, nil, stubAssignments{tpl: tpl}, inlineTx{}, clock, allowAll{}, nil, nil, nil)
+	ctx := context.Background()
+
+	if _, _, err := svc.NextQuestion(ctx, nil, "a1"); err != nil {
+		t.Fatalf("NextQuestion: %v", err)
+	}
+	clock.now = start.Add(30 * time.Second)
+	view, _, err := svc.AnswerCurrent(ctx, nil, "a1", 0, AnswerPayload{Kind: AnswerSingle, Single: 1})
+	if err != nil {
+		t.Fatalf("AnswerCurrent: %v", err)
+	}
+	if _, _, err := svc.NextQuestion(ctx, nil, "a1"); err != nil {
+		t.Fatalf("NextQuestion: %v", err)
+	}
+	clock.now = start.Add(2 * time.Minute)
+	if _, _, err := svc.AnswerCurrent(ctx, nil, "a1", view.Version, AnswerPayload{Kind: AnswerSingle, Single: 1}); !errors.Is(err, ErrQuestionTimeLimit) {
+		t.Fatalf("expected ErrQuestionTimeLimit, got %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, "a1")
+	if err != nil {
+		t.Fatalf("load the expired attempt: %v", err)
+	}
+	if score, max := got.Score(); got.Status() != StatusExpired || score != 1 || max != 2 {
+		t.Fatalf("expected expired with 1/2, got %s %v/%v", got.Status(), score, max)
+	}
+	if at := got.ExpiredAt(); at == nil || !at.Equal(start.Add(90*time.Second)) {
+		t.Fatalf("expected expiry when the second question ran out, got %v", at)
+	}
+}
+
+func TestResumeCodeGradingRunsLostJobs(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	a := NewAttempt("a1", "as1", "t1", 1, nil, nil, start, AttemptPolicy{}, 1, "", "")
+	a.InitializePlan([]QuestionID{"q1"})
+	code := AnswerPayload{Kind: AnswerCode, Code: &CodePayload{Lang: "python", Body: "upper"}}
+	if _, err := a.AnswerQuestion(0, start, "q1", code); err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	// Submitted with the code answer pending, and the run never stored.
+	if _, err := a.Submit(1, start, 0, 2); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	key := &CodeAnswerKey{TestCases: []CodeTestCase{{Stdin: "abc", ExpectedStdout: "ABC"}}}
+	tpl := &AssignmentTemplate{Questions: []TemplateQuestion{{ID: "q1", Type: "code", Weight: 2, CodeAnswer: key}}}
+	repo := newMemRepo(a)
+	svc := NewTestAttemptService(repo, nil, stubAssignments{tpl: tpl}, inlineTx{}, &fakeClock{now: start}, nil, nil, echoRunner{}, nil)
+
+	if err := svc.ResumeCodeGrading(context.Background()); err != nil {
+		t.Fatalf("ResumeCodeGrading: %v", err)
+	}
+	svc.codeJobs.wg.Wait()
+
+	if score, max := repo.attempts["a1"].Score(); score != 2 || max != 2 {
+		t.Fatalf("expected the resumed run to grade 2/2, got %v/%v", score, max)
+	}
+}
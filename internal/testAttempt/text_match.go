@@ -0,0 +1,117 @@
+package testAttempt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type TextMatchMode string
+
+const (
+	TextMatchExact      TextMatchMode = "exact"
+	TextMatchNormalized TextMatchMode = "normalized" // case and whitespace insensitive
+	TextMatchRegex      TextMatchMode = "regex"
+	TextMatchNumeric    TextMatchMode = "numeric"
+)
+
+// TextAnswerKey is the CorrectJSON payload of an auto-graded text question.
+type TextAnswerKey struct {
+	AcceptedAnswers []string      `json:"accepted_answers"`
+	MatchMode       TextMatchMode `json:"match_mode,omitempty"`
+	Tolerance       float64       `json:"tolerance,omitempty"`
+	ManualReview    bool          `json:"manual_review,omitempty"`
+}
+
+func ParseTextMatchMode(raw string) (TextMatchMode, error) {
+	switch mode := TextMatchMode(strings.ToLower(strings.TrimSpace(raw))); mode {
+	case "":
+		return TextMatchNormalized, nil
+	case TextMatchExact, TextMatchNormalized, TextMatchRegex, TextMatchNumeric:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported match_mode %q (use exact, normalized, regex or numeric)", raw)
+	}
+}
+
+// Validate checks that accepted answers can be used with the match mode.
+func (k TextAnswerKey) Validate() error {
+	if _, err := ParseTextMatchMode(string(k.MatchMode)); err != nil {
+		return err
+	}
+	if k.Tolerance < 0 {
+		return fmt.Errorf("tolerance must be >= 0")
+	}
+	for _, accepted := range k.AcceptedAnswers {
+		switch k.MatchMode {
+		case TextMatchRegex:
+			if _, err := compileAnchored(accepted); err != nil {
+				return fmt.Errorf("invalid accepted pattern %q: %w", accepted, err)
+			}
+		case TextMatchNumeric:
+			if _, ok := parseNumber(accepted); !ok {
+				return fmt.Errorf("accepted answer %q is not a number", accepted)
+			}
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the participant text equals one of the accepted answers.
+func (k TextAnswerKey) Matches(text string) bool {
+	for _, accepted := range k.AcceptedAnswers {
+		switch k.MatchMode {
+		case TextMatchExact:
+			if text == accepted {
+				return true
+			}
+		case TextMatchRegex:
+			re, err := compileAnchored(accepted)
+			if err == nil && re.MatchString(strings.TrimSpace(text)) {
+				return true
+			}
+		case TextMatchNumeric:
+			want, okWant := parseNumber(accepted)
+			got, okGot := parseNumber(text)
+			if okWant && okGot && math.Abs(got-want) <= k.Tolerance {
+				return true
+			}
+		default:
+			if normalizeText(text) == normalizeText(accepted) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeTextAnswerKey(raw []byte) (TextAnswerKey, bool) {
+	if len(raw) == 0 {
+		return TextAnswerKey{}, false
+	}
+	var key TextAnswerKey
+	if err := json.Unmarshal(raw, &key); err != nil || len(key.AcceptedAnswers) == 0 {
+		return TextAnswerKey{}, false
+	}
+	return key, true
+}
+
+func compileAnchored(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+func parseNumber(s string) (float64, bool) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
@@ -0,0 +1,54 @@
+package testAttempt
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestScoreTextAnswer(t *testing.T) {
+	textQuestion := func(key TextAnswerKey) QuestionForScoring {
+		raw, _ := json.Marshal(key)
+		return QuestionForScoring{ID: "q1", Type: "text", Weight: 2, CorrectJSON: raw}
+	}
+	textAnswer := func(text string) Answer {
+		return Answer{Payload: AnswerPayload{Kind: AnswerText, Text: text}}
+	}
+
+	cases := []struct {
+		name        string
+		key         TextAnswerKey
+		text        string
+		wantScore   float64
+		wantPending bool
+	}{
+		{"exact match", TextAnswerKey{AcceptedAnswers: []string{"Paris"}, MatchMode: TextMatchExact}, "Paris", 2, false},
+		{"exact is case sensitive", TextAnswerKey{AcceptedAnswers: []string{"Paris"}, MatchMode: TextMatchExact}, "paris", 0, false},
+		{"normalized", TextAnswerKey{AcceptedAnswers: []string{"New  York"}}, "  new york ", 2, false},
+		{"regex", TextAnswerKey{AcceptedAnswers: []string{`colou?r`}, MatchMode: TextMatchRegex}, "color", 2, false},
+		{"regex is anchored", TextAnswerKey{AcceptedAnswers: []string{`colou?r`}, MatchMode: TextMatchRegex}, "colors", 0, false},
+		{"numeric within tolerance", TextAnswerKey{AcceptedAnswers: []string{"3.14"}, MatchMode: TextMatchNumeric, Tolerance: 0.01}, "3,141", 2, false},
+		{"numeric outside tolerance", TextAnswerKey{AcceptedAnswers: []string{"3.14"}, MatchMode: TextMatchNumeric, Tolerance: 0.01}, "3.2", 0, false},
+		{"manual review fallback", TextAnswerKey{AcceptedAnswers: []string{"Paris"}, ManualReview: true}, "Lyon", 0, true},
+		{"no accepted answers", TextAnswerKey{}, "anything", 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			score, pending, err := scoreAnswer(textQuestion(tc.key), textAnswer(tc.text), true)
+			if err != nil {
+				t.Fatalf("scoreAnswer: %v", err)
+			}
+			if score != tc.wantScore || pending != tc.wantPending {
+				t.Fatalf("got score=%v pending=%v, want score=%v pending=%v", score, pending, tc.wantScore, tc.wantPending)
+			}
+		})
+	}
+}
+
+func TestTextAnswerKeyValidate(t *testing.T) {
+	if err := (TextAnswerKey{AcceptedAnswers: []string{"("}, MatchMode: TextMatchRegex}).Validate(); err == nil {
+		t.Fatal("expected invalid regex error")
+	}
+	if err := (TextAnswerKey{AcceptedAnswers: []string{"abc"}, MatchMode: TextMatchNumeric}).Validate(); err == nil {
+		t.Fatal("expected non-numeric accepted answer error")
+	}
+}
@@ -1,18 +1,29 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"edu-system/internal/ai"
 	"github.com/gin-gonic/gin"
 
 	"edu-system/internal/assignment"
 	"edu-system/internal/auth"
+	"edu-system/internal/bank"
 	"edu-system/internal/delivery"
 	"edu-system/internal/delivery/middleware"
 	"edu-system/internal/platform"
+	"edu-system/internal/platform/airepo"
 	"edu-system/internal/platform/assignmentrepo"
 	"edu-system/internal/platform/authrepo"
+	"edu-system/internal/platform/bankrepo"
+	"edu-system/internal/platform/coderunner"
+	"edu-system/internal/platform/mailer"
+	"edu-system/internal/platform/oidc"
 	"edu-system/internal/platform/testattemptrepo"
 	"edu-system/internal/platform/testrepo"
 	"edu-system/internal/test"
@@ -25,14 +36,85 @@ func main() {
 
 	// Initialize repositories
 	userRepo := authrepo.NewUserRepository(db)
+	tokenRepo := authrepo.NewTokenRepository(db)
 	testRepo := testrepo.NewTestRepository(db)
 	testAttemptRepo := testattemptrepo.NewTestAttemptRepository(db)
 	assignmentRepo := assignmentrepo.NewRepository(db)
+	bankRepo := bankrepo.NewRepository(db)
+	aiRunRepo := airepo.NewRepository(db)
+
+	// Code answers stay pending for manual grading unless a runner is configured
+	var codeRunner testAttempt.CodeRunner
+	if cfg.CodeRunner == "local" {
+		codeRunner = coderunner.NewLocalRunner(coderunner.Config{
+			TimeLimit:        time.Duration(cfg.CodeRunTimeoutMs) * time.Millisecond,
+			CompileTimeLimit: time.Duration(cfg.CodeCompileTimeoutSec) * time.Second,
+			MemoryLimitMB:    cfg.CodeRunMemoryMB,
+			GoBin:            cfg.CodeRunnerGoBin,
+			PythonBin:        cfg.CodeRunnerPythonBin,
+			MaxConcurrent:    cfg.CodeRunMaxConcurrent,
+			MaxProcesses:     cfg.CodeRunMaxProcesses,
+			SandboxUID:       cfg.CodeRunnerUID,
+			SandboxGID:       cfg.CodeRunnerGID,
+		})
+	}
+
+	// Account emails go to the log (links redacted) or MAIL_DIR unless SMTP is configured
+	var mail auth.Mailer = &mailer.FileMailer{Dir: cfg.MailDir, From: cfg.MailFrom}
+	if cfg.MailDriver != "smtp" && cfg.GinMode == gin.ReleaseMode {
+		log.Printf("Warning: MAIL_DRIVER is not smtp, account emails will not reach users")
+	}
+	if cfg.MailDriver == "smtp" {
+		mail = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		})
+	}
+
+	// Single sign-on stays off until an identity provider is configured
+	sso := auth.SSOSettings{
+		GroupRoles:           cfg.OIDCGroupRoles,
+		AutoProvision:        cfg.OIDCAutoProvision,
+		TrustUnverifiedEmail: cfg.OIDCTrustUnverifiedEmail,
+	}
+	if cfg.OIDCIssuerURL != "" && cfg.OIDCClientID != "" {
+		sso.Provider = oidc.NewProvider(oidc.Config{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+			GroupsClaim:  cfg.OIDCGroupsClaim,
+		})
+	}
 
 	// Initialize services
-	authService := auth.NewAuthService(userRepo, cfg.JWTSecret)
+	authService := auth.NewAuthService(userRepo, tokenRepo, mail, auth.Settings{
+		JWTSecret: cfg.JWTSecret,
+		TTL: auth.TokenTTL{
+			Access:            time.Duration(cfg.AccessTokenTTLMin) * time.Minute,
+			Refresh:           time.Duration(cfg.RefreshTokenTTLHours) * time.Hour,
+			EmailVerification: time.Duration(cfg.EmailVerifyTTLHours) * time.Hour,
+			PasswordReset:     time.Duration(cfg.PasswordResetTTLMin) * time.Minute,
+		},
+		AdminEmails:              cfg.AdminEmails,
+		AppBaseURL:               cfg.AppBaseURL,
+		RequireEmailVerification: cfg.RequireEmailVerification,
+		SSO:                      sso,
+		AnonymiseAttempts: func(ctx context.Context, userID uint) error {
+			return testAttemptRepo.AnonymiseUser(ctx, testAttempt.UserID(userID))
+		},
+		Tx: platform.GormTransactor{DB: db},
+	})
+	adminService := auth.NewAdminService(userRepo, authrepo.NewOrganisationRepository(db), tokenRepo,
+		time.Duration(cfg.AccessTokenTTLMin)*time.Minute)
 	testService := test.NewTestService(testRepo)
-	assignmentService := assignment.NewService(assignmentRepo, testRepo)
+	bankService := bank.NewService(bankRepo)
+	assignmentService := assignment.NewService(assignmentRepo, testRepo, bankService)
+	aiService := ai.NewService(cfg, aiRunRepo, testService)
 	testAttemptService := testAttempt.NewTestAttemptService(
 		testAttemptRepo,
 		testRepo,
@@ -41,14 +123,39 @@ func main() {
 		platform.SystemClock{},
 		platform.AllowGuestsAndOwnerPolicy{Tests: testRepo},
 		platform.GormUserDirectory{DB: db},
+		codeRunner,
+		aiService,
 	)
-	aiService := ai.NewService(cfg)
+
+	// Serve until SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Run code answers whose background run a restart cut short
+	if err := testAttemptService.ResumeCodeGrading(ctx); err != nil {
+		log.Printf("Resuming code grading: %v", err)
+	}
+
+	// Expire attempts abandoned past their deadline
+	sweepCtx, stopSweeper := context.WithCancel(ctx)
+	sweeper := testAttempt.NewSweeper(
+		testAttemptService,
+		platform.SystemClock{},
+		time.Duration(cfg.AttemptSweepIntervalSec)*time.Second,
+	)
+	sweepDone := make(chan struct{})
+	go func() {
+		defer close(sweepDone)
+		sweeper.Run(sweepCtx)
+	}()
 
 	// Initialize handlers
 	authHandler := auth.NewAuthHandler(authService)
+	adminHandler := auth.NewAdminHandler(adminService)
 	testHandler := test.NewTestHandler(testService)
 	testAttemptHandler := testAttempt.NewHandlers(testAttemptService)
 	assignmentHandler := assignment.NewHandlers(assignmentService)
+	bankHandler := bank.NewHandlers(bankService)
 	aiHandler := ai.NewHandler(aiService)
 
 	// Set gin mode
@@ -58,8 +165,13 @@ func main() {
 	server := response.NewServer()
 
 	// Create JWT middleware
-	jwtMW := middleware.JWTAuth(cfg.JWTSecret)
-	optionalJWTMW := middleware.OptionalJWTAuth(cfg.JWTSecret)
+	jwtMW := middleware.JWTAuth(cfg.JWTSecret, tokenRepo)
+	optionalJWTMW := middleware.OptionalJWTAuth(cfg.JWTSecret, tokenRepo)
+
+	// Teachers author tests, assignments and grade attempts; students only
+	// take attempts; admins can do both and manage accounts.
+	teacherMW := middleware.RequireRole(auth.RoleTeacher, auth.RoleAdmin)
+	adminMW := middleware.RequireRole(auth.RoleAdmin)
 
 	// Create CORS middleware
 	cors := middleware.CORS()
@@ -70,14 +182,26 @@ func main() {
 	// Setup routes with feature-based routing
 	server.SetupRoutes(
 		func(v1 gin.IRouter) { auth.RegisterRoutes(v1, authHandler, jwtMW) },
-		func(v1 gin.IRouter) { test.RegisterRoutes(v1, testHandler, jwtMW) },
-		func(v1 gin.IRouter) { assignment.RegisterRoutes(v1, assignmentHandler, jwtMW, optionalJWTMW) },
-		func(v1 gin.IRouter) { testAttempt.RegisterRoutes(v1, testAttemptHandler, optionalJWTMW, jwtMW) },
-		func(v1 gin.IRouter) { ai.RegisterRoutes(v1, aiHandler, jwtMW) },
+		func(v1 gin.IRouter) { auth.RegisterAdminRoutes(v1, adminHandler, jwtMW, adminMW) },
+		func(v1 gin.IRouter) { test.RegisterRoutes(v1, testHandler, jwtMW, teacherMW) },
+		func(v1 gin.IRouter) { bank.RegisterRoutes(v1, bankHandler, jwtMW, teacherMW) },
+		func(v1 gin.IRouter) {
+			assignment.RegisterRoutes(v1, assignmentHandler, jwtMW, optionalJWTMW, teacherMW)
+		},
+		func(v1 gin.IRouter) {
+			testAttempt.RegisterRoutes(v1, testAttemptHandler, optionalJWTMW, jwtMW, teacherMW)
+		},
+		func(v1 gin.IRouter) { ai.RegisterRoutes(v1, aiHandler, jwtMW, teacherMW) },
 	)
 
 	// Start server
-	if err := server.Run(cfg.Port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	err := server.Run(ctx, cfg.Port)
+
+	// Stop background work once no request can start more of it
+	stopSweeper()
+	<-sweepDone
+	testAttemptService.StopJobs()
+	if err != nil {
+		log.Fatal("Server stopped with error:", err)
 	}
 }
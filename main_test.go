@@ -7,9 +7,12 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 
+	"edu-system/internal/auth"
 	response "edu-system/internal/delivery"
 	"edu-system/internal/delivery/middleware"
+	"edu-system/internal/test"
 )
 
 func setupTestServer() *gin.Engine {
@@ -70,3 +73,32 @@ func TestCORSPreflight(t *testing.T) {
 		t.Fatalf("expected Vary header to be Origin, got %q", vary)
 	}
 }
+
+func TestStudentsCannotReachAuthorRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const secret = "test-secret"
+
+	server := response.NewServer()
+	server.SetupRoutes(func(v1 gin.IRouter) {
+		test.RegisterRoutes(v1, nil, middleware.JWTAuth(secret, nil), middleware.RequireRole(auth.RoleTeacher, auth.RoleAdmin))
+	})
+	app := server.GetEngine()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1,
+		"email":   "student@example.com",
+		"role":    auth.RoleStudent,
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tests", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d for a student, got %d", http.StatusForbidden, rec.Code)
+	}
+}